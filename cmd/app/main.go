@@ -3,24 +3,35 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"log"
 	"log/slog"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/hibiken/asynq"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
 
 	"music-go-bot/internal/delivery/asynq_delivery"
 	"music-go-bot/internal/delivery/http"
 	"music-go-bot/internal/delivery/telegram"
 	"music-go-bot/internal/domain"
+	"music-go-bot/internal/infrastructure/artifacts"
+	"music-go-bot/internal/infrastructure/deezer"
+	"music-go-bot/internal/infrastructure/events"
+	"music-go-bot/internal/infrastructure/notify"
+	"music-go-bot/internal/infrastructure/prefetch"
 	"music-go-bot/internal/infrastructure/queue"
 	"music-go-bot/internal/infrastructure/repository"
+	"music-go-bot/internal/infrastructure/stats"
+	"music-go-bot/internal/infrastructure/storage"
 	"music-go-bot/internal/logger"
 	"music-go-bot/internal/tasks"
 	"music-go-bot/internal/usecase"
@@ -46,76 +57,310 @@ func main() {
 	if redisAddr == "" {
 		redisAddr = "localhost:6379"
 	}
-	asynqQueue := queue.NewAsynqQueue(redisAddr)
+	// Очереди этапов: QUEUE_<STAGE>_CONCURRENCY — сколько задач этапа выполняется параллельно
+	queueCfg := queue.Config{}
+	for _, stage := range queue.Stages {
+		concurrency, _ := strconv.Atoi(os.Getenv("QUEUE_" + strings.ToUpper(stage) + "_CONCURRENCY"))
+		queueCfg[stage] = queue.StageConfig{Concurrency: concurrency}
+	}
+	queueCfg = queueCfg.WithDefaults()
+
+	// Отдельный клиент Redis для pub/sub событий обработки треков и замеров длительности этапов
+	redisClient := redis.NewClient(&redis.Options{Addr: redisAddr})
+	defer redisClient.Close()
+	trackEvents := events.NewRedisTrackEvents(redisClient)
+	stageStats := stats.NewRedisStageStats(redisClient)
+
+	asynqQueue := queue.NewAsynqQueue(redisAddr, queueCfg, stageStats)
 	defer asynqQueue.Close()
 
+	// 6.1. Хранилища аудио. Telegram доступен всегда (старые треки лежат там),
+	// остальные — если сконфигурированы. AUDIO_STORAGE выбирает, куда пишет пайплайн.
+	audioStorages := domain.AudioStorages{
+		domain.StorageTelegram: storage.NewTelegramStorage(bot, storageID),
+	}
+
+	if dir := os.Getenv("LOCAL_STORAGE_DIR"); dir != "" {
+		localStorage, err := storage.NewLocalStorage(dir)
+		if err != nil {
+			log.Fatalf("could not init local storage: %v", err)
+		}
+		audioStorages[domain.StorageLocal] = localStorage
+	}
+
+	if bucket := os.Getenv("S3_BUCKET"); bucket != "" {
+		presignTTL, _ := time.ParseDuration(os.Getenv("S3_PRESIGN_TTL"))
+		audioStorages[domain.StorageS3] = storage.NewS3Storage(storage.S3Config{
+			Endpoint:   os.Getenv("S3_ENDPOINT"),
+			Region:     os.Getenv("S3_REGION"),
+			Bucket:     bucket,
+			AccessKey:  os.Getenv("S3_ACCESS_KEY"),
+			SecretKey:  os.Getenv("S3_SECRET_KEY"),
+			PresignTTL: presignTTL,
+		})
+	}
+
+	primaryBackend := os.Getenv("AUDIO_STORAGE")
+	if primaryBackend == "" {
+		primaryBackend = domain.StorageTelegram
+	}
+	primaryStorage, err := audioStorages.Get(primaryBackend)
+	if err != nil {
+		log.Fatalf("invalid AUDIO_STORAGE: %v", err)
+	}
+	slog.Info("Audio storage configured", "primary", primaryBackend)
+
+	// 6.2. Кэш скачанных файлов между download и upload. Файл удаляется после загрузки в хранилище,
+	// брошенные — по ARTIFACTS_TTL с последнего использования и по лимиту ARTIFACTS_MAX_SIZE_MB
+	artifactsDir := os.Getenv("ARTIFACTS_DIR")
+	if artifactsDir == "" {
+		artifactsDir = "data/artifacts"
+	}
+	artifactsTTL, err := time.ParseDuration(os.Getenv("ARTIFACTS_TTL"))
+	if err != nil {
+		artifactsTTL = 24 * time.Hour
+	}
+	artifactsMaxMB, err := strconv.ParseInt(os.Getenv("ARTIFACTS_MAX_SIZE_MB"), 10, 64)
+	if err != nil {
+		artifactsMaxMB = 2048
+	}
+	artifactStore, err := artifacts.NewLocalStore(artifacts.Config{
+		Root:    artifactsDir,
+		MaxSize: artifactsMaxMB << 20,
+		TTL:     artifactsTTL,
+	})
+	if err != nil {
+		log.Fatalf("could not init artifacts store: %v", err)
+	}
+
+	// 6.3. Клиент Deezer. DEEZER_BASE_URL — другой адрес API (локальная заглушка),
+	// DEEZER_CACHE=redis — общий кэш ответов для нескольких инстансов, иначе в памяти процесса
+	var deezerCache deezer.Cache
+	switch os.Getenv("DEEZER_CACHE") {
+	case "redis":
+		deezerCache = deezer.NewRedisCache(redisClient)
+	case "", "memory":
+		deezerCache = deezer.NewMemoryCache(0)
+	case "off":
+	default:
+		log.Fatalf("invalid DEEZER_CACHE: %q", os.Getenv("DEEZER_CACHE"))
+	}
+	deezerSearchTTL, _ := time.ParseDuration(os.Getenv("DEEZER_SEARCH_TTL"))
+	deezerEntityTTL, _ := time.ParseDuration(os.Getenv("DEEZER_ENTITY_TTL"))
+	deezerClient := deezer.NewClient(deezer.Config{
+		BaseURL:   os.Getenv("DEEZER_BASE_URL"),
+		SearchTTL: deezerSearchTTL,
+		EntityTTL: deezerEntityTTL,
+		Cache:     deezerCache,
+	})
+
 	// 7. Сборка слоев (Clean Architecture)
 	userRepo := repository.NewUserRepo(db)
 	trackRepo := repository.NewTrackRepo(db)
-	searchUsecaseDZ := usecase.NewSearchUsecaseDZ()
+	playlistRepo := repository.NewPlaylistRepo(db)
+	candidateRepo := repository.NewCandidateRepo(db)
+	waiterRepo := repository.NewWaiterRepo(db)
+	batchRepo := repository.NewBatchRepo(db)
+	artistRepo := repository.NewArtistRepo(db)
+	albumRepo := repository.NewAlbumRepo(db)
+	searchUsecaseDZ := usecase.NewSearchUsecaseDZ(deezerClient)
+	linkResolver := usecase.NewLinkResolverUsecase(searchUsecaseDZ)
 
 	userUsecase := usecase.NewUserUsecase(userRepo)
+	prefetchRegistry := prefetch.NewRedisRegistry(redisClient)
 
 	// TrackUsecase — "входные ворота", ставит задачу на Download
-	trackUsecase := usecase.NewTrackUsecase(userRepo, trackRepo, asynqQueue, bot)
+	trackUsecase := usecase.NewTrackUsecase(userRepo, trackRepo, candidateRepo, waiterRepo, asynqQueue, audioStorages, trackEvents, batchRepo, prefetchRegistry)
+	playlistUsecase := usecase.NewPlaylistUsecase(playlistRepo, trackUsecase)
+	batchUsecase := usecase.NewBatchUsecase(batchRepo, trackRepo, trackUsecase, linkResolver, asynqQueue)
+	catalogUsecase := usecase.NewCatalogUsecase(searchUsecaseDZ, artistRepo, albumRepo, trackRepo)
+	searchUsecase := usecase.NewSearchUsecase(searchUsecaseDZ, trackRepo)
 
 	// --- ОБНОВЛЕННЫЕ USECASE ДЛЯ ВОРКЕРОВ ---
-	ytSearcherUC := usecase.NewSearchUsecaseYT(trackRepo, asynqQueue)
+	// Порог уверенности и размер топа кандидатов; пустые значения — дефолты матчера
+	matchThreshold, _ := strconv.ParseFloat(os.Getenv("YT_MATCH_THRESHOLD"), 64)
+	matchCandidates, _ := strconv.Atoi(os.Getenv("YT_MATCH_CANDIDATES"))
+	ytSearcherUC := usecase.NewSearchUsecaseYT(trackRepo, candidateRepo, asynqQueue, trackEvents, usecase.MatcherConfig{
+		Threshold:  matchThreshold,
+		Candidates: matchCandidates,
+	})
 	// 1. Только скачивание (нужен repo и очередь)
-	ytDownloaderUC := usecase.NewYTDownloaderUsecase(trackRepo, asynqQueue)
+	ytDownloaderUC := usecase.NewYTDownloaderUsecase(trackRepo, asynqQueue, trackEvents, artifactStore)
 
-	// 2. Только загрузка (нужен repo, бот и ID хранилища)
-	tgUploaderUC := usecase.NewTGUploaderUsecase(trackRepo, bot, storageID)
+	// 2. Только загрузка (нужен repo и основное хранилище)
+	tgUploaderUC := usecase.NewTGUploaderUsecase(trackRepo, primaryStorage, asynqQueue, trackEvents, artifactStore)
+
+	// 3. Уведомление ждущих пользователей в личку бота
+	notifyUC := usecase.NewNotifyUsecase(trackRepo, waiterRepo, userRepo, notify.NewTelegramMessenger(bot))
+
+	// 4. Реконсайлер: треки, застрявшие на этапе дольше таймаута (RECONCILE_<STATUS>_TIMEOUT)
+	reconcileTimeouts := map[string]time.Duration{}
+	for _, status := range []string{domain.StatusQueued, domain.StatusSearching, domain.StatusDownloading, domain.StatusUploading} {
+		if d, err := time.ParseDuration(os.Getenv("RECONCILE_" + strings.ToUpper(status) + "_TIMEOUT")); err == nil {
+			reconcileTimeouts[status] = d
+		}
+	}
+	reconcileMaxRequeues, _ := strconv.Atoi(os.Getenv("RECONCILE_MAX_REQUEUES"))
+	reconcilerUC := usecase.NewReconcilerUsecase(trackRepo, asynqQueue, trackEvents, usecase.ReconcilerConfig{
+		Timeouts:    reconcileTimeouts,
+		MaxRequeues: reconcileMaxRequeues,
+	})
+
+	// 5. Поиск пары на Deezer для присланных пользователями файлов
+	uploadMatcherUC := usecase.NewUploadMatcherUsecase(trackRepo, searchUsecaseDZ, asynqQueue)
 
 	// 8. Настройка Воркера (Asynq Server)
-	srv := asynq.NewServer(
-		asynq.RedisClientOpt{Addr: redisAddr},
-		asynq.Config{
-			Concurrency: 10,
-			Queues: map[string]int{
-				"critical": 6,
-				"default":  3,
-			},
-			// ВОТ ЭТОТ БЛОК
-			ErrorHandler: asynq.ErrorHandlerFunc(func(ctx context.Context, task *asynq.Task, err error) {
-				retried, _ := asynq.GetRetryCount(ctx)
-				maxRetry, _ := asynq.GetMaxRetry(ctx)
-
-				// Если задача провалилась окончательно
-				if retried >= maxRetry {
-					// Используем твою новую функцию из пакета tasks
-					id := tasks.ExtractID(task)
-					if id != 0 {
-						slog.Error("Final task failure, marking track as ERROR in DB",
-							"deezer_id", id,
-							"task_type", task.Type(),
-							"error", err,
-						)
-						// Обновляем статус в базе
-						_ = trackRepo.UpdateStatus(context.Background(), id, domain.StatusError)
-					}
-				}
-			}),
+	workerCfg := asynq.Config{
+		// Telegram 429 и подобные: ждем столько, сколько попросил провайдер
+		RetryDelayFunc: func(n int, err error, task *asynq.Task) time.Duration {
+			if delay, ok := domain.RetryAfter(err); ok {
+				return delay
+			}
+			return asynq.DefaultRetryDelayFunc(n, err, task)
 		},
-	)
+		// ВОТ ЭТОТ БЛОК
+		ErrorHandler: asynq.ErrorHandlerFunc(func(ctx context.Context, task *asynq.Task, err error) {
+			retried, _ := asynq.GetRetryCount(ctx)
+			maxRetry, _ := asynq.GetMaxRetry(ctx)
+			// Постоянную ошибку asynq не ретраит — это финал, даже если попытки остались
+			final := retried >= maxRetry || errors.Is(err, asynq.SkipRetry)
+
+			id := tasks.ExtractID(task)
+			if id == 0 {
+				return
+			}
+
+			// Уведомление — не этап цепочки: трек уже в финальном статусе, его не трогаем
+			if task.Type() == tasks.TypeTelegramNotify {
+				slog.Warn("Notify task failed", "deezer_id", id, "retry", retried, "error", err)
+				return
+			}
+			// Поиск пары для загрузки тоже: в payload внутренний ID, а не Deezer ID.
+			// Загрузка остается pending и будет дослана при следующем запуске
+			if task.Type() == tasks.TypeMatchUpload {
+				slog.Warn("Upload match task failed", "track_id", id, "retry", retried, "error", err)
+				return
+			}
+			stage := tasks.StageOf(task.Type())
+
+			// В базу пишем класс и причину: "[permanent] video_unavailable: ..."
+			reason := domain.ErrorRecord(err)
+
+			// Промежуточная ошибка: будет ретрай, статус не трогаем
+			if !final {
+				if recErr := trackRepo.RecordError(context.Background(), id, stage, reason); recErr != nil {
+					slog.Error("Failed to record task error", "deezer_id", id, "error", recErr)
+				}
+				return
+			}
+
+			// Задача провалилась окончательно
+			slog.Error("Final task failure, marking track as FAILED in DB",
+				"deezer_id", id,
+				"task_type", task.Type(),
+				"error", err,
+			)
+			if markErr := trackRepo.MarkFailed(context.Background(), id, stage, reason); markErr != nil {
+				slog.Error("Failed to mark track as failed", "deezer_id", id, "error", markErr)
+			}
+			_ = trackEvents.Publish(context.Background(), domain.TrackEvent{
+				DeezerID: id,
+				Stage:    domain.StageError,
+				Error:    "processing failed",
+			})
+			if notifyErr := asynqQueue.EnqueueNotify(context.Background(), id, tasks.ExtractUserID(task)); notifyErr != nil {
+				slog.Error("Failed to enqueue notify task", "deezer_id", id, "error", notifyErr)
+			}
+		}),
+	}
+
+	// По серверу на этап со своей конкуренцией: фоновые скачивания не занимают слоты поиска,
+	// а внутри этапа высокий приоритет (пользователь ждет) всегда разбирается первым.
+	// Уведомления и реконсайлер — в общей очереди default (там же доедут задачи этапов,
+	// поставленные до разделения очередей)
+	var servers []*asynq.Server
+	for _, stage := range queue.Stages {
+		cfg := workerCfg
+		cfg.Concurrency = queueCfg[stage].Concurrency
+		cfg.Queues = queue.StageQueues(stage)
+		cfg.StrictPriority = true
+		servers = append(servers, asynq.NewServer(asynq.RedisClientOpt{Addr: redisAddr}, cfg))
+	}
+	defaultCfg := workerCfg
+	defaultCfg.Concurrency = 2
+	defaultCfg.Queues = map[string]int{queue.DefaultQueue: 1}
+	servers = append(servers, asynq.NewServer(asynq.RedisClientOpt{Addr: redisAddr}, defaultCfg))
 
 	// Передаем оба юзкейса в хендлер
-	asynqHandler := asynq_delivery.NewTaskHandler(ytSearcherUC, ytDownloaderUC, tgUploaderUC)
+	asynqHandler := asynq_delivery.NewTaskHandler(ytSearcherUC, ytDownloaderUC, tgUploaderUC, notifyUC, reconcilerUC, batchUsecase, uploadMatcherUC)
 	mux := asynq.NewServeMux()
 
 	// Твой хендлер сам знает, какие типы задач к каким методам привязать
 	asynqHandler.Register(mux)
+	// Длительность успешных этапов — в статистику для ETA
+	mux.Use(asynq_delivery.StageTimer(stageStats))
 
-	go func() {
-		slog.Info("Worker is running with Task Chaining...")
-		if err := srv.Run(mux); err != nil {
+	for _, srv := range servers {
+		if err := srv.Start(mux); err != nil {
 			log.Fatalf("could not run asynq server: %v", err)
 		}
+		defer srv.Shutdown()
+	}
+	slog.Info("Workers are running", "stages", queueCfg)
+
+	// 8.1. Планировщик: раз в RECONCILE_INTERVAL ставит проверку застрявших треков.
+	// Unique — при нескольких инстансах за интервал выполнится только одна проверка
+	reconcileInterval := os.Getenv("RECONCILE_INTERVAL")
+	if reconcileInterval == "" {
+		reconcileInterval = "1m"
+	}
+	reconcileEvery, err := time.ParseDuration(reconcileInterval)
+	if err != nil {
+		log.Fatalf("invalid RECONCILE_INTERVAL: %v", err)
+	}
+	scheduler := asynq.NewScheduler(asynq.RedisClientOpt{Addr: redisAddr}, nil)
+	if _, err := scheduler.Register("@every "+reconcileInterval, tasks.NewReconcileTask(), asynq.Unique(reconcileEvery), asynq.MaxRetry(0)); err != nil {
+		log.Fatalf("could not register reconcile task: %v", err)
+	}
+	if err := scheduler.Start(); err != nil {
+		log.Fatalf("could not run asynq scheduler: %v", err)
+	}
+	defer scheduler.Shutdown()
+
+	// 8.2. Загрузки, для которых пару еще не искали (присланные до появления поиска или потерявшие задачу)
+	go func() {
+		if err := uploadMatcherUC.EnqueuePending(context.Background()); err != nil {
+			slog.Error("Failed to enqueue pending upload matches", "error", err)
+		}
 	}()
 
 	// 9-10. (Запуск API и Бота — без изменений)
-	handler := http.NewHandler(trackUsecase, searchUsecaseDZ, userUsecase, asynqQueue)
-	router := http.InitRouter(handler)
+	// Предзагрузка следующих треков: PREFETCH_AHEAD — сколько вперед,
+	// PREFETCH_MAX_OUTSTANDING — сколько предзагрузок пользователя обрабатывается одновременно
+	prefetchAhead, _ := strconv.Atoi(os.Getenv("PREFETCH_AHEAD"))
+	prefetchMaxOutstanding, _ := strconv.Atoi(os.Getenv("PREFETCH_MAX_OUTSTANDING"))
+	prefetchUsecase := usecase.NewPrefetchUsecase(
+		trackUsecase, searchUsecaseDZ, playlistUsecase, trackRepo, waiterRepo, asynqQueue,
+		prefetchRegistry,
+		usecase.PrefetchConfig{Ahead: prefetchAhead, MaxOutstanding: prefetchMaxOutstanding},
+	)
+
+	handler := http.NewHandler(trackUsecase, searchUsecaseDZ, userUsecase, playlistUsecase, prefetchUsecase, batchUsecase, catalogUsecase, searchUsecase, asynqQueue, trackEvents, os.Getenv("PUBLIC_BASE_URL"))
+
+	// Проверка initData Mini App. AUTH_DEV_MODE=true пускает по ?user_id= (только локально!)
+	authMaxAge, err := time.ParseDuration(os.Getenv("AUTH_MAX_AGE"))
+	if err != nil {
+		authMaxAge = 24 * time.Hour
+	}
+	authDevMode, _ := strconv.ParseBool(os.Getenv("AUTH_DEV_MODE"))
+	if authDevMode {
+		slog.Warn("AUTH_DEV_MODE is enabled: user_id from query is trusted without signature")
+	}
+	router := http.InitRouter(handler, http.AuthConfig{
+		BotToken: botToken,
+		MaxAge:   authMaxAge,
+		DevMode:  authDevMode,
+	})
 
 	go func() {
 		port := os.Getenv("PORT")
@@ -125,7 +370,7 @@ func main() {
 		router.Run(":" + port)
 	}()
 
-	botHandler := telegram.NewBotHandler(bot, trackUsecase, userUsecase)
+	botHandler := telegram.NewBotHandler(bot, trackUsecase, userUsecase, searchUsecaseDZ, linkResolver, batchUsecase, trackEvents)
 	slog.Info("Telegram Bot is running...")
 	botHandler.Start(ctx)
 
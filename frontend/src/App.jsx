@@ -174,6 +174,8 @@ function App() {
     setIsTelegram(isActuallyInTg);
 
     if (isActuallyInTg && tg.initDataUnsafe?.user) {
+      // Бэкенд проверяет подпись initData и берет user_id только из нее
+      axios.defaults.headers.common['X-Telegram-Init-Data'] = tg.initData;
       tg.ready();
       tg.expand();
       const tgTheme = tg.colorScheme || 'dark';
@@ -276,12 +278,11 @@ useEffect(() => {
 
     try {
         await axios.post(`${backendBaseUrl}/api/tracks/${isLiked ? 'unlike' : 'like'}`, {
-            user_id: Number(tgUser.id), 
             deezer_id: Number(track.deezer_id),
             title: track.title || "",
             artist: track.artist?.name || track.artist || "",
             cover_url: track.album?.cover_big || track.cover_url || ""
-        });
+        }, { params: { user_id: tgUser.id } }); // user_id учитывается только в AUTH_DEV_MODE
         window.Telegram?.WebApp?.HapticFeedback?.impactOccurred('light');
     } catch (err) {
         console.error("Ошибка при лайке:", err.response?.data || err.message);
@@ -37,7 +37,7 @@ const AudioPlayer = ({
       {/* ОДИН общий тег audio для всех режимов */}
 <audio
   ref={audioRef}
-  src={currentTrack.play_link || (currentTrack.file_id ? `${backendBaseUrl}/api/tracks/stream/${currentTrack.file_id}` : null)}
+  src={currentTrack.play_link || (currentTrack.file_id ? `${backendBaseUrl}/api/tracks/stream/${currentTrack.track_id || currentTrack.id}` : null)}
   playsInline
   preload="auto"
   autoPlay
@@ -3,7 +3,10 @@ package asynq_delivery
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"music-go-bot/internal/domain"
 	"music-go-bot/internal/tasks"
 	"music-go-bot/internal/usecase"
 
@@ -14,37 +17,73 @@ type TaskHandler struct {
 	searchUC *usecase.YTSearcherUsecase // Новое звено
 	ytUC     *usecase.YTDownloaderUsecase
 	tgUC     *usecase.TGUploaderUsecase
+	notifyUC *usecase.NotifyUsecase
+	reconcUC *usecase.ReconcilerUsecase
+	batchUC  *usecase.BatchUsecase
+	matchUC  *usecase.UploadMatcherUsecase
 }
 
 func NewTaskHandler(
 	searcher *usecase.YTSearcherUsecase,
 	yt *usecase.YTDownloaderUsecase,
 	tg *usecase.TGUploaderUsecase,
+	notifier *usecase.NotifyUsecase,
+	reconciler *usecase.ReconcilerUsecase,
+	batcher *usecase.BatchUsecase,
+	matcher *usecase.UploadMatcherUsecase,
 ) *TaskHandler {
 	return &TaskHandler{
 		searchUC: searcher,
 		ytUC:     yt,
 		tgUC:     tg,
+		notifyUC: notifier,
+		reconcUC: reconciler,
+		batchUC:  batcher,
+		matchUC:  matcher,
 	}
 }
 
 func (h *TaskHandler) Register(mux *asynq.ServeMux) {
-	// Регистрируем все три этапа
+	mux.Use(retryPolicy)
+
+	// Регистрируем все три этапа и уведомление в конце цепочки
 	mux.HandleFunc(tasks.TypeYoutubeSearch, h.HandleSearchTask)
 	mux.HandleFunc(tasks.TypeDownloadYoutube, h.HandleDownloadTask)
 	mux.HandleFunc(tasks.TypeTelegramUpload, h.HandleUploadTask)
+	mux.HandleFunc(tasks.TypeTelegramNotify, h.HandleNotifyTask)
+
+	// Периодическая проверка застрявших треков (ставит asynq.Scheduler)
+	mux.HandleFunc(tasks.TypeReconcile, h.HandleReconcileTask)
+
+	// Поиск пары на Deezer для присланных файлов
+	mux.HandleFunc(tasks.TypeMatchUpload, h.HandleMatchUploadTask)
+
+	// Треклист пакета (альбома/плейлиста) и запуск цепочек по трекам
+	mux.HandleFunc(tasks.TypeBatchStart, h.HandleBatchStartTask)
+}
+
+// retryPolicy: постоянные ошибки (видео удалено, ничего не нашлось) asynq не ретраит —
+// задача сразу уходит в archived, а ErrorHandler помечает трек failed
+func retryPolicy(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		err := next.ProcessTask(ctx, t)
+		if err != nil && domain.IsPermanentError(err) {
+			return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+		}
+		return err
+	})
 }
 
 // 1. Обработка поиска
 func (h *TaskHandler) HandleSearchTask(ctx context.Context, t *asynq.Task) error {
 	var p tasks.SearchYoutubePayload
 	if err := json.Unmarshal(t.Payload(), &p); err != nil {
-		return err
+		return domain.PermanentError("bad_payload", err)
 	}
 
 	// Вызываем поиск. Внутри него (как мы писали ранее)
 	// произойдет сохранение ID и вызов EnqueueDownload
-	return h.searchUC.ExecuteSearch(ctx, p.DeezerID)
+	return h.searchUC.ExecuteSearch(ctx, p.DeezerID, p.UserID, p.Priority)
 }
 
 // 2. Обработка скачивания
@@ -52,12 +91,12 @@ func (h *TaskHandler) HandleDownloadTask(ctx context.Context, t *asynq.Task) err
 	var p tasks.DownloadYoutubePayload
 	if err := json.Unmarshal(t.Payload(), &p); err != nil {
 		slog.Error("Failed to unmarshal download payload", "error", err)
-		return err
+		return domain.PermanentError("bad_payload", err)
 	}
 
 	slog.Info("Worker: starting download stage", "track_id", p.TrackID, "yt_id", p.YoutubeID)
 
-	err := h.ytUC.Download(ctx, p.TrackID, p.YoutubeID)
+	err := h.ytUC.Download(ctx, p.TrackID, p.YoutubeID, p.UserID, p.Priority)
 	if err != nil {
 		slog.Error("Worker: download stage failed", "error", err, "track_id", p.TrackID)
 		return err // Asynq увидит ошибку и попробует позже
@@ -71,8 +110,65 @@ func (h *TaskHandler) HandleDownloadTask(ctx context.Context, t *asynq.Task) err
 func (h *TaskHandler) HandleUploadTask(ctx context.Context, t *asynq.Task) error {
 	var p tasks.TelegramUploadPayload
 	if err := json.Unmarshal(t.Payload(), &p); err != nil {
-		return err
+		return domain.PermanentError("bad_payload", err)
+	}
+
+	return h.tgUC.UploadFile(ctx, p.TrackID, p.FilePath, p.UserID, p.Priority)
+}
+
+// 4. Уведомление ждущих пользователей
+func (h *TaskHandler) HandleNotifyTask(ctx context.Context, t *asynq.Task) error {
+	var p tasks.NotifyPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return domain.PermanentError("bad_payload", err)
 	}
 
-	return h.tgUC.UploadFile(ctx, p.TrackID, p.FilePath)
+	notifyErr := h.notifyUC.Notify(ctx, p.TrackID, p.UserID)
+
+	// Цепочка трека закончилась — возможно, вместе с ней и пакет (альбом целиком).
+	// На ретрае повторных уведомлений не будет: ожидания уже забраны через Claim
+	var batchErr error
+	if err := h.batchUC.TrackFinished(ctx, p.TrackID); err != nil {
+		batchErr = fmt.Errorf("batch update failed: %w", err)
+	}
+
+	return errors.Join(notifyErr, batchErr)
+}
+
+// 5. Поиск и перезапуск застрявших треков
+func (h *TaskHandler) HandleReconcileTask(ctx context.Context, t *asynq.Task) error {
+	return h.reconcUC.Run(ctx)
+}
+
+// 6. Поиск пары на Deezer для присланного файла
+func (h *TaskHandler) HandleMatchUploadTask(ctx context.Context, t *asynq.Task) error {
+	var p tasks.MatchUploadPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return domain.PermanentError("bad_payload", err)
+	}
+
+	return h.matchUC.Match(ctx, p.TrackID)
+}
+
+// 7. Треклист пакета и запуск цепочек по его трекам
+func (h *TaskHandler) HandleBatchStartTask(ctx context.Context, t *asynq.Task) error {
+	var p tasks.BatchStartPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return domain.PermanentError("bad_payload", err)
+	}
+
+	err := h.batchUC.Run(ctx, p.UserID, p.BatchID)
+	if err == nil {
+		return nil
+	}
+
+	// Последняя попытка — пакет больше не ждет треклиста, иначе он навсегда останется pending
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	if domain.IsPermanentError(err) || retried >= maxRetry {
+		if failErr := h.batchUC.Fail(context.WithoutCancel(ctx), p.BatchID); failErr != nil {
+			slog.Error("Failed to mark batch failed", "batch_id", p.BatchID, "error", failErr)
+		}
+	}
+	return err
 }
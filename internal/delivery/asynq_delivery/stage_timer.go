@@ -0,0 +1,38 @@
+package asynq_delivery
+
+import (
+	"context"
+	"log/slog"
+	"music-go-bot/internal/domain"
+	"music-go-bot/internal/infrastructure/queue"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// StageTimer замеряет, сколько длится этап цепочки, — по этим замерам очередь считает ETA.
+// Учитываются только успешные выполнения: упавшая на первой секунде задача занизила бы оценку
+func StageTimer(stats domain.StageStats) asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+			stage := queue.StageOfTask(t.Type())
+			if stage == "" {
+				return next.ProcessTask(ctx, t)
+			}
+
+			start := time.Now()
+			err := next.ProcessTask(ctx, t)
+			if err != nil {
+				return err
+			}
+
+			// Контекст задачи к этому моменту может быть уже отменен — замер пишем в своем
+			recCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			if recErr := stats.RecordDuration(recCtx, stage, time.Since(start)); recErr != nil {
+				slog.Warn("Failed to record stage duration", "stage", stage, "error", recErr)
+			}
+			return nil
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package http
+
+import (
+	"errors"
+	"log/slog"
+	"music-go-bot/internal/domain"
+	"music-go-bot/internal/usecase"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateBatchRequest — альбом или плейлист Deezer целиком
+type CreateBatchRequest struct {
+	Type         string `json:"type"` // album / playlist
+	ID           int64  `json:"id"`
+	AddToLibrary bool   `json:"add_to_library"` // Готовые треки попадут в библиотеку, когда пакет закончится
+}
+
+// CreateBatch — поставить на скачивание весь альбом/плейлист. Треклист загружается в фоне:
+// в ответе пакет в статусе pending, прогресс — через GET /api/batches/:id
+func (h *Handler) CreateBatch(c *gin.Context) {
+	var req CreateBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ID <= 0 || (req.Type != usecase.LinkAlbum && req.Type != usecase.LinkPlaylist) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type (album|playlist) and id are required"})
+		return
+	}
+
+	userID := currentUserID(c)
+	batch, err := h.batchUC.CreateFromDeezer(c.Request.Context(), userID, req.Type, req.ID, req.AddToLibrary)
+	if err != nil {
+		slog.Error("Failed to create batch", "user_id", userID, "type", req.Type, "id", req.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create batch"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, batch)
+}
+
+// GetBatch — прогресс пакета (n из m готово, ошибки) и статусы его треков
+func (h *Handler) GetBatch(c *gin.Context) {
+	batchID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid batch id"})
+		return
+	}
+
+	batch, err := h.batchUC.Get(c.Request.Context(), currentUserID(c), batchID)
+	if err != nil {
+		if errors.Is(err, domain.ErrBatchNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "batch not found"})
+			return
+		}
+		slog.Error("Failed to get batch", "batch_id", batchID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"errors"
+	"log/slog"
+	"music-go-bot/internal/domain"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CancelJob — остановить обработку трека. Если трек нужен другим пользователям (ждут,
+// качают пакетом, предзагружают), снимается только ожидание текущего, а цепочка продолжается (cancelled: false)
+func (h *Handler) CancelJob(c *gin.Context) {
+	deezerID, err := strconv.ParseInt(c.Param("deezer_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid deezer id"})
+		return
+	}
+
+	result, err := h.trackUc.CancelJob(c.Request.Context(), currentUserID(c), deezerID)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrTrackNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "track not in database"})
+		case errors.Is(err, domain.ErrTrackBusy):
+			c.JSON(http.StatusConflict, gin.H{"error": "track is already being uploaded and cannot be cancelled"})
+		default:
+			slog.Error("Failed to cancel track job", "deezer_id", deezerID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cancel job"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deezer_id": deezerID,
+		"status":    result.Status,
+		"cancelled": result.Cancelled,
+		"waiters":   result.Waiters,
+	})
+}
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"errors"
+	"log/slog"
+	"music-go-bot/internal/domain"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetArtist — страница артиста: популярные треки, альбомы, похожие артисты
+func (h *Handler) GetArtist(c *gin.Context) {
+	artistID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || artistID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid artist id"})
+		return
+	}
+
+	page, err := h.catalogUC.GetArtist(c.Request.Context(), artistID)
+	if err != nil {
+		if errors.Is(err, domain.ErrArtistNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "artist not found"})
+			return
+		}
+		slog.Error("Failed to get artist", "artist_id", artistID, "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to load artist from Deezer"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetAlbum — альбом с треклистом; у каждого трека — статус у нас (если он уже есть)
+func (h *Handler) GetAlbum(c *gin.Context) {
+	albumID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || albumID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid album id"})
+		return
+	}
+
+	page, err := h.catalogUC.GetAlbum(c.Request.Context(), albumID)
+	if err != nil {
+		if errors.Is(err, domain.ErrAlbumNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "album not found"})
+			return
+		}
+		slog.Error("Failed to get album", "album_id", albumID, "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to load album from Deezer"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"music-go-bot/internal/domain"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const sseHeartbeatInterval = 15 * time.Second
+
+// TrackEvents — SSE-поток этапов обработки трека (вместо поллинга /tracks/status/:id).
+// Первым событием отдается текущее состояние из базы, дальше — живые события из Redis.
+// Поток закрывается после ready/error, а для idle/blocked — сразу после снимка
+// (или после события отмены): цепочка не идет, ждать нечего.
+func (h *Handler) TrackEvents(c *gin.Context) {
+	deezerID, err := strconv.ParseInt(c.Param("deezer_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid deezer id"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	// Сначала подписываемся, потом читаем базу — так ничего не теряется между ними
+	events, err := h.events.Subscribe(ctx, deezerID)
+	if err != nil {
+		slog.Error("Failed to subscribe to track events", "deezer_id", deezerID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "events unavailable"})
+		return
+	}
+
+	track, err := h.trackUc.GetByDeezerID(ctx, deezerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if track == nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": "not_found", "error": "track not in database"})
+		return
+	}
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no") // Чтобы прокси не копил поток
+	c.Status(http.StatusOK)
+
+	snapshot := domain.TrackEvent{
+		DeezerID: track.DeezerID,
+		TrackID:  track.ID,
+		Stage:    track.Status,
+		At:       time.Now(),
+	}
+	if track.Status == domain.StatusFailed {
+		snapshot.Stage = domain.StageError
+	}
+	if !h.writeEvent(c, snapshot) || snapshot.IsFinal() {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			// Комментарий SSE: держит соединение живым через прокси
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if !h.writeEvent(c, event) || event.IsFinal() {
+				return
+			}
+		}
+	}
+}
+
+func (h *Handler) writeEvent(c *gin.Context, event domain.TrackEvent) bool {
+	var body any = event
+
+	// Клиенту — ссылка на прокси, как в /tracks/status/:id
+	if event.Stage == domain.StageReady && event.TrackID != 0 {
+		body = struct {
+			domain.TrackEvent
+			PlayLink string `json:"play_link"`
+		}{event, h.playLink(event.TrackID)}
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return false
+	}
+
+	if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event.Stage, payload); err != nil {
+		return false
+	}
+	c.Writer.Flush()
+	return true
+}
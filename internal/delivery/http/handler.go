@@ -17,65 +17,104 @@ type Handler struct {
 	searchYTUc *usecase.YTSearcherUsecase
 	searchDZUC *usecase.SearchUsecaseDZ
 	userUC     *usecase.UserUsecase
+	playlistUC *usecase.PlaylistUsecase
+	prefetchUC *usecase.PrefetchUsecase
+	batchUC    *usecase.BatchUsecase
+	catalogUC  *usecase.CatalogUsecase
+	searchUC   *usecase.SearchUsecase
 	queue      *queue.AsynqQueue
+	events     domain.TrackEventSubscriber
+	// Внешний адрес API для ссылок play_link (пустой — ссылки относительные)
+	publicBaseURL string
 }
 
 func NewHandler(
 	trackUC *usecase.TrackUsecase,
 	searchDZUC *usecase.SearchUsecaseDZ,
 	userUC *usecase.UserUsecase,
+	playlistUC *usecase.PlaylistUsecase,
+	prefetchUC *usecase.PrefetchUsecase,
+	batchUC *usecase.BatchUsecase,
+	catalogUC *usecase.CatalogUsecase,
+	searchUC *usecase.SearchUsecase,
 	queue *queue.AsynqQueue,
+	events domain.TrackEventSubscriber,
+	publicBaseURL string,
 ) *Handler {
 	return &Handler{
-		trackUc:    trackUC,
-		searchDZUC: searchDZUC,
-		userUC:     userUC,
-		queue:      queue,
+		trackUc:       trackUC,
+		searchDZUC:    searchDZUC,
+		userUC:        userUC,
+		playlistUC:    playlistUC,
+		prefetchUC:    prefetchUC,
+		batchUC:       batchUC,
+		catalogUC:     catalogUC,
+		searchUC:      searchUC,
+		queue:         queue,
+		events:        events,
+		publicBaseURL: strings.TrimRight(publicBaseURL, "/"),
 	}
 }
 
-func (h *Handler) RegisterRoutes(r *gin.Engine) {
+func (h *Handler) RegisterRoutes(r *gin.Engine, auth, optionalAuth gin.HandlerFunc) {
 	r.Use(gin.Recovery())
 
 	api := r.Group("/api")
 	{
-		api.POST("/tracks/play", h.HandlePlay)
-		api.GET("/tracks/stream/:file_id", h.StreamTrack)
-		api.GET("/tracks", h.GetTracks)
+		// Из Mini App пользователь известен — ему придет уведомление, когда трек будет готов
+		api.POST("/tracks/play", optionalAuth, h.HandlePlay)
+		api.GET("/tracks/stream/:id", h.StreamTrack)
+		// Вошедшему пользователю в выдаче отмечаются его лайки
+		api.GET("/search", optionalAuth, h.Search)
 		api.GET("/search/deezer", h.SearchTracksDZ)
 		api.GET("/search/artist", h.SearchArtistsDZ)
-		api.POST("/tracks/like", h.HandleLike)
-		api.POST("/tracks/unlike", h.HandleUnlike)
 		api.GET("/tracks/status/:id", h.CheckStatus)
+		api.GET("/tracks/events/:deezer_id", h.TrackEvents)
+		api.GET("/tracks/:deezer_id/eta", h.GetTrackETA)
 		api.GET("/queue/stats", h.GetQueueStats)
 		api.GET("/search/album", h.SearchAlbumsDZ)
+		api.GET("/artists/:id", h.GetArtist)
+		api.GET("/albums/:id", h.GetAlbum)
 	}
-}
-
-func (h *Handler) StreamTrack(c *gin.Context) {
-	fileID := c.Param("file_id")
 
-	// Пробрасываем контекст запроса c.Request.Context()
-	fileURL, err := h.trackUc.GetTelegramFileLink(c.Request.Context(), fileID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get file link"})
-		return
+	// Всё, что касается библиотеки пользователя, — только с проверенным initData
+	authed := api.Group("", auth)
+	{
+		authed.GET("/tracks", h.GetTracks)
+		authed.POST("/tracks/like", h.HandleLike)
+		authed.POST("/tracks/unlike", h.HandleUnlike)
+		authed.POST("/tracks/:deezer_id/report", h.ReportTrack)
+		authed.DELETE("/tracks/:deezer_id/job", h.CancelJob)
+		authed.PATCH("/me/notifications", h.SetNotifications)
+		authed.POST("/prefetch", h.Prefetch)
+		authed.POST("/batches", h.CreateBatch)
+		authed.GET("/batches/:id", h.GetBatch)
+
+		authed.GET("/playlists", h.ListPlaylists)
+		authed.POST("/playlists", h.CreatePlaylist)
+		authed.GET("/playlists/:id", h.GetPlaylist)
+		authed.PATCH("/playlists/:id", h.RenamePlaylist)
+		authed.DELETE("/playlists/:id", h.DeletePlaylist)
+		authed.POST("/playlists/:id/tracks", h.AddPlaylistTrack)
+		authed.DELETE("/playlists/:id/tracks/:position", h.RemovePlaylistTrack)
+		authed.POST("/playlists/:id/tracks/move", h.MovePlaylistTrack)
 	}
-
-	// Просто перенаправляем плеер на сервер Телеграма
-	c.Redirect(http.StatusTemporaryRedirect, fileURL)
 }
 
 func (h *Handler) GetTracks(c *gin.Context) {
-	userIDParam := c.Query("user_id")
-	userID, err := strconv.ParseInt(userIDParam, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
-		return
-	}
+	userID := currentUserID(c)
 
-	// Передаем контекст запроса в Usecase -> Repository -> DB
-	tracks, err := h.trackUc.GetUserLibrary(c.Request.Context(), userID)
+	// Передаем контекст запроса в Usecase -> Repository -> DB.
+	// С q — поиск по библиотеке (по релевантности), без него — вся библиотека
+	var (
+		tracks []domain.Track
+		err    error
+	)
+	if query := strings.TrimSpace(c.Query("q")); query != "" {
+		tracks, err = h.trackUc.SearchUserLibrary(c.Request.Context(), userID, query)
+	} else {
+		tracks, err = h.trackUc.GetUserLibrary(c.Request.Context(), userID)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -112,6 +151,12 @@ func (h *Handler) HandlePlay(c *gin.Context) {
 		Artist   string `json:"artist"`
 		CoverURL string `json:"cover_url"`
 		Duration int    `json:"duration"`
+		// Из выдачи поиска: по ним трек привязывается к артисту и альбому
+		ArtistID   int64  `json:"artist_id"`
+		AlbumID    int64  `json:"album_id"`
+		AlbumTitle string `json:"album_title"`
+		// Откуда запущен трек — тогда следующие треки начнут готовиться заранее
+		Context *usecase.PrefetchContext `json:"context"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -121,11 +166,14 @@ func (h *Handler) HandlePlay(c *gin.Context) {
 	}
 
 	track := domain.Track{
-		DeezerID: req.DeezerID,
-		Title:    req.Title,
-		Artist:   req.Artist,
-		CoverURL: req.CoverURL,
-		Duration: req.Duration,
+		DeezerID:   req.DeezerID,
+		Title:      req.Title,
+		Artist:     req.Artist,
+		CoverURL:   req.CoverURL,
+		Duration:   req.Duration,
+		ArtistID:   req.ArtistID,
+		AlbumID:    req.AlbumID,
+		AlbumTitle: req.AlbumTitle,
 	}
 
 	// Логируем начало процесса
@@ -134,7 +182,7 @@ func (h *Handler) HandlePlay(c *gin.Context) {
 		"track", req.Artist+" - "+req.Title,
 	)
 
-	result, err := h.trackUc.GetPlaybackState(ctx, track)
+	result, err := h.trackUc.GetPlaybackState(ctx, track, currentUserID(c), domain.PriorityHigh)
 	if err != nil {
 		slog.Error("Failed to get playback state",
 			"deezer_id", req.DeezerID,
@@ -144,25 +192,49 @@ func (h *Handler) HandlePlay(c *gin.Context) {
 		return
 	}
 
+	// Предзагрузка привязана к пользователю (лимит, отмена при смене контекста) — анонимам не делаем
+	if userID := currentUserID(c); userID != 0 && req.Context != nil {
+		pc := *req.Context
+		pc.Current = req.DeezerID
+		h.autoPrefetch(userID, pc)
+	}
+
 	switch result.Status {
 	case domain.StatusReady:
 		slog.Info("Track is ready", "deezer_id", req.DeezerID)
 		c.JSON(http.StatusOK, gin.H{
 			"status":    "ready",
-			"play_link": result.PlayLink,
+			"play_link": h.playLink(result.TrackID),
+			"track_id":  result.TrackID,
 		})
 
-	case domain.StatusProcessing:
-		slog.Debug("Track is being processed", "deezer_id", req.DeezerID)
+	case domain.StatusBlocked:
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":    domain.StatusBlocked,
+			"deezer_id": req.DeezerID,
+		})
+
+	case domain.StatusLowConfidence:
+		// Видео не выбрано автоматически — кандидатов можно посмотреть в /tracks/status/:id
+		c.JSON(http.StatusConflict, gin.H{
+			"status":    domain.StatusLowConfidence,
+			"deezer_id": req.DeezerID,
+		})
+
+	default:
+		// queued / searching / downloading / uploading — фронт ждет событий по SSE
+		slog.Debug("Track is being processed", "deezer_id", req.DeezerID, "status", result.Status)
 		c.JSON(http.StatusAccepted, gin.H{
-			"status":    "processing",
+			"status":    result.Status,
 			"deezer_id": req.DeezerID,
 		})
 	}
 }
 
+// LikeRequest — user_id берется из проверенного initData, а не из тела.
+// Трек задается либо track_id (внутренний ID — он есть и у присланных файлов), либо deezer_id
 type LikeRequest struct {
-	UserID   int64  `json:"user_id"`
+	TrackID  int64  `json:"track_id"`
 	DeezerID int64  `json:"deezer_id"`
 	Title    string `json:"title"`
 	Artist   string `json:"artist"`
@@ -177,25 +249,23 @@ func (h *Handler) HandleLike(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
+	userID := currentUserID(c)
 
 	// 1. Проверяем наличие пользователя в базе
 	// (Метод GetByID должен вернуть ошибку, если юзера нет)
-	_, err := h.userUC.GetByID(ctx, req.UserID)
+	user, err := h.userUC.GetByID(ctx, userID)
 	if err != nil {
 		c.JSON(404, gin.H{"error": "user not found in database"})
 		return
 	}
 
-	// 2. Ищем трек по DeezerID
-	// (Метод GetByDeezerID возвращает существующий трек с внутренним ID)
-	existingTrack, err := h.trackUc.GetByDeezerID(ctx, req.DeezerID)
-	if err != nil {
-		c.JSON(404, gin.H{"error": "track not found in system (must be played first)"})
+	// 2. Ищем трек: по внутреннему ID или по DeezerID
+	existingTrack, ok := h.likeTarget(c, req)
+	if !ok {
 		return
 	}
 
 	// 3. Если всё нашли — создаем связь
-	user := &domain.User{ID: req.UserID}
 	err = h.trackUc.AddTrackToUser(ctx, user, existingTrack)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "failed to link track: " + err.Error()})
@@ -214,13 +284,12 @@ func (h *Handler) HandleUnlike(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	// 1. Проверяем трек (нужно получить его внутренний ID)
-	track, err := h.trackUc.GetByDeezerID(ctx, req.DeezerID)
-	if err != nil {
-		c.JSON(404, gin.H{"error": "track not found"})
+	track, ok := h.likeTarget(c, req)
+	if !ok {
 		return
 	}
 
-	err = h.trackUc.RemoveTrackFromUser(ctx, req.UserID, track.ID)
+	err := h.trackUc.RemoveTrackFromUser(ctx, currentUserID(c), track.ID)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "failed to remove like"})
 		return
@@ -228,6 +297,34 @@ func (h *Handler) HandleUnlike(c *gin.Context) {
 
 	c.JSON(200, gin.H{"status": "unliked"})
 }
+
+// likeTarget находит трек из LikeRequest. При false ответ уже отправлен
+func (h *Handler) likeTarget(c *gin.Context, req LikeRequest) (*domain.Track, bool) {
+	var (
+		track *domain.Track
+		err   error
+	)
+	switch {
+	case req.TrackID != 0:
+		track, err = h.trackUc.GetByID(c.Request.Context(), req.TrackID)
+	case req.DeezerID != 0:
+		track, err = h.trackUc.GetByDeezerID(c.Request.Context(), req.DeezerID)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "track_id or deezer_id is required"})
+		return nil, false
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return nil, false
+	}
+	if track == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "track not found in system (must be played first)"})
+		return nil, false
+	}
+	return track, true
+}
+
 func (h *Handler) CheckStatus(c *gin.Context) {
 	idStr := c.Param("id")
 	deezerID, err := strconv.ParseInt(idStr, 10, 64)
@@ -252,38 +349,115 @@ func (h *Handler) CheckStatus(c *gin.Context) {
 
 	// 3. Теперь БЕЗОПАСНО создаем ответ, так как мы уверены, что track != nil
 	response := gin.H{
-		"status":    track.Status,
-		"deezer_id": track.DeezerID,
-		"title":     track.Title,
-		"artist":    track.Artist,
-		"cover_url": track.CoverURL,
-	}
-
-	// 4. Если готов — пробуем получить ссылку
-	if track.Status == "ready" && track.FileID != "" {
-		fileLink, err := h.trackUc.GetTelegramFileLink(c.Request.Context(), track.FileID)
-		if err == nil {
-			response["play_link"] = fileLink
-			response["file_id"] = track.FileID
-			response["track_id"] = track.ID // полезно для фронта
+		"status":     track.Status,
+		"deezer_id":  track.DeezerID,
+		"title":      track.Title,
+		"artist":     track.Artist,
+		"cover_url":  track.CoverURL,
+		"attempts":   track.Attempts,
+		"updated_at": track.UpdatedAt,
+	}
+
+	// Причина последней ошибки: при failed — окончательная, в остальных состояниях — с прошлой попытки
+	if track.LastError != "" {
+		response["last_error"] = track.LastError
+		response["failed_stage"] = track.FailedStage
+	}
+
+	// Поиск не уверен в выборе — показываем, из чего выбирали
+	if track.Status == domain.StatusLowConfidence {
+		candidates, err := h.trackUc.GetCandidates(c.Request.Context(), track.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			return
 		}
+		response["candidates"] = candidates
+	}
+
+	// 4. Если готов — отдаем ссылку на наш прокси (ссылку хранилища с токеном наружу не светим)
+	if _, _, ok := track.Locate(); ok && track.Status == domain.StatusReady {
+		response["play_link"] = h.playLink(track.ID)
+		response["file_id"] = track.FileID
+		response["track_id"] = track.ID // полезно для фронта
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// GetQueueStats — оценка ожидания и состояние очередей по этапам: сколько задач ждет
+// (по приоритетам), сколько выполняется и сколько по замерам длится этап (p50/p95).
+// С ?deezer_id= seconds считается для конкретного трека, без него — для нового запроса
 func (h *Handler) GetQueueStats(c *gin.Context) {
-	seconds, err := h.queue.GetEstimatedWaitTime()
+	deezerID, _ := strconv.ParseInt(c.Query("deezer_id"), 10, 64)
+	est, err := h.queue.EstimateTrack(c.Request.Context(), deezerID)
 	if err != nil {
+		slog.Error("Failed to estimate wait time", "deezer_id", deezerID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	stages, err := h.queue.StageStats(c.Request.Context())
+	if err != nil {
+		slog.Error("Failed to get queue stats", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"seconds": seconds,
+		"seconds": int(est.Remaining.Seconds()),
+		"stages":  stages,
 	})
 }
 
+// GetTrackETA — сколько ждать готовности трека. eta_seconds = null, если трек
+// не обрабатывается (idle, failed, blocked…) или его задачи нет в очереди
+func (h *Handler) GetTrackETA(c *gin.Context) {
+	deezerID, err := strconv.ParseInt(c.Param("deezer_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid deezer id"})
+		return
+	}
+
+	track, err := h.trackUc.GetByDeezerID(c.Request.Context(), deezerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if track == nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": "not_found", "error": "track not in database"})
+		return
+	}
+
+	response := gin.H{
+		"deezer_id":   track.DeezerID,
+		"status":      track.Status,
+		"eta_seconds": nil,
+	}
+
+	switch {
+	case track.Status == domain.StatusReady:
+		response["eta_seconds"] = 0
+
+	case domain.IsInProgress(track.Status):
+		est, err := h.queue.EstimateTrack(c.Request.Context(), deezerID)
+		if err != nil {
+			slog.Error("Failed to estimate track ETA", "deezer_id", deezerID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			return
+		}
+		// Задача потерялась — оценивать нечего, трек подберет реконсайлер
+		if est.Found {
+			response["eta_seconds"] = int(est.Remaining.Seconds())
+			response["stage"] = est.Stage
+			response["priority"] = est.Priority
+			response["state"] = est.State
+			response["ahead"] = est.Ahead
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 func (h *Handler) SearchArtistsDZ(c *gin.Context) {
 	query := strings.TrimSpace(c.Query("q"))
 
@@ -2,6 +2,11 @@ package http
 
 import (
 	"log"
+	"log/slog"
+	"music-go-bot/internal/domain"
+	"music-go-bot/internal/usecase"
+	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -35,8 +40,8 @@ func LoggerMiddleware() gin.HandlerFunc {
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Telegram-Init-Data")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -45,3 +50,91 @@ func CORSMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// AuthConfig — настройки проверки initData из Telegram Mini App
+type AuthConfig struct {
+	BotToken string
+	MaxAge   time.Duration // Сколько живет initData после auth_date
+	DevMode  bool          // Разрешает ?user_id= без подписи (только для локальной разработки)
+}
+
+const (
+	initDataHeader = "X-Telegram-Init-Data"
+	ctxUserIDKey   = "user_id"
+)
+
+// TelegramAuthMiddleware проверяет заголовок X-Telegram-Init-Data,
+// обновляет пользователя в базе и кладет проверенный user_id в контекст.
+func TelegramAuthMiddleware(cfg AuthConfig, userUC *usecase.UserUsecase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, status := authenticate(c, cfg, userUC)
+		switch status {
+		case http.StatusOK:
+		case http.StatusUnauthorized:
+			c.AbortWithStatusJSON(status, gin.H{"error": "unauthorized"})
+			return
+		default:
+			c.AbortWithStatusJSON(status, gin.H{"error": "internal error"})
+			return
+		}
+		c.Set(ctxUserIDKey, userID)
+		c.Next()
+	}
+}
+
+// OptionalTelegramAuthMiddleware — для ручек, доступных и без Telegram: с валидным initData
+// кладет user_id в контекст, без него (или с невалидным) пропускает запрос анонимно.
+func OptionalTelegramAuthMiddleware(cfg AuthConfig, userUC *usecase.UserUsecase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(initDataHeader) == "" && !(cfg.DevMode && c.Query("user_id") != "") {
+			c.Next()
+			return
+		}
+		if userID, status := authenticate(c, cfg, userUC); status == http.StatusOK {
+			c.Set(ctxUserIDKey, userID)
+		}
+		c.Next()
+	}
+}
+
+// authenticate достает пользователя из initData. Статус != 200 — запрос не авторизован
+func authenticate(c *gin.Context, cfg AuthConfig, userUC *usecase.UserUsecase) (int64, int) {
+	initData := c.GetHeader(initDataHeader)
+
+	// В dev-режиме без initData доверяем user_id из query (как раньше).
+	// Пользователя заводим сразу: лайки и плейлисты ссылаются на users
+	if initData == "" && cfg.DevMode {
+		userID, err := strconv.ParseInt(c.Query("user_id"), 10, 64)
+		if err != nil || userID == 0 {
+			return 0, http.StatusUnauthorized
+		}
+		if err := userUC.EnsureUser(c.Request.Context(), userID); err != nil {
+			slog.Error("Failed to ensure dev user", "user_id", userID, "error", err)
+			return 0, http.StatusInternalServerError
+		}
+		return userID, http.StatusOK
+	}
+
+	tgUser, err := ValidateInitData(initData, cfg.BotToken, cfg.MaxAge, time.Now())
+	if err != nil {
+		slog.Warn("Init data rejected", "path", c.Request.URL.Path, "error", err)
+		return 0, http.StatusUnauthorized
+	}
+
+	user := &domain.User{
+		ID:        tgUser.ID,
+		Username:  tgUser.Username,
+		FirstName: tgUser.FirstName,
+	}
+	if err := userUC.UpsertUser(c.Request.Context(), user); err != nil {
+		slog.Error("Failed to upsert user", "user_id", tgUser.ID, "error", err)
+		return 0, http.StatusInternalServerError
+	}
+
+	return tgUser.ID, http.StatusOK
+}
+
+// currentUserID достает проверенный user_id, положенный TelegramAuthMiddleware
+func currentUserID(c *gin.Context) int64 {
+	return c.GetInt64(ctxUserIDKey)
+}
@@ -0,0 +1,31 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationsRequest — указатель, чтобы отличить false от отсутствующего поля
+type NotificationsRequest struct {
+	NotifyReady *bool `json:"notify_ready"`
+}
+
+// SetNotifications включает/выключает сообщения в Telegram о готовности треков
+func (h *Handler) SetNotifications(c *gin.Context) {
+	var req NotificationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.NotifyReady == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "notify_ready is required"})
+		return
+	}
+
+	userID := currentUserID(c)
+	if err := h.userUC.SetNotifyReady(c.Request.Context(), userID, *req.NotifyReady); err != nil {
+		slog.Error("Failed to update notification settings", "user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notify_ready": *req.NotifyReady})
+}
@@ -0,0 +1,198 @@
+package http
+
+import (
+	"errors"
+	"log/slog"
+	"music-go-bot/internal/domain"
+	"music-go-bot/internal/usecase"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PlaylistRequest struct {
+	Title string `json:"title"`
+}
+
+type PlaylistTrackRequest struct {
+	DeezerID int64  `json:"deezer_id"`
+	Title    string `json:"title"`
+	Artist   string `json:"artist"`
+	CoverURL string `json:"cover_url"`
+	Duration int    `json:"duration"`
+}
+
+type MoveTrackRequest struct {
+	From *int `json:"from"`
+	To   *int `json:"to"`
+}
+
+func (h *Handler) ListPlaylists(c *gin.Context) {
+	playlists, err := h.playlistUC.List(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		slog.Error("Failed to list playlists", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list playlists"})
+		return
+	}
+
+	c.JSON(http.StatusOK, playlists)
+}
+
+func (h *Handler) CreatePlaylist(c *gin.Context) {
+	var req PlaylistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	playlist, err := h.playlistUC.Create(c.Request.Context(), currentUserID(c), req.Title)
+	if err != nil {
+		h.playlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, playlist)
+}
+
+func (h *Handler) GetPlaylist(c *gin.Context) {
+	playlistID, ok := playlistIDParam(c)
+	if !ok {
+		return
+	}
+
+	playlist, err := h.playlistUC.Get(c.Request.Context(), currentUserID(c), playlistID)
+	if err != nil {
+		h.playlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, playlist)
+}
+
+func (h *Handler) RenamePlaylist(c *gin.Context) {
+	playlistID, ok := playlistIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req PlaylistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	if err := h.playlistUC.Rename(c.Request.Context(), currentUserID(c), playlistID, req.Title); err != nil {
+		h.playlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "renamed"})
+}
+
+func (h *Handler) DeletePlaylist(c *gin.Context) {
+	playlistID, ok := playlistIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.playlistUC.Delete(c.Request.Context(), currentUserID(c), playlistID); err != nil {
+		h.playlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+func (h *Handler) AddPlaylistTrack(c *gin.Context) {
+	playlistID, ok := playlistIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req PlaylistTrackRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.DeezerID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	track := domain.Track{
+		DeezerID: req.DeezerID,
+		Title:    req.Title,
+		Artist:   req.Artist,
+		CoverURL: req.CoverURL,
+		Duration: req.Duration,
+	}
+
+	position, err := h.playlistUC.AddTrack(c.Request.Context(), currentUserID(c), playlistID, track)
+	if err != nil {
+		h.playlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"status": "added", "position": position})
+}
+
+func (h *Handler) RemovePlaylistTrack(c *gin.Context) {
+	playlistID, ok := playlistIDParam(c)
+	if !ok {
+		return
+	}
+
+	position, err := strconv.Atoi(c.Param("position"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid position"})
+		return
+	}
+
+	if err := h.playlistUC.RemoveTrack(c.Request.Context(), currentUserID(c), playlistID, position); err != nil {
+		h.playlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "removed"})
+}
+
+func (h *Handler) MovePlaylistTrack(c *gin.Context) {
+	playlistID, ok := playlistIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req MoveTrackRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.From == nil || req.To == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to are required"})
+		return
+	}
+
+	if err := h.playlistUC.MoveTrack(c.Request.Context(), currentUserID(c), playlistID, *req.From, *req.To); err != nil {
+		h.playlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "moved"})
+}
+
+func playlistIDParam(c *gin.Context) (int64, bool) {
+	playlistID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid playlist id"})
+		return 0, false
+	}
+	return playlistID, true
+}
+
+// playlistError переводит ошибки usecase в HTTP-статусы
+func (h *Handler) playlistError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrPlaylistNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "playlist not found"})
+	case errors.Is(err, domain.ErrInvalidPosition):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid position"})
+	case errors.Is(err, usecase.ErrInvalidPlaylistTitle):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid playlist title"})
+	default:
+		slog.Error("Playlist operation failed", "path", c.Request.URL.Path, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"music-go-bot/internal/domain"
+	"music-go-bot/internal/usecase"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Фоновая предзагрузка после play не должна жить дольше пары запросов к Deezer
+const autoPrefetchTimeout = 30 * time.Second
+
+// Prefetch — заранее поставить в очередь следующие треки альбома, плейлиста или списка
+func (h *Handler) Prefetch(c *gin.Context) {
+	var req usecase.PrefetchContext
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	userID := currentUserID(c)
+	result, err := h.prefetchUC.Prefetch(c.Request.Context(), userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidPrefetchContext):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrPlaylistNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "playlist not found"})
+		default:
+			slog.Error("Failed to prefetch", "user_id", userID, "context", req.Key(), "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to prefetch"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, result)
+}
+
+// autoPrefetch — предзагрузка после play: ответ на play ее не ждет
+func (h *Handler) autoPrefetch(userID int64, pc usecase.PrefetchContext) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), autoPrefetchTimeout)
+		defer cancel()
+
+		if _, err := h.prefetchUC.Prefetch(ctx, userID, pc); err != nil {
+			slog.Warn("Auto prefetch failed", "user_id", userID, "context", pc.Key(), "error", err)
+		}
+	}()
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"errors"
+	"log/slog"
+	"music-go-bot/internal/domain"
+	"music-go-bot/internal/usecase"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReportRequest — youtube_url необязателен: без него берется следующий кандидат поиска
+type ReportRequest struct {
+	YoutubeURL string `json:"youtube_url"`
+}
+
+// ReportTrack — "скачался не тот трек": отклоняем текущее видео и перезапускаем цепочку
+func (h *Handler) ReportTrack(c *gin.Context) {
+	deezerID, err := strconv.ParseInt(c.Param("deezer_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid deezer id"})
+		return
+	}
+
+	var req ReportRequest
+	// Пустое тело допустимо
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+	}
+
+	result, err := h.trackUc.ReportWrongMatch(c.Request.Context(), currentUserID(c), deezerID, req.YoutubeURL)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrTrackNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "track not in database"})
+		case errors.Is(err, usecase.ErrInvalidYoutubeURL):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid youtube url"})
+		case errors.Is(err, domain.ErrVideoRejected):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "this video was already rejected for the track"})
+		case errors.Is(err, domain.ErrTrackBusy):
+			c.JSON(http.StatusConflict, gin.H{"error": "track is being processed, try again later"})
+		default:
+			slog.Error("Failed to report track", "deezer_id", deezerID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to report track"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":    result.Status,
+		"deezer_id": deezerID,
+		"track_id":  result.TrackID,
+	})
+}
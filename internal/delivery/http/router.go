@@ -3,7 +3,7 @@ package http
 import "github.com/gin-gonic/gin"
 
 // Используем твой тип Handler, который мы определили ранее
-func InitRouter(h *Handler) *gin.Engine {
+func InitRouter(h *Handler, authCfg AuthConfig) *gin.Engine {
 	r := gin.New()
 
 	// 1. Сначала подключаем глобальные прослойки
@@ -14,7 +14,10 @@ func InitRouter(h *Handler) *gin.Engine {
 	// 2. Делегируем регистрацию путей самому хендлеру
 	// Это и есть чистый подход: роутер создает каркас,
 	// а хендлер сам говорит, какие пути он обслуживает.
-	h.RegisterRoutes(r)
+	h.RegisterRoutes(r,
+		TelegramAuthMiddleware(authCfg, h.userUC),
+		OptionalTelegramAuthMiddleware(authCfg, h.userUC),
+	)
 
 	return r
 }
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"errors"
+	"log/slog"
+	"music-go-bot/internal/usecase"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Search — общий поиск: /api/search?q=&types=track,artist,album,playlist&limit=&cursor=.
+// Типы ищутся параллельно, у каждой секции свой курсор; next_cursor в корне — следующая
+// страница всех типов сразу. С курсором types не нужен: типы берутся из курсора
+func (h *Handler) Search(c *gin.Context) {
+	q := usecase.SearchQuery{
+		Query:  strings.TrimSpace(c.Query("q")),
+		Cursor: c.Query("cursor"),
+		UserID: currentUserID(c),
+	}
+
+	if raw := c.Query("types"); raw != "" {
+		for _, t := range strings.Split(raw, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				q.Types = append(q.Types, t)
+			}
+		}
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		q.Limit = limit
+	}
+
+	res, err := h.searchUC.Search(c.Request.Context(), q)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidSearchType), errors.Is(err, usecase.ErrInvalidSearchCursor):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			slog.Error("Search failed", "query", q.Query, "error", err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "failed to search Deezer"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,153 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamClient ходит за байтами в хранилище. Общий таймаут не ставим —
+// длинный трек может отдаваться дольше любого разумного лимита.
+var streamClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		ResponseHeaderTimeout: 15 * time.Second,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConnsPerHost:   16,
+	},
+}
+
+// Заголовки ответа хранилища, которые пробрасываем клиенту как есть
+var proxiedHeaders = []string{"Content-Length", "Content-Range", "Accept-Ranges", "ETag", "Last-Modified"}
+
+// playLink — публичная ссылка на аудио трека через наш прокси
+func (h *Handler) playLink(trackID int64) string {
+	return fmt.Sprintf("%s/api/tracks/stream/%d", h.publicBaseURL, trackID)
+}
+
+// StreamTrack проксирует байты трека из хранилища с поддержкой Range/If-Range,
+// чтобы перемотка в <audio> работала, а токен бота не уходил в браузер.
+func (h *Handler) StreamTrack(c *gin.Context) {
+	trackID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid track id"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	track, err := h.trackUc.GetByID(ctx, trackID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if track == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "track not found"})
+		return
+	}
+
+	source, err := h.trackUc.ResolveAudioSource(ctx, track)
+	if err != nil {
+		slog.Warn("Failed to resolve audio source", "track_id", trackID, "error", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "audio is not available"})
+		return
+	}
+
+	// В логах — только ID трека: в source может быть токен или подпись
+	if strings.HasPrefix(source, "file://") {
+		h.serveLocalFile(c, source)
+		return
+	}
+	h.proxyRemote(c, trackID, source)
+}
+
+// serveLocalFile отдает файл с диска; http.ServeContent сам обрабатывает Range и If-Range
+func (h *Handler) serveLocalFile(c *gin.Context, source string) {
+	u, err := url.Parse(source)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid audio source"})
+		return
+	}
+
+	f, err := os.Open(u.Path)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "audio is not available"})
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to stat audio"})
+		return
+	}
+
+	c.Header("Content-Type", "audio/mpeg")
+	http.ServeContent(c.Writer, c.Request, info.Name(), info.ModTime(), f)
+}
+
+func (h *Handler) proxyRemote(c *gin.Context, trackID int64, source string) {
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, source, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid audio source"})
+		return
+	}
+	for _, name := range []string{"Range", "If-Range"} {
+		if v := c.GetHeader(name); v != "" {
+			req.Header.Set(name, v)
+		}
+	}
+
+	resp, err := streamClient.Do(req)
+	if err != nil {
+		// url.Error содержит адрес с токеном — логируем только причину
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		slog.Error("Audio upstream request failed", "track_id", trackID, "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "audio upstream unavailable"})
+		return
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent, http.StatusRequestedRangeNotSatisfiable:
+	default:
+		slog.Error("Audio upstream returned unexpected status", "track_id", trackID, "status", resp.StatusCode)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "audio upstream unavailable"})
+		return
+	}
+
+	header := c.Writer.Header()
+	for _, name := range proxiedHeaders {
+		if v := resp.Header.Get(name); v != "" {
+			header.Set(name, v)
+		}
+	}
+	if header.Get("Accept-Ranges") == "" {
+		header.Set("Accept-Ranges", "bytes")
+	}
+
+	// Telegram отдает application/octet-stream — браузеру нужен аудио-тип
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "audio/") {
+		contentType = "audio/mpeg"
+	}
+	header.Set("Content-Type", contentType)
+
+	c.Status(resp.StatusCode)
+	if _, err := io.Copy(c.Writer, resp.Body); err != nil {
+		// Обычное дело: пользователь перемотал или закрыл плеер
+		slog.Debug("Audio stream interrupted", "track_id", trackID, "error", err)
+	}
+}
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInitDataMissing = errors.New("init data is missing")
+	ErrInitDataHash    = errors.New("init data hash mismatch")
+	ErrInitDataExpired = errors.New("init data is expired")
+)
+
+// TelegramUser — пользователь из поля `user` в initData Mini App
+type TelegramUser struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+}
+
+// ValidateInitData проверяет подпись initData по схеме Telegram WebApp:
+// secret = HMAC_SHA256("WebAppData", botToken), hash = HMAC_SHA256(secret, data_check_string).
+// maxAge <= 0 отключает проверку свежести auth_date.
+func ValidateInitData(initData, botToken string, maxAge time.Duration, now time.Time) (*TelegramUser, error) {
+	if initData == "" {
+		return nil, ErrInitDataMissing
+	}
+
+	values, err := url.ParseQuery(initData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse init data: %w", err)
+	}
+
+	hash := values.Get("hash")
+	if hash == "" {
+		return nil, ErrInitDataHash
+	}
+
+	// data_check_string: все поля кроме hash, отсортированные по ключу, через \n
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		if k == "hash" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+values.Get(k))
+	}
+	dataCheckString := strings.Join(pairs, "\n")
+
+	secret := hmac.New(sha256.New, []byte("WebAppData"))
+	secret.Write([]byte(botToken))
+
+	mac := hmac.New(sha256.New, secret.Sum(nil))
+	mac.Write([]byte(dataCheckString))
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(hash)) {
+		return nil, ErrInitDataHash
+	}
+
+	// Проверяем, что initData не протухла
+	authDate, err := strconv.ParseInt(values.Get("auth_date"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth_date: %w", err)
+	}
+	if maxAge > 0 && now.Sub(time.Unix(authDate, 0)) > maxAge {
+		return nil, ErrInitDataExpired
+	}
+
+	var user TelegramUser
+	if err := json.Unmarshal([]byte(values.Get("user")), &user); err != nil {
+		return nil, fmt.Errorf("invalid user field: %w", err)
+	}
+	if user.ID == 0 {
+		return nil, errors.New("init data has no user id")
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,187 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"music-go-bot/internal/domain"
+	"music-go-bot/internal/usecase"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testBotToken = "123456:ABC-DEF1234ghIkl-zyx57W2v1u123ew11"
+
+// signInitData собирает initData так же, как это делает Telegram
+func signInitData(values url.Values, botToken string) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+values.Get(k))
+	}
+
+	secret := hmac.New(sha256.New, []byte("WebAppData"))
+	secret.Write([]byte(botToken))
+	mac := hmac.New(sha256.New, secret.Sum(nil))
+	mac.Write([]byte(strings.Join(pairs, "\n")))
+
+	signed := url.Values{}
+	for k, v := range values {
+		signed[k] = v
+	}
+	signed.Set("hash", hex.EncodeToString(mac.Sum(nil)))
+	return signed.Encode()
+}
+
+func TestValidateInitData(t *testing.T) {
+	now := time.Unix(1_760_000_000, 0)
+	fields := func(authDate time.Time) url.Values {
+		return url.Values{
+			"query_id":  {"AAHdF6IQAAAAAN0XohDhrOrc"},
+			"user":      {`{"id":279058397,"first_name":"Vlad","username":"vdkfrost","language_code":"ru"}`},
+			"auth_date": {strconv.FormatInt(authDate.Unix(), 10)},
+		}
+	}
+	valid := signInitData(fields(now.Add(-time.Minute)), testBotToken)
+
+	tamper := func(key, value string) string {
+		v, _ := url.ParseQuery(valid)
+		v.Set(key, value)
+		return v.Encode()
+	}
+	without := func(key string) string {
+		v, _ := url.ParseQuery(valid)
+		v.Del(key)
+		return v.Encode()
+	}
+
+	tests := []struct {
+		name     string
+		initData string
+		token    string
+		maxAge   time.Duration
+		wantErr  error // nil — ожидаем пользователя
+		anyError bool  // Ошибка без отдельного sentinel
+	}{
+		{name: "valid", initData: valid, token: testBotToken, maxAge: time.Hour},
+		{name: "valid without max age", initData: signInitData(fields(now.Add(-30*24*time.Hour)), testBotToken), token: testBotToken},
+		{name: "empty", initData: "", token: testBotToken, wantErr: ErrInitDataMissing},
+		{name: "missing hash", initData: without("hash"), token: testBotToken, wantErr: ErrInitDataHash},
+		{name: "tampered user", initData: tamper("user", `{"id":1,"first_name":"Eve"}`), token: testBotToken, wantErr: ErrInitDataHash},
+		{name: "tampered auth_date", initData: tamper("auth_date", strconv.FormatInt(now.Unix(), 10)), token: testBotToken, wantErr: ErrInitDataHash},
+		{name: "tampered hash", initData: tamper("hash", strings.Repeat("0", 64)), token: testBotToken, wantErr: ErrInitDataHash},
+		{name: "extra field", initData: tamper("chat_type", "private"), token: testBotToken, wantErr: ErrInitDataHash},
+		{name: "other bot token", initData: valid, token: "654321:other", wantErr: ErrInitDataHash},
+		{name: "expired", initData: signInitData(fields(now.Add(-2*time.Hour)), testBotToken), token: testBotToken, maxAge: time.Hour, wantErr: ErrInitDataExpired},
+		{name: "bad auth_date", initData: signInitData(url.Values{"user": {`{"id":1}`}, "auth_date": {"yesterday"}}, testBotToken), token: testBotToken, anyError: true},
+		{name: "no user", initData: signInitData(url.Values{"auth_date": {"1760000000"}}, testBotToken), token: testBotToken, anyError: true},
+		{name: "user without id", initData: signInitData(url.Values{"user": {`{"first_name":"x"}`}, "auth_date": {"1760000000"}}, testBotToken), token: testBotToken, anyError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := ValidateInitData(tt.initData, tt.token, tt.maxAge, now)
+			switch {
+			case tt.anyError:
+				if err == nil {
+					t.Fatalf("got user %+v, want error", user)
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if user.ID != 279058397 || user.Username != "vdkfrost" || user.FirstName != "Vlad" {
+					t.Errorf("user = %+v", user)
+				}
+			}
+		})
+	}
+}
+
+type fakeUserRepo struct {
+	upserted []domain.User
+	ensured  []int64
+}
+
+func (r *fakeUserRepo) Upsert(u *domain.User) error            { r.upserted = append(r.upserted, *u); return nil }
+func (r *fakeUserRepo) Ensure(id int64) error                  { r.ensured = append(r.ensured, id); return nil }
+func (r *fakeUserRepo) GetByID(id int64) (*domain.User, error) { return nil, nil }
+func (r *fakeUserRepo) SetNotifyReady(int64, bool) error       { return nil }
+
+func TestTelegramAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	initData := signInitData(url.Values{
+		"user":      {`{"id":42,"first_name":"Ann","username":"ann"}`},
+		"auth_date": {now},
+	}, testBotToken)
+
+	tests := []struct {
+		name       string
+		devMode    bool
+		query      string
+		initData   string
+		wantStatus int
+		wantUserID int64
+		wantUpsert bool
+		wantEnsure bool
+	}{
+		{name: "signed init data", initData: initData, wantStatus: http.StatusOK, wantUserID: 42, wantUpsert: true},
+		{name: "no init data", wantStatus: http.StatusUnauthorized},
+		{name: "user_id ignored outside dev mode", query: "?user_id=7", wantStatus: http.StatusUnauthorized},
+		{name: "dev user_id", devMode: true, query: "?user_id=7", wantStatus: http.StatusOK, wantUserID: 7, wantEnsure: true},
+		{name: "dev bad user_id", devMode: true, query: "?user_id=abc", wantStatus: http.StatusUnauthorized},
+		{name: "dev prefers init data", devMode: true, query: "?user_id=7", initData: initData, wantStatus: http.StatusOK, wantUserID: 42, wantUpsert: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeUserRepo{}
+			cfg := AuthConfig{BotToken: testBotToken, MaxAge: time.Hour, DevMode: tt.devMode}
+
+			var gotUserID int64
+			r := gin.New()
+			r.GET("/me", TelegramAuthMiddleware(cfg, usecase.NewUserUsecase(repo)), func(c *gin.Context) {
+				gotUserID = currentUserID(c)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/me"+tt.query, nil)
+			if tt.initData != "" {
+				req.Header.Set(initDataHeader, tt.initData)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if gotUserID != tt.wantUserID {
+				t.Errorf("user_id = %d, want %d", gotUserID, tt.wantUserID)
+			}
+			if got := len(repo.upserted) == 1 && repo.upserted[0].ID == tt.wantUserID; got != tt.wantUpsert {
+				t.Errorf("upserted = %+v, want upsert %v", repo.upserted, tt.wantUpsert)
+			}
+			if got := len(repo.ensured) == 1 && repo.ensured[0] == tt.wantUserID; got != tt.wantEnsure {
+				t.Errorf("ensured = %v, want ensure %v", repo.ensured, tt.wantEnsure)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"music-go-bot/internal/domain"
+	"net/url"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	trackWaitLimit       = 10 * time.Minute // Сколько ждем готовности одного трека
+	progressEditInterval = 3 * time.Second  // Чаще править сообщение не даст лимит Telegram
+)
+
+// waitTrack ждет, пока цепочка по треку закончится, и возвращает итоговый статус
+// (ready, failed, low_confidence, blocked) или "", если не дождались.
+// onEvent получает промежуточные события (этапы, проценты скачивания).
+func (h *BotHandler) waitTrack(ctx context.Context, deezerID int64, onEvent func(domain.TrackEvent)) string {
+	ctx, cancel := context.WithTimeout(ctx, trackWaitLimit)
+	defer cancel()
+
+	events, err := h.events.Subscribe(ctx, deezerID)
+	if err != nil {
+		log.Printf("Failed to subscribe to track %d: %v", deezerID, err)
+		return ""
+	}
+
+	// Подписка уже есть — теперь смотрим в базу: трек мог закончиться до нее
+	track, err := h.trackUC.GetByDeezerID(ctx, deezerID)
+	if err != nil || track == nil {
+		log.Printf("Failed to load track %d: %v", deezerID, err)
+		return ""
+	}
+	switch track.Status {
+	case domain.StatusReady, domain.StatusFailed, domain.StatusLowConfidence, domain.StatusBlocked:
+		return track.Status
+	case domain.StatusIdle:
+		// Цепочку никто не запускал — ждать нечего
+		return ""
+	}
+
+	// Канал событий закрывается вместе с ctx — это и есть таймаут ожидания
+	for event := range events {
+		switch event.Stage {
+		case domain.StageReady:
+			return domain.StatusReady
+		case domain.StageLowConfidence:
+			return domain.StatusLowConfidence
+		case domain.StageError:
+			return domain.StatusFailed
+		case domain.StageBlocked:
+			return domain.StatusBlocked
+		case domain.StageIdle:
+			// Цепочку отменили — ждать больше нечего
+			return ""
+		}
+		if onEvent != nil {
+			onEvent(event)
+		}
+	}
+	return ""
+}
+
+// deliverTrack запускает цепочку по треку, показывает прогресс в сообщении progress
+// и присылает аудио ответом на msg, когда оно готово
+func (h *BotHandler) deliverTrack(ctx context.Context, msg *tgbotapi.Message, progress int, dzTrack domain.Track) {
+	name := fmt.Sprintf("%s — %s", dzTrack.Artist, dzTrack.Title)
+
+	if _, err := h.trackUC.GetPlaybackState(ctx, dzTrack, 0, domain.PriorityHigh); err != nil {
+		log.Printf("Failed to start track %d: %v", dzTrack.DeezerID, err)
+		h.editProgress(msg.Chat.ID, progress, "❌ "+name+"\nНе удалось запустить обработку трека.")
+		return
+	}
+
+	var lastEdit time.Time
+	status := h.waitTrack(ctx, dzTrack.DeezerID, func(e domain.TrackEvent) {
+		if time.Since(lastEdit) < progressEditInterval {
+			return
+		}
+		lastEdit = time.Now()
+		h.editProgress(msg.Chat.ID, progress, "⏳ "+name+"\n"+stageText(e))
+	})
+
+	switch status {
+	case domain.StatusReady:
+		if h.sendTrack(ctx, msg.Chat.ID, msg.MessageID, dzTrack.DeezerID) {
+			h.deleteMessage(msg.Chat.ID, progress)
+			return
+		}
+		h.editProgress(msg.Chat.ID, progress, "❌ "+name+"\nНе удалось отправить аудио.")
+	case domain.StatusLowConfidence:
+		h.editProgress(msg.Chat.ID, progress, "🤔 "+name+"\nНе нашел точного совпадения на YouTube.")
+	case domain.StatusBlocked:
+		h.editProgress(msg.Chat.ID, progress, "🚫 "+name+"\nЭтот трек недоступен.")
+	case domain.StatusFailed:
+		h.editProgress(msg.Chat.ID, progress, "❌ "+name+"\nНе удалось скачать трек.")
+	default:
+		h.editProgress(msg.Chat.ID, progress, "⌛ "+name+"\nТрек готовится слишком долго, попробуй позже.")
+	}
+}
+
+func stageText(e domain.TrackEvent) string {
+	switch e.Stage {
+	case domain.StageSearching:
+		return "🔎 Ищу на YouTube…"
+	case domain.StageDownloading:
+		if e.Progress > 0 {
+			return fmt.Sprintf("⬇️ Скачиваю… %d%%", e.Progress)
+		}
+		return "⬇️ Скачиваю…"
+	case domain.StageUploading:
+		return "⬆️ Загружаю…"
+	}
+	return "⏳ В очереди…"
+}
+
+// sendTrack присылает готовый трек: из Telegram — по file_id, из других хранилищ — файлом или ссылкой
+func (h *BotHandler) sendTrack(ctx context.Context, chatID int64, replyTo int, deezerID int64) bool {
+	track, err := h.trackUC.GetByDeezerID(ctx, deezerID)
+	if err != nil || track == nil {
+		log.Printf("Failed to load ready track %d: %v", deezerID, err)
+		return false
+	}
+
+	var file tgbotapi.RequestFileData
+	if track.FileID != "" {
+		file = tgbotapi.FileID(track.FileID)
+	} else {
+		source, err := h.trackUC.ResolveAudioSource(ctx, track)
+		if err != nil {
+			log.Printf("Failed to resolve audio for track %d: %v", deezerID, err)
+			return false
+		}
+		if strings.HasPrefix(source, "file://") {
+			u, err := url.Parse(source)
+			if err != nil {
+				return false
+			}
+			file = tgbotapi.FilePath(u.Path)
+		} else {
+			file = tgbotapi.FileURL(source)
+		}
+	}
+
+	audio := tgbotapi.NewAudio(chatID, file)
+	audio.Title = track.Title
+	audio.Performer = track.Artist
+	audio.Duration = track.Duration
+	audio.ReplyToMessageID = replyTo
+
+	if _, err := h.bot.Send(audio); err != nil {
+		log.Printf("Failed to send audio for track %d: %v", deezerID, err)
+		return false
+	}
+	return true
+}
+
+func (h *BotHandler) deleteMessage(chatID int64, messageID int) {
+	if messageID == 0 {
+		return
+	}
+	if _, err := h.bot.Request(tgbotapi.NewDeleteMessage(chatID, messageID)); err != nil {
+		log.Printf("Failed to delete message: %v", err)
+	}
+}
@@ -2,26 +2,45 @@ package telegram
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"music-go-bot/internal/domain"
 	"music-go-bot/internal/usecase"
+	"strconv"
+	"strings"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 type BotHandler struct {
-	bot     *tgbotapi.BotAPI
-	trackUC *usecase.TrackUsecase
-	userUc  *usecase.UserUsecase
+	bot      *tgbotapi.BotAPI
+	trackUC  *usecase.TrackUsecase
+	userUc   *usecase.UserUsecase
+	searchUC *usecase.SearchUsecaseDZ
+	linkUC   *usecase.LinkResolverUsecase
+	batchUC  *usecase.BatchUsecase
+	events   domain.TrackEventSubscriber
 }
 
-func NewBotHandler(bot *tgbotapi.BotAPI, trackUC *usecase.TrackUsecase, userUc *usecase.UserUsecase) *BotHandler {
+func NewBotHandler(
+	bot *tgbotapi.BotAPI,
+	trackUC *usecase.TrackUsecase,
+	userUc *usecase.UserUsecase,
+	searchUC *usecase.SearchUsecaseDZ,
+	linkUC *usecase.LinkResolverUsecase,
+	batchUC *usecase.BatchUsecase,
+	events domain.TrackEventSubscriber,
+) *BotHandler {
 	return &BotHandler{
-		bot:     bot,
-		trackUC: trackUC,
-		userUc:  userUc,
+		bot:      bot,
+		trackUC:  trackUC,
+		userUc:   userUc,
+		searchUC: searchUC,
+		linkUC:   linkUC,
+		batchUC:  batchUC,
+		events:   events,
 	}
 }
 
@@ -41,6 +60,23 @@ func (h *BotHandler) Start(ctx context.Context) {
 			if !ok {
 				return
 			}
+			// Inline-режим: @bot <запрос> в любом чате. Поиск на Deezer идет на каждое нажатие
+			// клавиши — в фоне, чтобы не держать сообщения и апдейты других пользователей
+			if update.InlineQuery != nil {
+				query := update.InlineQuery
+				go func() {
+					inlineCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+					defer cancel()
+					h.handleInlineQuery(inlineCtx, query)
+				}()
+				continue
+			}
+			if update.ChosenInlineResult != nil {
+				// Ждет готовности трека в фоне, поэтому живет на корневом контексте
+				h.handleChosenInlineResult(ctx, update.ChosenInlineResult)
+				continue
+			}
+
 			if update.Message == nil {
 				continue
 			}
@@ -53,9 +89,24 @@ func (h *BotHandler) Start(ctx context.Context) {
 				h.handleAudio(handleCtx, update.Message)
 			}
 
-			// Если будет команда /start для Mini App
-			if update.Message.IsCommand() && update.Message.Command() == "start" {
-				h.handleStart(update.Message)
+			// Ссылки на Deezer/YouTube/Spotify — резолвим в треки и присылаем аудио
+			if !update.Message.IsCommand() {
+				if links := extractMusicLinks(update.Message.Text); len(links) > 0 {
+					h.handleLinks(ctx, update.Message, links)
+				}
+			}
+
+			if update.Message.IsCommand() {
+				switch update.Message.Command() {
+				case "start": // Для Mini App
+					h.handleStart(update.Message)
+				case "wrong":
+					h.handleWrong(handleCtx, update.Message)
+				case "notify":
+					h.handleNotify(handleCtx, update.Message)
+				case "batch":
+					h.handleBatch(handleCtx, update.Message)
+				}
 			}
 
 			cancel() // Освобождаем ресурсы контекста
@@ -140,3 +191,102 @@ func (h *BotHandler) handleStart(msg *tgbotapi.Message) {
 
 	h.bot.Send(reply)
 }
+
+// handleWrong — "/wrong [deezer_id] [youtube_url]": скачался не тот трек.
+// Вместо deezer_id можно ответить командой на сообщение с аудио.
+func (h *BotHandler) handleWrong(ctx context.Context, msg *tgbotapi.Message) {
+	args := strings.Fields(msg.CommandArguments())
+
+	var deezerID int64
+	if len(args) > 0 {
+		if id, err := strconv.ParseInt(args[0], 10, 64); err == nil {
+			deezerID = id
+			args = args[1:]
+		}
+	}
+
+	if deezerID == 0 && msg.ReplyToMessage != nil && msg.ReplyToMessage.Audio != nil {
+		track, err := h.trackUC.GetByFileUniqueID(ctx, msg.ReplyToMessage.Audio.FileUniqueID)
+		if err != nil {
+			log.Printf("Error finding track by file: %v", err)
+		}
+		if track != nil {
+			deezerID = track.DeezerID
+		}
+	}
+
+	if deezerID == 0 {
+		h.reply(msg, "Ответь командой /wrong на сообщение с треком или укажи Deezer ID: /wrong <deezer_id> [ссылка на YouTube]")
+		return
+	}
+
+	var youtubeURL string
+	if len(args) > 0 {
+		youtubeURL = args[0]
+	}
+
+	// Отказ и ожидание ссылаются на пользователя — он должен быть в базе
+	if err := h.userUc.UpsertUser(ctx, userFrom(msg)); err != nil {
+		log.Printf("Failed to upsert user %d: %v", msg.From.ID, err)
+	}
+
+	_, err := h.trackUC.ReportWrongMatch(ctx, msg.From.ID, deezerID, youtubeURL)
+	switch {
+	case err == nil:
+		h.reply(msg, "🔁 Понял, качаю другую версию трека. Пришлю, когда будет готова.")
+	case errors.Is(err, domain.ErrTrackNotFound):
+		h.reply(msg, "❌ Такого трека нет в базе.")
+	case errors.Is(err, usecase.ErrInvalidYoutubeURL):
+		h.reply(msg, "❌ Не понял ссылку на YouTube.")
+	case errors.Is(err, domain.ErrVideoRejected):
+		h.reply(msg, "❌ Это видео уже отмечено как неподходящее для трека.")
+	case errors.Is(err, domain.ErrTrackBusy):
+		h.reply(msg, "⏳ Трек сейчас обрабатывается, попробуй чуть позже.")
+	default:
+		log.Printf("Error reporting track %d: %v", deezerID, err)
+		h.reply(msg, "❌ Не удалось перезапустить обработку трека.")
+	}
+}
+
+// handleNotify — "/notify on|off": присылать ли треки, заказанные в Mini App, когда они готовы
+func (h *BotHandler) handleNotify(ctx context.Context, msg *tgbotapi.Message) {
+	var enabled bool
+	switch strings.ToLower(strings.TrimSpace(msg.CommandArguments())) {
+	case "on":
+		enabled = true
+	case "off":
+		enabled = false
+	default:
+		h.reply(msg, "Уведомления о готовых треках: /notify on — включить, /notify off — выключить.")
+		return
+	}
+
+	if err := h.userUc.UpsertUser(ctx, userFrom(msg)); err != nil {
+		log.Printf("Failed to upsert user %d: %v", msg.From.ID, err)
+	}
+	if err := h.userUc.SetNotifyReady(ctx, msg.From.ID, enabled); err != nil {
+		log.Printf("Failed to update notify setting for %d: %v", msg.From.ID, err)
+		h.reply(msg, "❌ Не удалось сохранить настройку.")
+		return
+	}
+
+	if enabled {
+		h.reply(msg, "🔔 Буду присылать треки, как только они будут готовы.")
+	} else {
+		h.reply(msg, "🔕 Больше не буду присылать готовые треки.")
+	}
+}
+
+func userFrom(msg *tgbotapi.Message) *domain.User {
+	return &domain.User{
+		ID:        msg.From.ID,
+		Username:  msg.From.UserName,
+		FirstName: msg.From.FirstName,
+	}
+}
+
+func (h *BotHandler) reply(msg *tgbotapi.Message, text string) {
+	res := tgbotapi.NewMessage(msg.Chat.ID, text)
+	res.ReplyToMessageID = msg.MessageID
+	h.bot.Send(res)
+}
@@ -0,0 +1,160 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"music-go-bot/internal/domain"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	inlinePageSize  = 20 // Telegram принимает до 50 результатов за ответ
+	inlineCacheTime = 30 // Секунд: статусы треков меняются, долго кэшировать нельзя
+
+	// ID результата-статьи: по нему в chosen_inline_result узнаем, какой трек запускать
+	inlineResultPrefix = "dz:"
+)
+
+// handleInlineQuery отвечает на "@bot <запрос>": готовые треки — сразу аудио из Telegram,
+// остальные — статьи, выбор которых запускает скачивание.
+func (h *BotHandler) handleInlineQuery(ctx context.Context, q *tgbotapi.InlineQuery) {
+	query := strings.TrimSpace(q.Query)
+	if query == "" {
+		return
+	}
+
+	// offset — индекс следующей страницы выдачи Deezer
+	index, _ := strconv.Atoi(q.Offset)
+
+	found, total, err := h.searchUC.SearchDeezerPage(ctx, query, index, inlinePageSize)
+	if err != nil {
+		log.Printf("Inline search failed for %q: %v", query, err)
+		return
+	}
+
+	ids := make([]int64, 0, len(found))
+	for _, t := range found {
+		ids = append(ids, t.DeezerID)
+	}
+	local, err := h.trackUC.GetByDeezerIDs(ctx, ids)
+	if err != nil {
+		// Без локальных данных все равно можно ответить статьями
+		log.Printf("Inline lookup of local tracks failed: %v", err)
+	}
+
+	results := make([]interface{}, 0, len(found))
+	for _, t := range found {
+		if lt := local[t.DeezerID]; lt != nil && lt.FileID != "" && lt.Status == domain.StatusReady {
+			results = append(results, tgbotapi.NewInlineQueryResultCachedAudio(
+				inlineResultPrefix+strconv.FormatInt(t.DeezerID, 10), lt.FileID,
+			))
+			continue
+		}
+		results = append(results, inlineArticle(t))
+	}
+
+	answer := tgbotapi.InlineConfig{
+		InlineQueryID: q.ID,
+		Results:       results,
+		CacheTime:     inlineCacheTime,
+	}
+	if next := index + len(found); len(found) > 0 && next < total {
+		answer.NextOffset = strconv.Itoa(next)
+	}
+
+	if _, err := h.bot.Request(answer); err != nil {
+		log.Printf("Failed to answer inline query: %v", err)
+	}
+}
+
+// inlineArticle — результат для трека, которого еще нет в Telegram.
+// Клавиатура обязательна: без нее в chosen_inline_result не приходит inline_message_id
+// и сообщение потом нельзя отредактировать.
+func inlineArticle(t domain.Track) tgbotapi.InlineQueryResultArticle {
+	article := tgbotapi.NewInlineQueryResultArticle(
+		inlineResultPrefix+strconv.FormatInt(t.DeezerID, 10),
+		t.Title,
+		fmt.Sprintf("⏳ %s — %s\nГотовлю трек…", t.Artist, t.Title),
+	)
+	article.Description = fmt.Sprintf("%s · %s · нажми, чтобы скачать", t.Artist, formatDuration(t.Duration))
+	article.ThumbURL = t.CoverURL
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonURL("Открыть в Deezer", fmt.Sprintf("https://www.deezer.com/track/%d", t.DeezerID)),
+	))
+	article.ReplyMarkup = &keyboard
+	return article
+}
+
+// handleChosenInlineResult запускает цепочку для выбранного трека и, когда аудио готово,
+// правит отправленное сообщение. Приходит, только если у бота включен inline feedback (/setinlinefeedback).
+func (h *BotHandler) handleChosenInlineResult(ctx context.Context, chosen *tgbotapi.ChosenInlineResult) {
+	deezerID, err := strconv.ParseInt(strings.TrimPrefix(chosen.ResultID, inlineResultPrefix), 10, 64)
+	if err != nil || chosen.InlineMessageID == "" {
+		// Готовое аудио (или сообщение без клавиатуры) — править нечего
+		return
+	}
+
+	go h.deliverInline(ctx, deezerID, chosen.InlineMessageID)
+}
+
+func (h *BotHandler) deliverInline(ctx context.Context, deezerID int64, inlineMessageID string) {
+	dzTrack, err := h.searchUC.GetTrack(ctx, deezerID)
+	if err != nil {
+		log.Printf("Inline: failed to load deezer track %d: %v", deezerID, err)
+		h.editInline(inlineMessageID, "❌ Не удалось получить данные трека.", nil)
+		return
+	}
+	name := fmt.Sprintf("%s — %s", dzTrack.Artist, dzTrack.Title)
+
+	if _, err := h.trackUC.GetPlaybackState(ctx, *dzTrack, 0, domain.PriorityHigh); err != nil {
+		log.Printf("Inline: playback state failed for %d: %v", deezerID, err)
+		h.editInline(inlineMessageID, "❌ Не удалось запустить обработку трека.", nil)
+		return
+	}
+
+	switch h.waitTrack(ctx, deezerID, nil) {
+	case domain.StatusReady:
+		h.editInlineReady(inlineMessageID, dzTrack)
+	case domain.StatusBlocked:
+		h.editInline(inlineMessageID, "🚫 "+name+"\nЭтот трек недоступен.", nil)
+	case domain.StatusLowConfidence:
+		h.editInline(inlineMessageID, "🤔 "+name+"\nНе нашел точного совпадения на YouTube.", nil)
+	case domain.StatusFailed:
+		h.editInline(inlineMessageID, "❌ "+name+"\nНе удалось скачать трек.", nil)
+	default:
+		h.editInline(inlineMessageID, "⌛ "+name+"\nТрек готовится слишком долго, попробуй позже.", nil)
+	}
+}
+
+// editInlineReady — текстовое сообщение нельзя превратить в аудио (editMessageMedia работает
+// только с медиа), поэтому даем кнопку, которая снова открывает inline-поиск: там трек уже готовый.
+func (h *BotHandler) editInlineReady(inlineMessageID string, t *domain.Track) {
+	query := fmt.Sprintf("%s %s", t.Artist, t.Title)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.InlineKeyboardButton{Text: "🎵 Отправить трек", SwitchInlineQueryCurrentChat: &query},
+	))
+	h.editInline(inlineMessageID, fmt.Sprintf("✅ %s — %s\nТрек готов!", t.Artist, t.Title), &keyboard)
+}
+
+func (h *BotHandler) editInline(inlineMessageID, text string, keyboard *tgbotapi.InlineKeyboardMarkup) {
+	edit := tgbotapi.EditMessageTextConfig{
+		BaseEdit: tgbotapi.BaseEdit{
+			InlineMessageID: inlineMessageID,
+			ReplyMarkup:     keyboard,
+		},
+		Text: text,
+	}
+	// Для inline-сообщений Telegram возвращает true вместо Message, поэтому Request, а не Send
+	if _, err := h.bot.Request(edit); err != nil {
+		log.Printf("Failed to edit inline message: %v", err)
+	}
+}
+
+func formatDuration(seconds int) string {
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
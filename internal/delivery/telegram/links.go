@@ -0,0 +1,307 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"music-go-bot/internal/domain"
+	"music-go-bot/internal/usecase"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	maxLinksPerMessage = 3
+	linkResolveTimeout = 3 * time.Minute // Плейлист из Spotify — это до сотни поисков на Deezer
+
+	batchStartPollInterval = 2 * time.Second
+)
+
+var musicLinkRe = regexp.MustCompile(`(?i)(?:https?://)?(?:[a-z0-9-]+\.)*(?:deezer\.com|deezer\.page\.link|youtube\.com|youtu\.be|spotify\.com|spotify\.link)/\S+`)
+
+// Короткие ссылки без ID внутри — их сначала раскрываем по редиректу
+var shortLinkHosts = map[string]bool{
+	"deezer.page.link": true,
+	"link.deezer.com":  true,
+	"spotify.link":     true,
+}
+
+// extractMusicLinks находит в тексте ссылки на музыкальные сервисы
+func extractMusicLinks(text string) []string {
+	links := musicLinkRe.FindAllString(text, maxLinksPerMessage)
+	for i, l := range links {
+		// Знаки препинания после ссылки в тексте — не часть URL
+		links[i] = strings.TrimRight(l, ".,!?;:)»\"'")
+	}
+	return links
+}
+
+// parseMusicLink распознает ссылку. short = true — ссылку надо раскрыть и разобрать еще раз
+func parseMusicLink(raw string) (link usecase.MusicLink, short bool, ok bool) {
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return link, false, false
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if shortLinkHosts[host] {
+		return link, true, true
+	}
+
+	segments := strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' })
+
+	switch host {
+	case "deezer.com":
+		// deezer.com/en/track/123 — языковой префикс необязателен
+		if len(segments) == 3 {
+			segments = segments[1:]
+		}
+		if len(segments) != 2 || !isCollectionKind(segments[0]) {
+			return link, false, false
+		}
+		if _, err := strconv.ParseInt(segments[1], 10, 64); err != nil {
+			return link, false, false
+		}
+		return usecase.MusicLink{Source: usecase.LinkDeezer, Kind: segments[0], ID: segments[1]}, false, true
+
+	case "open.spotify.com":
+		// open.spotify.com/intl-de/track/<id>
+		if len(segments) == 3 && strings.HasPrefix(segments[0], "intl-") {
+			segments = segments[1:]
+		}
+		if len(segments) != 2 || !isCollectionKind(segments[0]) {
+			return link, false, false
+		}
+		return usecase.MusicLink{Source: usecase.LinkSpotify, Kind: segments[0], ID: segments[1]}, false, true
+
+	case "youtube.com", "m.youtube.com", "music.youtube.com":
+		// Альбомы YouTube Music — это тоже плейлисты
+		if u.Path == "/playlist" {
+			if list := u.Query().Get("list"); list != "" {
+				return usecase.MusicLink{Source: usecase.LinkYoutube, Kind: usecase.LinkPlaylist, ID: list}, false, true
+			}
+			return link, false, false
+		}
+		fallthrough
+
+	case "youtu.be":
+		if id, ok := usecase.ParseYoutubeID(raw); ok {
+			return usecase.MusicLink{Source: usecase.LinkYoutube, Kind: usecase.LinkTrack, ID: id}, false, true
+		}
+	}
+
+	return link, false, false
+}
+
+func isCollectionKind(kind string) bool {
+	return kind == usecase.LinkTrack || kind == usecase.LinkAlbum || kind == usecase.LinkPlaylist
+}
+
+// handleLinks — пользователь прислал ссылки на треки/альбомы/плейлисты.
+// Каждая обрабатывается в фоне: поиск и скачивание занимают минуты.
+func (h *BotHandler) handleLinks(ctx context.Context, msg *tgbotapi.Message, links []string) {
+	for _, raw := range links {
+		go h.processLink(ctx, msg, raw)
+	}
+}
+
+func (h *BotHandler) processLink(ctx context.Context, msg *tgbotapi.Message, raw string) {
+	resolveCtx, cancel := context.WithTimeout(ctx, linkResolveTimeout)
+	defer cancel()
+
+	link, short, ok := parseMusicLink(raw)
+	if ok && short {
+		expanded, err := h.linkUC.ExpandShortLink(resolveCtx, raw)
+		if err != nil {
+			log.Printf("Failed to expand short link %s: %v", raw, err)
+			h.reply(msg, "❌ Не удалось открыть ссылку.")
+			return
+		}
+		link, short, ok = parseMusicLink(expanded)
+		ok = ok && !short
+	}
+	if !ok {
+		h.reply(msg, "🤷 Не понимаю такую ссылку. Пришли ссылку на трек, альбом или плейлист.")
+		return
+	}
+
+	progress := h.replyProgress(msg, "🔎 Разбираю ссылку…")
+
+	// Треклист альбома/плейлиста загружает воркер пакета
+	if link.Kind != usecase.LinkTrack {
+		h.deliverCollection(ctx, msg, progress, link)
+		return
+	}
+
+	resolved, err := h.linkUC.Resolve(resolveCtx, link)
+	if err != nil {
+		if errors.Is(err, usecase.ErrLinkNotResolved) {
+			h.editProgress(msg.Chat.ID, progress, "😔 Не нашел эти треки на Deezer.")
+			return
+		}
+		log.Printf("Failed to resolve link %s: %v", raw, err)
+		h.editProgress(msg.Chat.ID, progress, "❌ Не удалось получить данные по ссылке.")
+		return
+	}
+
+	h.deliverTrack(ctx, msg, progress, resolved.Tracks[0])
+}
+
+// deliverCollection — альбом/плейлист: пакетная задача (по цепочке на трек), аудио присылаем по порядку
+func (h *BotHandler) deliverCollection(ctx context.Context, msg *tgbotapi.Message, progress int, link usecase.MusicLink) {
+	// Пакет принадлежит пользователю — он должен быть в базе
+	if err := h.userUc.UpsertUser(ctx, userFrom(msg)); err != nil {
+		log.Printf("Failed to upsert user %d: %v", msg.From.ID, err)
+	}
+
+	batch, err := h.batchUC.Create(ctx, &domain.Batch{
+		UserID:   msg.From.ID,
+		Kind:     link.Kind,
+		Source:   link.Source,
+		SourceID: link.ID,
+	})
+	if err != nil {
+		log.Printf("Failed to create batch for %s %s: %v", link.Kind, link.ID, err)
+		h.editProgress(msg.Chat.ID, progress, "❌ Не удалось поставить треки в очередь.")
+		return
+	}
+
+	// Треклист загружает воркер, он же запускает все цепочки — ждем, пока пакет стартует
+	batch, err = h.waitBatchStarted(ctx, batch)
+	if err != nil {
+		log.Printf("Batch %d has not started: %v", batch.ID, err)
+		h.editProgress(msg.Chat.ID, progress, fmt.Sprintf("⏳ Треклист еще загружается. Прогресс: /batch %d", batch.ID))
+		return
+	}
+	if batch.Status == domain.BatchFailed {
+		h.editProgress(msg.Chat.ID, progress, "😔 Не удалось получить треки по ссылке.")
+		return
+	}
+
+	header := fmt.Sprintf("💿 %s: %d треков", batch.Title, batch.Progress.Total)
+	if batch.Missed > 0 {
+		header += fmt.Sprintf(" (%d не нашлось на Deezer)", batch.Missed)
+	}
+	header += fmt.Sprintf("\nПрогресс: /batch %d", batch.ID)
+	h.editProgress(msg.Chat.ID, progress, header+"\n"+batchProgressLine(batch.Progress))
+
+	// Ждем треки по порядку, чтобы они пришли в порядке альбома
+	sent := 0
+	for _, t := range batch.Tracks {
+		if h.waitTrack(ctx, t.DeezerID, nil) == domain.StatusReady && h.sendTrack(ctx, msg.Chat.ID, 0, t.DeezerID) {
+			sent++
+		}
+		if fresh, err := h.batchUC.Get(ctx, batch.UserID, batch.ID); err == nil {
+			batch = fresh
+		}
+		h.editProgress(msg.Chat.ID, progress, header+"\n"+batchProgressLine(batch.Progress))
+	}
+
+	h.editProgress(msg.Chat.ID, progress, fmt.Sprintf("%s\n✅ Отправлено %d из %d", header, sent, batch.Progress.Total))
+}
+
+// waitBatchStarted опрашивает пакет, пока воркер не загрузит треклист (или не сдастся).
+// Ошибка — не дождались за linkResolveTimeout; batch при этом — последнее известное состояние
+func (h *BotHandler) waitBatchStarted(ctx context.Context, batch *domain.Batch) (*domain.Batch, error) {
+	ctx, cancel := context.WithTimeout(ctx, linkResolveTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(batchStartPollInterval)
+	defer ticker.Stop()
+
+	for batch.Status == domain.BatchPending {
+		select {
+		case <-ctx.Done():
+			return batch, ctx.Err()
+		case <-ticker.C:
+		}
+
+		fresh, err := h.batchUC.Get(ctx, batch.UserID, batch.ID)
+		if err != nil {
+			log.Printf("Failed to poll batch %d: %v", batch.ID, err)
+			continue
+		}
+		batch = fresh
+	}
+	return batch, nil
+}
+
+// handleBatch — "/batch <id>": прогресс пакета (альбома/плейлиста целиком)
+func (h *BotHandler) handleBatch(ctx context.Context, msg *tgbotapi.Message) {
+	batchID, err := strconv.ParseInt(strings.TrimSpace(msg.CommandArguments()), 10, 64)
+	if err != nil {
+		h.reply(msg, "Пришли номер пакета: /batch 42. Его видно в сообщении о прогрессе альбома.")
+		return
+	}
+
+	batch, err := h.batchUC.Get(ctx, msg.From.ID, batchID)
+	if errors.Is(err, domain.ErrBatchNotFound) {
+		h.reply(msg, "🤷 Нет такого пакета.")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get batch %d: %v", batchID, err)
+		h.reply(msg, "❌ Не удалось получить прогресс.")
+		return
+	}
+
+	var text string
+	switch batch.Status {
+	case domain.BatchPending:
+		text = "⏳ Треклист еще загружается"
+	case domain.BatchFailed:
+		text = "😔 Не удалось получить треки по ссылке"
+	default:
+		text = fmt.Sprintf("💿 %s\n%s", batch.Title, batchProgressLine(batch.Progress))
+		if batch.Status == domain.BatchCompleted {
+			text += "\n🏁 Пакет завершен"
+		}
+	}
+	h.reply(msg, text)
+}
+
+// batchProgressLine — "⏳ Готово 3 из 12 · ❌ ошибок 1"
+func batchProgressLine(p domain.BatchProgress) string {
+	icon := "⏳"
+	if p.Done() {
+		icon = "✅"
+	}
+	line := fmt.Sprintf("%s Готово %d из %d", icon, p.Ready, p.Total)
+	if p.Failed > 0 {
+		line += fmt.Sprintf(" · ❌ ошибок %d", p.Failed)
+	}
+	if p.Cancelled > 0 {
+		line += fmt.Sprintf(" · отменено %d", p.Cancelled)
+	}
+	return line
+}
+
+// replyProgress отправляет сообщение, которое потом будем править. 0 — не получилось
+func (h *BotHandler) replyProgress(msg *tgbotapi.Message, text string) int {
+	res := tgbotapi.NewMessage(msg.Chat.ID, text)
+	res.ReplyToMessageID = msg.MessageID
+	sent, err := h.bot.Send(res)
+	if err != nil {
+		log.Printf("Failed to send progress message: %v", err)
+		return 0
+	}
+	return sent.MessageID
+}
+
+func (h *BotHandler) editProgress(chatID int64, messageID int, text string) {
+	if messageID == 0 {
+		return
+	}
+	if _, err := h.bot.Request(tgbotapi.NewEditMessageText(chatID, messageID, text)); err != nil {
+		log.Printf("Failed to edit progress message: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package telegram
+
+import (
+	"music-go-bot/internal/usecase"
+	"reflect"
+	"testing"
+)
+
+func TestParseMusicLink(t *testing.T) {
+	deezer := func(kind, id string) usecase.MusicLink {
+		return usecase.MusicLink{Source: usecase.LinkDeezer, Kind: kind, ID: id}
+	}
+	spotify := func(kind, id string) usecase.MusicLink {
+		return usecase.MusicLink{Source: usecase.LinkSpotify, Kind: kind, ID: id}
+	}
+	youtube := func(kind, id string) usecase.MusicLink {
+		return usecase.MusicLink{Source: usecase.LinkYoutube, Kind: kind, ID: id}
+	}
+
+	tests := []struct {
+		raw   string
+		want  usecase.MusicLink
+		short bool
+		ok    bool
+	}{
+		// Deezer
+		{"https://www.deezer.com/track/3135556", deezer(usecase.LinkTrack, "3135556"), false, true},
+		{"https://www.deezer.com/en/album/302127", deezer(usecase.LinkAlbum, "302127"), false, true},
+		{"deezer.com/ru/playlist/908622995?utm_source=deezer", deezer(usecase.LinkPlaylist, "908622995"), false, true},
+		{"https://www.deezer.com/track/abc", usecase.MusicLink{}, false, false},
+		{"https://www.deezer.com/en/artist/27", usecase.MusicLink{}, false, false},
+		{"https://www.deezer.com/", usecase.MusicLink{}, false, false},
+		{"https://deezer.page.link/ZuK2rVDfz5QcLB5b8", usecase.MusicLink{}, true, true},
+		{"https://link.deezer.com/s/30OfHbJ6lpLGEaXSEYwIw", usecase.MusicLink{}, true, true},
+
+		// Spotify
+		{"https://open.spotify.com/track/4uLU6hMCjMI75M1A2tKUQC?si=1a2b3c4d5e", spotify(usecase.LinkTrack, "4uLU6hMCjMI75M1A2tKUQC"), false, true},
+		{"https://open.spotify.com/intl-de/album/1DFixLWuPkv3KT3TnV35m3", spotify(usecase.LinkAlbum, "1DFixLWuPkv3KT3TnV35m3"), false, true},
+		{"open.spotify.com/intl-pt/playlist/37i9dQZF1DXcBWIGoYBM5M?si=x", spotify(usecase.LinkPlaylist, "37i9dQZF1DXcBWIGoYBM5M"), false, true},
+		{"https://open.spotify.com/artist/0OdUWJ0sBjDrqHygGUXeCF", usecase.MusicLink{}, false, false},
+		{"https://open.spotify.com/user/spotify/playlist/37i9dQZF1DXcBWIGoYBM5M", usecase.MusicLink{}, false, false},
+		{"https://spotify.link/AbCdEf123", usecase.MusicLink{}, true, true},
+
+		// YouTube
+		{"https://youtu.be/dQw4w9WgXcQ?si=AbCdEfGh12", youtube(usecase.LinkTrack, "dQw4w9WgXcQ"), false, true},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&list=RDdQw4w9WgXcQ", youtube(usecase.LinkTrack, "dQw4w9WgXcQ"), false, true},
+		{"https://music.youtube.com/watch?v=dQw4w9WgXcQ&si=xyz", youtube(usecase.LinkTrack, "dQw4w9WgXcQ"), false, true},
+		{"https://m.youtube.com/watch?v=dQw4w9WgXcQ", youtube(usecase.LinkTrack, "dQw4w9WgXcQ"), false, true},
+		{"https://youtube.com/shorts/dQw4w9WgXcQ?feature=share", youtube(usecase.LinkTrack, "dQw4w9WgXcQ"), false, true},
+		{"https://music.youtube.com/playlist?list=OLAK5uy_kNqTtj0C8bRWzJ6pDOfUqRlbTQ2tgSaZc", youtube(usecase.LinkPlaylist, "OLAK5uy_kNqTtj0C8bRWzJ6pDOfUqRlbTQ2tgSaZc"), false, true},
+		{"https://www.youtube.com/playlist?list=PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI&si=abc", youtube(usecase.LinkPlaylist, "PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI"), false, true},
+		{"https://www.youtube.com/playlist", usecase.MusicLink{}, false, false},
+		{"https://www.youtube.com/@channel", usecase.MusicLink{}, false, false},
+
+		// Чужие хосты
+		{"https://example.com/track/123", usecase.MusicLink{}, false, false},
+		{"https://soundcloud.com/artist/track", usecase.MusicLink{}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, short, ok := parseMusicLink(tt.raw)
+			if got != tt.want || short != tt.short || ok != tt.ok {
+				t.Errorf("parseMusicLink(%q) = %+v, short=%v, ok=%v; want %+v, short=%v, ok=%v",
+					tt.raw, got, short, ok, tt.want, tt.short, tt.ok)
+			}
+		})
+	}
+}
+
+func TestExtractMusicLinks(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"привет", nil},
+		{"https://www.deezer.com/track/3135556", []string{"https://www.deezer.com/track/3135556"}},
+		{
+			"Послушай https://youtu.be/dQw4w9WgXcQ?si=1, а еще open.spotify.com/track/abc!",
+			[]string{"https://youtu.be/dQw4w9WgXcQ?si=1", "open.spotify.com/track/abc"},
+		},
+		{"(https://music.youtube.com/watch?v=dQw4w9WgXcQ)", []string{"https://music.youtube.com/watch?v=dQw4w9WgXcQ"}},
+		{"«https://deezer.page.link/abc»", []string{"https://deezer.page.link/abc"}},
+		{
+			"a.deezer.com/x b.deezer.com/y c.deezer.com/z d.deezer.com/w",
+			[]string{"a.deezer.com/x", "b.deezer.com/y", "c.deezer.com/z"}, // Не больше maxLinksPerMessage
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := extractMusicLinks(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractMusicLinks(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrAlbumNotFound = errors.New("album not found")
+
+// Album — альбом Deezer (ID — Deezer ID)
+type Album struct {
+	ID          int64     `json:"id"`
+	Title       string    `json:"title"`
+	ArtistID    int64     `json:"artist_id,omitempty"`
+	ArtistName  string    `json:"artist_name,omitempty"`
+	CoverMedium string    `json:"cover_medium,omitempty"`
+	CoverXL     string    `json:"cover_xl,omitempty"`     // Обложка в высоком разрешении (1000x1000)
+	ReleaseDate string    `json:"release_date,omitempty"` // YYYY-MM-DD
+	RecordType  string    `json:"record_type,omitempty"`  // album / single / ep / compile
+	NbTracks    int       `json:"nb_tracks,omitempty"`
+	UpdatedAt   time.Time `json:"-"`
+}
+
+// AlbumRepository — сохраненные альбомы. Артист альбома (ArtistID) должен быть сохранен раньше
+type AlbumRepository interface {
+	// Upsert записывает альбомы; пустые поля не затирают уже известные значения
+	Upsert(ctx context.Context, albums []Album) error
+	// Возвращает nil, nil, если альбома нет
+	GetByID(ctx context.Context, id int64) (*Album, error)
+}
@@ -0,0 +1,17 @@
+package domain
+
+import "context"
+
+// ArtifactStore — локальный кэш файлов, скачанных на этапе download.
+// Файл живет здесь до подтвержденной загрузки в хранилище, поэтому ретраи
+// upload его не теряют, а повторное скачивание того же видео не запускает yt-dlp.
+type ArtifactStore interface {
+	// Get ищет артефакт по ключу источника (YouTube ID). ok=false — нет или истек срок
+	Get(key string) (path string, ok bool)
+	// TempPath — куда писать новый файл (yt-dlp) перед Put
+	TempPath(key string, ext string) (string, error)
+	// Put забирает файл srcPath в кэш под ключом key и возвращает его постоянный путь
+	Put(ctx context.Context, key string, srcPath string) (string, error)
+	// Remove удаляет артефакт — после того как файл надежно лежит в хранилище
+	Remove(key string) error
+}
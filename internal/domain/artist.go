@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrArtistNotFound = errors.New("artist not found")
+
+// Artist — артист Deezer (ID — Deezer ID)
+type Artist struct {
+	ID            int64     `json:"id"`
+	Name          string    `json:"name"`
+	PictureMedium string    `json:"picture_medium,omitempty"`
+	PictureXL     string    `json:"picture_xl,omitempty"`
+	NbAlbum       int       `json:"nb_album,omitempty"`
+	NbFan         int       `json:"nb_fan,omitempty"`
+	UpdatedAt     time.Time `json:"-"`
+}
+
+// ArtistRepository — сохраненные артисты
+type ArtistRepository interface {
+	// Upsert записывает артистов; пустые поля не затирают уже известные значения
+	Upsert(ctx context.Context, artists []Artist) error
+	// Возвращает nil, nil, если артиста нет
+	GetByID(ctx context.Context, id int64) (*Artist, error)
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+)
+
+// Имена бэкендов хранения аудио (пишутся в tracks.storage_backend)
+const (
+	StorageTelegram = "telegram"
+	StorageLocal    = "local"
+	StorageS3       = "s3"
+)
+
+// StoredAudio — результат Put: ключ в бэкенде и, для Telegram, данные файла
+type StoredAudio struct {
+	Key          string
+	FileID       string
+	FileUniqueID string
+}
+
+// AudioStorage — место, где физически лежат аудиофайлы треков
+type AudioStorage interface {
+	// Name возвращает имя бэкенда (StorageTelegram, StorageLocal, StorageS3)
+	Name() string
+	// Put кладет локальный файл filePath в хранилище
+	Put(ctx context.Context, track *Track, filePath string) (StoredAudio, error)
+	// StreamURL отдает ссылку, по которой сервер может вычитать аудио (http(s):// или file://).
+	// Ссылка может содержать секреты (токен бота, подпись S3), поэтому клиентам ее не отдаем —
+	// они слушают через прокси /api/tracks/stream/:id
+	StreamURL(ctx context.Context, key string) (string, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// AudioStorages — все сконфигурированные бэкенды по имени.
+// Трек читается из того бэкенда, в который его положили, даже если основной уже другой.
+type AudioStorages map[string]AudioStorage
+
+func (s AudioStorages) Get(backend string) (AudioStorage, error) {
+	storage, ok := s[backend]
+	if !ok {
+		return nil, fmt.Errorf("audio storage %q is not configured", backend)
+	}
+	return storage, nil
+}
+
+// Locate возвращает бэкенд и ключ, где лежит аудио трека.
+// Старые записи без storage_backend считаются лежащими в Telegram по file_id.
+func (t *Track) Locate() (backend, key string, ok bool) {
+	if t.StorageKey != "" {
+		return t.StorageBackend, t.StorageKey, true
+	}
+	if t.FileID != "" {
+		return StorageTelegram, t.FileID, true
+	}
+	return "", "", false
+}
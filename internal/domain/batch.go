@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrBatchNotFound = errors.New("batch not found")
+
+// Состояния пакетной задачи
+const (
+	BatchPending   = "pending"   // Создан, треклист еще загружается (задача batches:start)
+	BatchRunning   = "running"   // Есть треки, цепочка которых еще идет
+	BatchCompleted = "completed" // Все треки дошли до финального состояния
+	BatchFailed    = "failed"    // Треклист так и не удалось получить
+)
+
+// Batch — альбом или плейлист, поставленный на скачивание целиком:
+// по цепочке на трек, общий прогресс — по статусам треков
+type Batch struct {
+	ID           int64         `json:"id"`
+	UserID       int64         `json:"user_id"`
+	Kind         string        `json:"kind"`      // album / playlist
+	Source       string        `json:"source"`    // deezer / spotify / youtube
+	SourceID     string        `json:"source_id"` // ID альбома/плейлиста в источнике
+	Title        string        `json:"title"`
+	Missed       int           `json:"missed"`         // Сколько треков из YouTube/Spotify не нашлось на Deezer
+	AddToLibrary bool          `json:"add_to_library"` // По завершении готовые треки попадут в библиотеку
+	Status       string        `json:"status"`
+	CreatedAt    time.Time     `json:"created_at"`
+	CompletedAt  *time.Time    `json:"completed_at,omitempty"`
+	Progress     BatchProgress `json:"progress"`
+	Tracks       []BatchTrack  `json:"tracks,omitempty"`
+}
+
+// BatchProgress — сколько треков пакета в каком состоянии
+type BatchProgress struct {
+	Total      int `json:"total"`
+	Ready      int `json:"ready"`
+	Failed     int `json:"failed"`      // failed, low_confidence, blocked
+	InProgress int `json:"in_progress"` // queued … uploading
+	Cancelled  int `json:"cancelled"`   // idle: цепочку отменили или не смогли запустить
+}
+
+// Done — ни одна цепочка пакета больше не идет
+func (p BatchProgress) Done() bool {
+	return p.InProgress == 0
+}
+
+// BatchTrack — трек пакета на своей позиции (позиции с нуля)
+type BatchTrack struct {
+	Position int `json:"position"`
+	Track
+}
+
+type BatchRepository interface {
+	Create(ctx context.Context, batch *Batch) error
+	// AddTracks добавляет треки в порядке trackIDs начиная с позиции 0
+	AddTracks(ctx context.Context, batchID int64, trackIDs []int64) error
+	// Возвращает nil, nil, если пакета нет или он чужой. Progress заполнен
+	GetByID(ctx context.Context, userID, batchID int64) (*Batch, error)
+	GetTracks(ctx context.Context, batchID int64) ([]BatchTrack, error)
+	GetProgress(ctx context.Context, batchID int64) (BatchProgress, error)
+	// GetRunningByTrack — незавершенные пакеты с этим треком
+	GetRunningByTrack(ctx context.Context, trackID int64) ([]Batch, error)
+	// Start переводит pending-пакет в running с названием из треклиста. false — он уже не pending
+	Start(ctx context.Context, batchID int64, title string, missed int) (bool, error)
+	// Fail завершает pending-пакет, треклист которого так и не удалось получить
+	Fail(ctx context.Context, batchID int64) error
+	// Complete переводит пакет в completed. false — его уже завершил кто-то другой
+	Complete(ctx context.Context, batchID int64) (bool, error)
+}
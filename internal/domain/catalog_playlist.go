@@ -0,0 +1,11 @@
+package domain
+
+// CatalogPlaylist — публичный плейлист Deezer из выдачи поиска (ID — Deezer ID).
+// Не путать с Playlist — плейлистом пользователя у нас
+type CatalogPlaylist struct {
+	ID            int64  `json:"id"`
+	Title         string `json:"title"`
+	PictureMedium string `json:"picture_medium,omitempty"`
+	NbTracks      int    `json:"nb_tracks"`
+	Creator       string `json:"creator,omitempty"`
+}
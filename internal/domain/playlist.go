@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrPlaylistNotFound = errors.New("playlist not found")
+	ErrInvalidPosition  = errors.New("invalid playlist position")
+)
+
+type Playlist struct {
+	ID         int64           `json:"id"`
+	UserID     int64           `json:"user_id"`
+	Title      string          `json:"title"`
+	TrackCount int             `json:"track_count"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+	Tracks     []PlaylistEntry `json:"tracks,omitempty"`
+}
+
+// PlaylistEntry — трек на конкретной позиции плейлиста (позиции с нуля)
+type PlaylistEntry struct {
+	Position int       `json:"position"`
+	AddedAt  time.Time `json:"added_at"`
+	Track
+}
+
+// PlaylistRepository — все методы проверяют, что плейлист принадлежит userID.
+// Чужой или несуществующий плейлист — ErrPlaylistNotFound.
+type PlaylistRepository interface {
+	Create(ctx context.Context, playlist *Playlist) error
+	GetByUserID(ctx context.Context, userID int64) ([]Playlist, error)
+	// Возвращает nil, nil, если плейлиста нет
+	GetByID(ctx context.Context, userID, playlistID int64) (*Playlist, error)
+	GetEntries(ctx context.Context, playlistID int64) ([]PlaylistEntry, error)
+	Rename(ctx context.Context, userID, playlistID int64, title string) error
+	Delete(ctx context.Context, userID, playlistID int64) error
+
+	// Добавляет трек в конец, возвращает его позицию
+	AddTrack(ctx context.Context, userID, playlistID, trackID int64) (int, error)
+	// Удаляет запись и сдвигает хвост на одну позицию вверх
+	RemoveTrack(ctx context.Context, userID, playlistID int64, position int) error
+	// Переносит запись с позиции from на позицию to
+	MoveTrack(ctx context.Context, userID, playlistID int64, from, to int) error
+}
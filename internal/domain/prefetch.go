@@ -0,0 +1,34 @@
+package domain
+
+import "context"
+
+// Контексты, из которых предзагружаются следующие треки
+const (
+	PrefetchAlbum        = "album"         // Альбом Deezer
+	PrefetchPlaylist     = "playlist"      // Плейлист Deezer
+	PrefetchUserPlaylist = "user_playlist" // Плейлист пользователя в нашей библиотеке
+	PrefetchTracks       = "tracks"        // Произвольный упорядоченный список (выдача поиска)
+)
+
+// PrefetchState — что пользователь сейчас слушает и какие треки ему поставлены заранее
+type PrefetchState struct {
+	Context   string  `json:"context"`    // Ключ контекста, например "album:302127"
+	DeezerIDs []int64 `json:"deezer_ids"` // Треки, поставленные в очередь предзагрузкой
+}
+
+// PrefetchRegistry хранит состояние предзагрузки пользователей.
+// Один трек могут предзагружать несколько пользователей — отменять его можно,
+// только когда он не нужен никому из них.
+type PrefetchRegistry interface {
+	// Lock сериализует Get → изменение → Save для одного пользователя между запросами
+	// и инстансами API. unlock обязателен; ошибка — не дождались блокировки за время ctx
+	Lock(ctx context.Context, userID int64) (unlock func(), err error)
+	// Возвращает nil, nil, если предзагрузки у пользователя нет
+	Get(ctx context.Context, userID int64) (*PrefetchState, error)
+	Save(ctx context.Context, userID int64, state PrefetchState) error
+	AddOwner(ctx context.Context, deezerID, userID int64) error
+	// RemoveOwner возвращает, сколько пользователей еще предзагружают трек
+	RemoveOwner(ctx context.Context, deezerID, userID int64) (int64, error)
+	// Owners — кто сейчас предзагружает трек
+	Owners(ctx context.Context, deezerID int64) ([]int64, error)
+}
@@ -0,0 +1,15 @@
+package domain
+
+// Приоритет цепочки обработки трека. Едет в payload через все этапы
+const (
+	PriorityHigh = "high" // Пользователь ждет: play, выбор в inline, жалоба на трек
+	PriorityLow  = "low"  // Фон: предзагрузка, импорт альбомов, перепроверка застрявших
+)
+
+// NormalizePriority — пустой или неизвестный приоритет (старые задачи) считаем высоким
+func NormalizePriority(p string) string {
+	if p == PriorityLow {
+		return PriorityLow
+	}
+	return PriorityHigh
+}
@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// DurationStats — скользящая статистика длительности задач одного этапа
+type DurationStats struct {
+	Samples int
+	P50     time.Duration
+	P95     time.Duration
+}
+
+// StageStats хранит длительности выполнения задач этапов (search, download, upload).
+// Общая для всех воркеров, поэтому живет в Redis
+type StageStats interface {
+	RecordDuration(ctx context.Context, stage string, d time.Duration) error
+	Durations(ctx context.Context, stage string) (DurationStats, error)
+}
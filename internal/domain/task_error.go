@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Классы ошибок этапов цепочки. От класса зависит, повторит ли воркер задачу и когда
+const (
+	ErrorPermanent   = "permanent"    // Повтор ничего не изменит: видео удалено, ничего не нашлось
+	ErrorRateLimited = "rate_limited" // Провайдер попросил подождать (Telegram 429 с retry_after)
+	ErrorTransient   = "transient"    // Сеть, таймауты, все неизвестное — обычный ретрай
+)
+
+// TaskError — ошибка этапа с классом и коротким машинным кодом причины
+// ("not_found", "video_unavailable", "too_many_requests"...)
+type TaskError struct {
+	Class      string
+	Reason     string
+	RetryAfter time.Duration // Только для ErrorRateLimited
+	Err        error
+}
+
+func (e *TaskError) Error() string {
+	switch {
+	case e.Err == nil:
+		return e.Reason
+	case e.Reason == "":
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %v", e.Reason, e.Err)
+}
+
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// PermanentError — ретраить бесполезно, трек сразу уходит в failed
+func PermanentError(reason string, err error) error {
+	return &TaskError{Class: ErrorPermanent, Reason: reason, Err: err}
+}
+
+// RateLimitedError — повторить не раньше чем через retryAfter
+func RateLimitedError(reason string, retryAfter time.Duration, err error) error {
+	return &TaskError{Class: ErrorRateLimited, Reason: reason, RetryAfter: retryAfter, Err: err}
+}
+
+// TransientError — временный сбой с известной причиной
+func TransientError(reason string, err error) error {
+	return &TaskError{Class: ErrorTransient, Reason: reason, Err: err}
+}
+
+// ClassifyError разбирает ошибку этапа. Ошибки без класса считаются временными
+func ClassifyError(err error) *TaskError {
+	var se *TaskError
+	if errors.As(err, &se) {
+		return se
+	}
+	return &TaskError{Class: ErrorTransient, Err: err}
+}
+
+// IsPermanentError — ошибку не надо ретраить
+func IsPermanentError(err error) bool {
+	return ClassifyError(err).Class == ErrorPermanent
+}
+
+// RetryAfter — задержка, которую попросил провайдер. ok=false — решает обычный backoff
+func RetryAfter(err error) (time.Duration, bool) {
+	se := ClassifyError(err)
+	if se.Class != ErrorRateLimited || se.RetryAfter <= 0 {
+		return 0, false
+	}
+	return se.RetryAfter, true
+}
+
+// ErrorRecord — как ошибка записывается в tracks.last_error: "[класс] причина: текст"
+func ErrorRecord(err error) string {
+	se := ClassifyError(err)
+	return fmt.Sprintf("[%s] %s", se.Class, se.Error())
+}
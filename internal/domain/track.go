@@ -5,15 +5,9 @@ import (
 	"time"
 )
 
-const (
-	StatusReady      = "ready"
-	StatusProcessing = "processing"
-	StatusError      = "error"
-)
-
 type PlaybackResult struct {
-	Status   string
-	PlayLink string
+	Status  string
+	TrackID int64 // Внутренний ID — по нему строится ссылка на прокси /api/tracks/stream/:id
 }
 type Track struct {
 	ID           int64     `json:"id"`
@@ -27,22 +21,87 @@ type Track struct {
 	Duration     int       `json:"duration"`
 	CreatedAt    time.Time `json:"created_at"`
 	Status       string    `json:"status,omitempty"`
+
+	// Диагностика сбоев цепочки (см. track_state.go)
+	LastError   string    `json:"last_error,omitempty"`
+	FailedStage string    `json:"failed_stage,omitempty"`
+	Attempts    int       `json:"attempts,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Где лежит аудио: имя бэкенда AudioStorage и ключ в нем
+	StorageBackend string `json:"storage_backend,omitempty"`
+	StorageKey     string `json:"-"`
+
+	// Каталог Deezer: артист и альбом трека, место трека в альбоме
+	ArtistID      int64  `json:"artist_id,omitempty"`
+	AlbumID       int64  `json:"album_id,omitempty"`
+	AlbumTitle    string `json:"album_title,omitempty"`
+	DiscNumber    int    `json:"disc_number,omitempty"`
+	TrackPosition int    `json:"track_position,omitempty"`
+
+	// Откуда трек: из Deezer или присланный пользователем файл (у таких нет deezer_id,
+	// их стабильный идентификатор — ID)
+	Source string `json:"source"`
+	// Для загрузок — найдена ли пара на Deezer, и какая
+	MatchStatus     string `json:"match_status,omitempty"`
+	MatchedDeezerID int64  `json:"matched_deezer_id,omitempty"`
 }
 
+// Источники треков
+const (
+	SourceDeezer = "deezer"
+	SourceUpload = "upload"
+)
+
+// Поиск пары на Deezer для загруженного файла
+const (
+	MatchPending   = "pending"
+	MatchMatched   = "matched"   // Пара найдена (MatchedDeezerID)
+	MatchUnmatched = "unmatched" // Уверенного совпадения нет
+)
+
 // TrackRepository — контракт для работы с БД по новой схеме
 type TrackRepository interface {
 	// Сохраняет трек и создает связь с пользователем
 	Save(ctx context.Context, track *Track) error
 	AddToUser(ctx context.Context, userID int64, trackID int64) error
+	// SaveUpload сохраняет присланный файл; один и тот же файл (file_unique_id) — одна запись.
+	// created — запись новая, а не уже известный файл
+	SaveUpload(ctx context.Context, track *Track) (created bool, err error)
 	// Получает библиотеку конкретного пользователя
 	GetByUserID(ctx context.Context, userID int64) ([]Track, error)
+	// Поиск в библиотеке пользователя по названию и артисту, по убыванию релевантности
+	SearchByUserID(ctx context.Context, userID int64, query string) ([]Track, error)
 
 	// Удаляет только связь пользователя с треком (сам трек остается в базе)
 	DeleteFromUser(ctx context.Context, userID int64, trackID int64) error
+	// GetLikedIDs — какие из trackIDs есть в библиотеке пользователя
+	GetLikedIDs(ctx context.Context, userID int64, trackIDs []int64) (map[int64]bool, error)
 
 	// Поиск для кэша
 	//GetByYoutubeID(ctx context.Context, youtubeID string) (*Track, error)
+	GetByID(ctx context.Context, id int64) (*Track, error)
 	GetByDeezerID(ctx context.Context, deezerID int64) (*Track, error)
+	GetByDeezerIDs(ctx context.Context, deezerIDs []int64) (map[int64]*Track, error)
 	GetByFileUniqueID(ctx context.Context, fileUniqueID string) (*Track, error)
-	UpdateStatus(ctx context.Context, deezerID int64, status string) error
+
+	// Transition атомарно переводит трек в состояние to, если это разрешено из текущего.
+	// Иначе — ErrInvalidTransition (значит, трек уже кто-то перевел).
+	Transition(ctx context.Context, deezerID int64, to string) error
+	// RecordError фиксирует неудачную попытку этапа (будет ретрай), статус не меняет
+	RecordError(ctx context.Context, deezerID int64, stage string, reason string) error
+	// MarkFailed переводит трек в failed с причиной и этапом, на котором все упало
+	MarkFailed(ctx context.Context, deezerID int64, stage string, reason string) error
+	// ResetAudio меняет видео-источник трека и забывает старый файл (file_id, ключ хранилища)
+	ResetAudio(ctx context.Context, deezerID int64, youtubeID string) error
+	// GetStale — треки в статусе status, который не менялся с updatedBefore (для реконсайлера)
+	GetStale(ctx context.Context, status string, updatedBefore time.Time, limit int) ([]Track, error)
+
+	// GetPendingUploads — загрузки, для которых еще не искали пару на Deezer (по возрастанию ID, после afterID)
+	GetPendingUploads(ctx context.Context, afterID int64, limit int) ([]Track, error)
+	// LinkUpload связывает загрузку с найденным треком Deezer. Если deezer_id еще никем не занят,
+	// загрузка занимает его сама (claimed) — и этот трек Deezer дальше играет из присланного файла
+	LinkUpload(ctx context.Context, trackID int64, match *Track) (claimed bool, err error)
+	// SetMatchStatus — итог поиска пары без совпадения (MatchUnmatched)
+	SetMatchStatus(ctx context.Context, trackID int64, status string) error
 }
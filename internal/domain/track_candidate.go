@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrTrackNotFound = errors.New("track not found")
+	ErrTrackBusy     = errors.New("track is being processed")
+	ErrVideoRejected = errors.New("video was rejected for this track")
+)
+
+// TrackCandidate — видео с YouTube, которое поиск посчитал возможным совпадением для трека
+type TrackCandidate struct {
+	ID        int64     `json:"id"`
+	TrackID   int64     `json:"track_id"`
+	YoutubeID string    `json:"youtube_id"`
+	Title     string    `json:"title"`
+	Channel   string    `json:"channel"`
+	Duration  int       `json:"duration"`
+	Score     float64   `json:"score"` // 0..1, чем больше — тем увереннее совпадение
+	Rank      int       `json:"rank"`  // 0 — лучший кандидат
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CandidateRepository хранит топ кандидатов последнего поиска по треку
+type CandidateRepository interface {
+	// Replace заменяет кандидатов трека результатами нового поиска
+	Replace(ctx context.Context, trackID int64, candidates []TrackCandidate) error
+	// GetByTrackID возвращает кандидатов по порядку rank
+	GetByTrackID(ctx context.Context, trackID int64) ([]TrackCandidate, error)
+
+	// Reject навсегда исключает видео для трека ("не тот трек")
+	Reject(ctx context.Context, trackID int64, youtubeID string, userID int64) error
+	// GetRejected возвращает отклоненные видео трека
+	GetRejected(ctx context.Context, trackID int64) (map[string]bool, error)
+}
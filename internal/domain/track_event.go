@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Этапы цепочки обработки трека, о которых узнает клиент
+const (
+	StageSearching     = "searching"
+	StageLowConfidence = "low_confidence"
+	StageDownloading   = "downloading"
+	StageUploading     = "uploading"
+	StageReady         = "ready"
+	StageError         = "error"
+	StageIdle          = "idle"    // Цепочку остановили (отмена) — трек снова ждет play
+	StageBlocked       = "blocked" // Трек запрещен к обработке
+)
+
+// TrackEvent — смена этапа обработки трека
+type TrackEvent struct {
+	DeezerID int64     `json:"deezer_id"`
+	TrackID  int64     `json:"track_id,omitempty"`
+	Stage    string    `json:"stage"`
+	Progress int       `json:"progress,omitempty"` // Проценты, только для downloading
+	Error    string    `json:"error,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// IsFinal — после этого события цепочка по треку закончилась (или не идет вовсе)
+func (e TrackEvent) IsFinal() bool {
+	switch e.Stage {
+	case StageReady, StageError, StageLowConfidence, StageIdle, StageBlocked:
+		return true
+	}
+	return false
+}
+
+// TrackEventPublisher — воркеры публикуют через него этапы цепочки
+type TrackEventPublisher interface {
+	Publish(ctx context.Context, event TrackEvent) error
+}
+
+// TrackEventSubscriber — API подписывается на события трека (в том числе из другого процесса).
+// Канал закрывается, когда отменен ctx.
+type TrackEventSubscriber interface {
+	Subscribe(ctx context.Context, deezerID int64) (<-chan TrackEvent, error)
+}
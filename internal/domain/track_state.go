@@ -0,0 +1,67 @@
+package domain
+
+import "errors"
+
+// Состояния трека. Меняются только через TrackRepository.Transition / MarkFailed
+const (
+	StatusIdle          = "idle"           // Запись есть, цепочка не запускалась (или отменена)
+	StatusQueued        = "queued"         // Задача поставлена в очередь
+	StatusSearching     = "searching"      // Ищем видео на YouTube
+	StatusLowConfidence = "low_confidence" // Поиск нашел только сомнительные варианты, качать не стали
+	StatusDownloading   = "downloading"    // yt-dlp качает файл
+	StatusUploading     = "uploading"      // Кладем файл в хранилище
+	StatusReady         = "ready"          // Аудио доступно
+	StatusFailed        = "failed"         // Цепочка упала окончательно (см. last_error, failed_stage)
+	StatusBlocked       = "blocked"        // Трек запрещен к обработке
+)
+
+var ErrInvalidTransition = errors.New("invalid track status transition")
+
+// trackTransitions — разрешенные переходы: из состояния -> в состояния.
+// Повтор того же этапа (ретрай asynq) разрешен отдельно, см. CanTransition.
+var trackTransitions = map[string][]string{
+	StatusIdle:          {StatusQueued, StatusBlocked},
+	StatusQueued:        {StatusSearching, StatusDownloading, StatusIdle, StatusFailed, StatusBlocked},
+	StatusSearching:     {StatusDownloading, StatusLowConfidence, StatusIdle, StatusFailed, StatusBlocked},
+	StatusLowConfidence: {StatusQueued, StatusIdle, StatusBlocked}, // queued — выбрали видео вручную
+	StatusDownloading:   {StatusUploading, StatusIdle, StatusFailed, StatusBlocked},
+	StatusUploading:     {StatusReady, StatusDownloading, StatusFailed, StatusBlocked}, // downloading — файл пропал до загрузки
+	StatusReady:         {StatusQueued, StatusBlocked},                                 // queued — файл пропал из хранилища, качаем заново
+	StatusFailed:        {StatusQueued, StatusBlocked},
+	StatusBlocked:       {StatusIdle},
+}
+
+// CanTransition проверяет, можно ли перевести трек из from в to.
+// Этапы searching/downloading/uploading можно "повторить" — так выглядит ретрай задачи.
+func CanTransition(from, to string) bool {
+	if from == to && IsStageStatus(to) {
+		return true
+	}
+	for _, next := range trackTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionSources — все состояния, из которых можно прийти в to
+func TransitionSources(to string) []string {
+	var sources []string
+	for from := range trackTransitions {
+		if CanTransition(from, to) {
+			sources = append(sources, from)
+		}
+	}
+	return sources
+}
+
+// IsStageStatus — трек сейчас на одном из этапов воркера
+func IsStageStatus(status string) bool {
+	return status == StatusSearching || status == StatusDownloading || status == StatusUploading
+}
+
+// IsInProgress — цепочка по треку запущена и еще не закончилась
+func IsInProgress(status string) bool {
+	return status == StatusQueued || IsStageStatus(status)
+}
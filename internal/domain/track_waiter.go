@@ -0,0 +1,18 @@
+package domain
+
+import "context"
+
+// WaiterRepository — пользователи, которые ждут окончания цепочки по треку
+type WaiterRepository interface {
+	Add(ctx context.Context, trackID, userID int64) error
+	Remove(ctx context.Context, trackID, userID int64) error
+	List(ctx context.Context, trackID int64) ([]int64, error)
+	// Claim атомарно забирает ожидание пользователя. false — его уже забрал другой воркер
+	Claim(ctx context.Context, trackID, userID int64) (bool, error)
+}
+
+// Messenger отправляет пользователю сообщения в Telegram от имени бота
+type Messenger interface {
+	SendAudio(ctx context.Context, chatID int64, track *Track) error
+	SendText(ctx context.Context, chatID int64, text string) error
+}
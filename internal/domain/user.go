@@ -7,10 +7,14 @@ type User struct {
 	Username  string    `json:"username"`
 	FirstName string    `json:"first_name"`
 	CreatedAt time.Time `json:"created_at"`
+	// Присылать ли в чат готовый трек (или причину неудачи), когда закончилась обработка
+	NotifyReady bool `json:"notify_ready"`
 }
 
 // UserRepository — контракт для работы с юзерами
 type UserRepository interface {
 	Upsert(user *User) error
+	Ensure(id int64) error // Создает пустую запись, если пользователя еще нет
 	GetByID(id int64) (*User, error)
+	SetNotifyReady(id int64, enabled bool) error
 }
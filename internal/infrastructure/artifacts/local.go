@@ -0,0 +1,373 @@
+package artifacts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"music-go-bot/internal/domain"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config — настройки кэша артефактов
+type Config struct {
+	Root    string        // Корневая директория кэша
+	MaxSize int64         // Байт на все артефакты; при превышении удаляются давно не использованные. 0 — без лимита
+	TTL     time.Duration // Сколько хранить артефакт с последнего использования. 0 — бессрочно
+}
+
+// Недокачанные файлы yt-dlp старше этого возраста точно брошены
+const tmpMaxAge = 6 * time.Hour
+
+// Пин дольше любого расписания ретраев загрузки: такой пин оставила задача, которая
+// провалилась окончательно, и защищать объект больше незачем
+const pinMaxAge = 7 * 24 * time.Hour
+
+var safeKeyRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// localStore — content-addressed кэш на диске:
+//
+//	objects/ab/ab12…ef.mp3 — файлы по sha256 содержимого
+//	refs/<key>             — ключ источника (YouTube ID) -> путь объекта внутри objects
+//	pins/<key>             — у артефакта есть незавершенная загрузка (пишет Put, снимает Remove)
+//	tmp/                   — файлы, которые еще пишет yt-dlp
+//
+// Время изменения объекта — время последнего использования, по нему работают TTL и LRU.
+// Закрепленные объекты не вытесняются: их удаляет только Remove после загрузки.
+type localStore struct {
+	cfg     Config
+	objects string
+	refs    string
+	pins    string
+	tmp     string
+	mu      sync.Mutex // Put и вытеснение не пересекаются внутри процесса
+}
+
+func NewLocalStore(cfg Config) (domain.ArtifactStore, error) {
+	root, err := filepath.Abs(cfg.Root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid artifacts dir: %w", err)
+	}
+	cfg.Root = root
+
+	s := &localStore{
+		cfg:     cfg,
+		objects: filepath.Join(root, "objects"),
+		refs:    filepath.Join(root, "refs"),
+		pins:    filepath.Join(root, "pins"),
+		tmp:     filepath.Join(root, "tmp"),
+	}
+	for _, dir := range []string{s.objects, s.refs, s.pins, s.tmp} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create artifacts dir: %w", err)
+		}
+	}
+
+	// Хвосты прошлого запуска
+	s.mu.Lock()
+	s.evict()
+	s.mu.Unlock()
+	return s, nil
+}
+
+func (s *localStore) Get(key string) (string, bool) {
+	ref, err := os.ReadFile(s.refPath(key))
+	if err != nil {
+		return "", false
+	}
+
+	p := filepath.Join(s.objects, strings.TrimSpace(string(ref)))
+	info, err := os.Stat(p)
+	if err != nil {
+		// Объект вытеснили — ссылка больше не нужна
+		os.Remove(s.refPath(key))
+		return "", false
+	}
+	pinned := s.isPinned(key)
+	if !pinned && s.cfg.TTL > 0 && time.Since(info.ModTime()) > s.cfg.TTL {
+		s.Remove(key)
+		return "", false
+	}
+
+	touch(p)
+	if pinned {
+		// Артефакт снова идет на загрузку — пин отсчитывается заново
+		touch(s.pinPath(key))
+	}
+	return p, true
+}
+
+func (s *localStore) TempPath(key string, ext string) (string, error) {
+	name := fmt.Sprintf("%s-%d%s", safeKey(key), time.Now().UnixNano(), ext)
+	return filepath.Join(s.tmp, name), nil
+}
+
+func (s *localStore) Put(ctx context.Context, key string, srcPath string) (string, error) {
+	sum, err := hashFile(srcPath)
+	if err != nil {
+		return "", err
+	}
+	rel := filepath.Join(sum[:2], sum+filepath.Ext(srcPath))
+	dst := filepath.Join(s.objects, rel)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(dst); err == nil {
+		// Такое содержимое уже есть (то же видео под другим ID) — второй копии не нужно
+		os.Remove(srcPath)
+		touch(dst)
+	} else {
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return "", fmt.Errorf("failed to create dir: %w", err)
+		}
+		if err := moveFile(srcPath, dst); err != nil {
+			return "", err
+		}
+		// yt-dlp ставит файлу mtime = дату публикации видео, а нам нужно время использования
+		touch(dst)
+	}
+
+	if err := writeFileAtomic(s.refPath(key), rel); err != nil {
+		return "", fmt.Errorf("failed to write artifact ref: %w", err)
+	}
+	// Файл еще не загружен в хранилище — до Remove его не вытесняем
+	if err := writeFileAtomic(s.pinPath(key), rel); err != nil {
+		return "", fmt.Errorf("failed to pin artifact: %w", err)
+	}
+
+	s.evict()
+	return dst, nil
+}
+
+// Remove снимает пин и ссылку key. Сам объект удаляется, только когда на него
+// не ссылается ни один другой ключ (то же содержимое под другим YouTube ID)
+func (s *localStore) Remove(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.pinPath(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to unpin artifact: %w", err)
+	}
+
+	refPath := s.refPath(key)
+	ref, err := os.ReadFile(refPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read artifact ref: %w", err)
+	}
+	if err := os.Remove(refPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove artifact ref: %w", err)
+	}
+
+	rel := strings.TrimSpace(string(ref))
+	if s.referenced(rel) {
+		return nil
+	}
+	if err := os.Remove(filepath.Join(s.objects, rel)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove artifact: %w", err)
+	}
+	return nil
+}
+
+// referenced — на объект rel ссылается хоть один ключ. Вызывается под s.mu
+func (s *localStore) referenced(rel string) bool {
+	entries, _ := os.ReadDir(s.refs)
+	for _, e := range entries {
+		ref, err := os.ReadFile(filepath.Join(s.refs, e.Name()))
+		if err == nil && strings.TrimSpace(string(ref)) == rel {
+			return true
+		}
+	}
+	return false
+}
+
+// isPinned — у артефакта key есть незавершенная загрузка
+func (s *localStore) isPinned(key string) bool {
+	info, err := os.Stat(s.pinPath(key))
+	return err == nil && time.Since(info.ModTime()) <= pinMaxAge
+}
+
+// pinnedObjects — объекты (пути внутри objects) с незавершенной загрузкой.
+// Просроченные пины снимает. Вызывается под s.mu
+func (s *localStore) pinnedObjects() map[string]bool {
+	pinned := make(map[string]bool)
+	entries, _ := os.ReadDir(s.pins)
+	for _, e := range entries {
+		p := filepath.Join(s.pins, e.Name())
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		if time.Since(info.ModTime()) > pinMaxAge {
+			slog.Warn("Dropping stale artifact pin", "key", e.Name(), "age", time.Since(info.ModTime()).String())
+			os.Remove(p)
+			continue
+		}
+		if rel, err := os.ReadFile(p); err == nil {
+			pinned[strings.TrimSpace(string(rel))] = true
+		}
+	}
+	return pinned
+}
+
+type objectInfo struct {
+	path   string
+	size   int64
+	usedAt time.Time
+}
+
+// evict удаляет просроченные артефакты, а затем самые давно не использованные,
+// пока кэш не влезет в MaxSize. Закрепленные (еще не загруженные) объекты не трогает,
+// хотя место они занимают. Вызывается под s.mu.
+// Ссылки на удаленные объекты подчищает Get.
+func (s *localStore) evict() {
+	var (
+		objects []objectInfo
+		total   int64
+	)
+	now := time.Now()
+	pinned := s.pinnedObjects()
+
+	filepath.WalkDir(s.objects, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if rel, err := filepath.Rel(s.objects, p); err == nil && pinned[rel] {
+			total += info.Size()
+			return nil
+		}
+		if s.cfg.TTL > 0 && now.Sub(info.ModTime()) > s.cfg.TTL {
+			s.removeObject(p, "expired")
+			return nil
+		}
+		objects = append(objects, objectInfo{path: p, size: info.Size(), usedAt: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+
+	if s.cfg.MaxSize > 0 && total > s.cfg.MaxSize {
+		sort.Slice(objects, func(i, j int) bool { return objects[i].usedAt.Before(objects[j].usedAt) })
+		for _, o := range objects {
+			if total <= s.cfg.MaxSize {
+				break
+			}
+			s.removeObject(o.path, "size limit")
+			total -= o.size
+		}
+	}
+
+	// Брошенные недокачанные файлы
+	entries, _ := os.ReadDir(s.tmp)
+	for _, e := range entries {
+		info, err := e.Info()
+		if err == nil && now.Sub(info.ModTime()) > tmpMaxAge {
+			os.Remove(filepath.Join(s.tmp, e.Name()))
+		}
+	}
+}
+
+func (s *localStore) removeObject(p, reason string) {
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("Failed to evict artifact", "path", p, "error", err)
+		return
+	}
+	slog.Debug("Artifact evicted", "path", p, "reason", reason)
+}
+
+func (s *localStore) refPath(key string) string {
+	return filepath.Join(s.refs, safeKey(key))
+}
+
+func (s *localStore) pinPath(key string) string {
+	return filepath.Join(s.pins, safeKey(key))
+}
+
+// writeFileAtomic пишет через rename, чтобы читатель не увидел файл наполовину
+func writeFileAtomic(p, data string) error {
+	if err := os.WriteFile(p+".tmp", []byte(data), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(p+".tmp", p); err != nil {
+		os.Remove(p + ".tmp")
+		return err
+	}
+	return nil
+}
+
+// safeKey — YouTube ID годится как имя файла, все остальное хэшируем
+func safeKey(key string) string {
+	if safeKeyRe.MatchString(key) {
+		return key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func hashFile(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", fmt.Errorf("failed to open artifact: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash artifact: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// moveFile переносит файл; между файловыми системами — копированием
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open artifact: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst + ".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create artifact: %w", err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst + ".tmp")
+		return fmt.Errorf("failed to copy artifact: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst + ".tmp")
+		return fmt.Errorf("failed to copy artifact: %w", err)
+	}
+	if err := os.Rename(dst+".tmp", dst); err != nil {
+		os.Remove(dst + ".tmp")
+		return fmt.Errorf("failed to move artifact: %w", err)
+	}
+	os.Remove(src)
+	return nil
+}
+
+func touch(p string) {
+	now := time.Now()
+	os.Chtimes(p, now, now)
+}
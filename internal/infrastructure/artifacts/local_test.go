@@ -0,0 +1,137 @@
+package artifacts
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T, cfg Config) *localStore {
+	t.Helper()
+	cfg.Root = t.TempDir()
+	s, err := NewLocalStore(cfg)
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	return s.(*localStore)
+}
+
+// put кладет в кэш файл с содержимым content так же, как это делает загрузчик
+func put(t *testing.T, s *localStore, key, content string) string {
+	t.Helper()
+	src, err := s.TempPath(key, ".mp3")
+	if err != nil {
+		t.Fatalf("TempPath: %v", err)
+	}
+	if err := os.WriteFile(src, []byte(content), 0644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	p, err := s.Put(context.Background(), key, src)
+	if err != nil {
+		t.Fatalf("Put(%s): %v", key, err)
+	}
+	return p
+}
+
+// unpin снимает пин, не трогая ссылку: загрузка завершилась, а объект остался в кэше
+func unpin(t *testing.T, s *localStore, key string) {
+	t.Helper()
+	if err := os.Remove(s.pinPath(key)); err != nil {
+		t.Fatalf("unpin %s: %v", key, err)
+	}
+}
+
+func age(t *testing.T, p string, d time.Duration) {
+	t.Helper()
+	at := time.Now().Add(-d)
+	if err := os.Chtimes(p, at, at); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+}
+
+func exists(p string) bool {
+	_, err := os.Stat(p)
+	return err == nil
+}
+
+func TestPinnedArtifactSurvivesEviction(t *testing.T) {
+	s := newTestStore(t, Config{MaxSize: 10, TTL: time.Hour})
+
+	pinned := put(t, s, "pinned", "aaaaaaaa")
+	// Самый старый и просроченный — первый кандидат и на TTL, и на LRU
+	age(t, pinned, 2*time.Hour)
+
+	loose := put(t, s, "loose", "bbbbbbbb")
+	unpin(t, s, "loose")
+	age(t, loose, time.Minute)
+
+	// Третий объект выводит кэш за лимит и запускает вытеснение
+	put(t, s, "fresh", "cccccccc")
+
+	if !exists(pinned) {
+		t.Error("pinned artifact was evicted")
+	}
+	if p, ok := s.Get("pinned"); !ok || p != pinned {
+		t.Errorf("Get(pinned) = %q, %v; want %q, true", p, ok, pinned)
+	}
+	if exists(loose) {
+		t.Error("unpinned artifact should be evicted to fit MaxSize")
+	}
+	if _, ok := s.Get("loose"); ok {
+		t.Error("Get(loose) should miss after eviction")
+	}
+}
+
+func TestRemoveKeepsSharedObject(t *testing.T) {
+	s := newTestStore(t, Config{})
+
+	first := put(t, s, "first", "same audio")
+	second := put(t, s, "second", "same audio")
+	if first != second {
+		t.Fatalf("identical content stored twice: %q and %q", first, second)
+	}
+
+	if err := s.Remove("first"); err != nil {
+		t.Fatalf("Remove(first): %v", err)
+	}
+	if _, ok := s.Get("first"); ok {
+		t.Error("Get(first) should miss after Remove")
+	}
+	if p, ok := s.Get("second"); !ok || p != second {
+		t.Errorf("Get(second) = %q, %v; want %q, true", p, ok, second)
+	}
+
+	if err := s.Remove("second"); err != nil {
+		t.Fatalf("Remove(second): %v", err)
+	}
+	if exists(second) {
+		t.Error("object should be deleted once no key references it")
+	}
+}
+
+func TestGetSkipsExpiredArtifact(t *testing.T) {
+	s := newTestStore(t, Config{TTL: time.Hour})
+
+	expired := put(t, s, "expired", "old audio")
+	unpin(t, s, "expired")
+	age(t, expired, 2*time.Hour)
+
+	// Пока загрузка не завершилась, TTL не действует
+	pinned := put(t, s, "pinned", "pinned audio")
+	age(t, pinned, 2*time.Hour)
+
+	if _, ok := s.Get("expired"); ok {
+		t.Error("Get returned an expired artifact")
+	}
+	if exists(expired) {
+		t.Error("expired artifact should be removed on Get")
+	}
+	if exists(filepath.Join(s.refs, "expired")) {
+		t.Error("ref of expired artifact should be removed")
+	}
+	if _, ok := s.Get("pinned"); !ok {
+		t.Error("pinned artifact should be returned regardless of TTL")
+	}
+}
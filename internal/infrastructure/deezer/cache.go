@@ -0,0 +1,113 @@
+package deezer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Cache хранит сырые ответы Deezer по ключу запроса (путь с параметрами).
+// Ошибки кэша не должны ломать запрос — клиент их только логирует
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// Сколько ответов держим в памяти по умолчанию
+const defaultMemoryCacheSize = 5000
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// memoryCache — кэш в памяти процесса с ограничением на число записей
+type memoryCache struct {
+	mu         sync.Mutex
+	entries    map[string]memoryEntry
+	maxEntries int
+}
+
+func NewMemoryCache(maxEntries int) Cache {
+	if maxEntries <= 0 {
+		maxEntries = defaultMemoryCacheSize
+	}
+	return &memoryCache{
+		entries:    make(map[string]memoryEntry),
+		maxEntries: maxEntries,
+	}
+}
+
+func (c *memoryCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+func (c *memoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		c.evict()
+	}
+	c.entries[key] = memoryEntry{value: value, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// evict освобождает место: сначала протухшие записи, а если их нет — любую
+func (c *memoryCache) evict() {
+	now := time.Now()
+	for key, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, key)
+		}
+	}
+	if len(c.entries) < c.maxEntries {
+		return
+	}
+	for key := range c.entries {
+		delete(c.entries, key)
+		return
+	}
+}
+
+// redisCache — общий кэш для всех инстансов API и воркеров
+type redisCache struct {
+	client *redis.Client
+}
+
+func NewRedisCache(client *redis.Client) Cache {
+	return &redisCache{client: client}
+}
+
+func cacheKey(key string) string {
+	return "deezer:cache:" + key
+}
+
+func (c *redisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := c.client.Get(ctx, cacheKey(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (c *redisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.client.Set(ctx, cacheKey(key), value, ttl).Err()
+}
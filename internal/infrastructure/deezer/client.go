@@ -0,0 +1,356 @@
+// Package deezer — клиент публичного API Deezer: общий лимит запросов, ретраи при
+// превышении квоты, кэш ответов и настраиваемый адрес API (в тестах — локальная заглушка)
+package deezer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"music-go-bot/internal/domain"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// API — все, что приложению нужно от Deezer
+type API interface {
+	// Поиск: найденное на странице с позиции index и общее число результатов (limit 0 — по умолчанию Deezer)
+	SearchTracks(ctx context.Context, query string, index, limit int) ([]domain.Track, int, error)
+	SearchArtists(ctx context.Context, query string, index, limit int) ([]domain.Artist, int, error)
+	SearchAlbums(ctx context.Context, query string, index, limit int) ([]domain.Album, int, error)
+	SearchPlaylists(ctx context.Context, query string, index, limit int) ([]domain.CatalogPlaylist, int, error)
+
+	Track(ctx context.Context, id int64) (*domain.Track, error)
+	Artist(ctx context.Context, id int64) (*domain.Artist, error)
+	ArtistTop(ctx context.Context, id int64, limit int) ([]domain.Track, error)
+	ArtistAlbums(ctx context.Context, id int64, limit int) ([]domain.Album, error)
+	ArtistRelated(ctx context.Context, id int64, limit int) ([]domain.Artist, error)
+	// Album — альбом и весь его треклист по порядку (с номерами диска и трека)
+	Album(ctx context.Context, id int64) (*domain.Album, []domain.Track, error)
+	// Playlist — название плейлиста и до maxTracks его треков по порядку
+	Playlist(ctx context.Context, id int64, maxTracks int) (string, []domain.Track, error)
+}
+
+const DefaultBaseURL = "https://api.deezer.com"
+
+// Пауза перед первым повтором, дальше 2с, 4с: квота считается по окну в 5 секунд.
+// Переменная, чтобы тесты не ждали
+var retryBaseDelay = time.Second
+
+// Квота Deezer: 50 запросов за 5 секунд
+const (
+	quotaRequests = 50
+	quotaWindow   = 5 * time.Second
+)
+
+const (
+	defaultTimeout    = 10 * time.Second
+	defaultSearchTTL  = 10 * time.Minute
+	defaultEntityTTL  = time.Hour
+	defaultMaxRetries = 3
+
+	// Защита от бесконечной пагинации: больше треков в альбоме не бывает даже у боксов
+	maxAlbumTracks = 500
+)
+
+type Config struct {
+	BaseURL    string        // Пусто — DefaultBaseURL
+	Timeout    time.Duration // На один HTTP-запрос
+	SearchTTL  time.Duration // Сколько живут в кэше результаты поиска
+	EntityTTL  time.Duration // Сколько живут треки, артисты, альбомы, плейлисты
+	MaxRetries int           // Повторы при превышении квоты и перегрузке Deezer
+	Cache      Cache         // nil — без кэша
+}
+
+func (c Config) withDefaults() Config {
+	if c.BaseURL == "" {
+		c.BaseURL = DefaultBaseURL
+	}
+	c.BaseURL = strings.TrimRight(c.BaseURL, "/")
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeout
+	}
+	if c.SearchTTL <= 0 {
+		c.SearchTTL = defaultSearchTTL
+	}
+	if c.EntityTTL <= 0 {
+		c.EntityTTL = defaultEntityTTL
+	}
+	if c.MaxRetries <= 0 {
+		c.MaxRetries = defaultMaxRetries
+	}
+	return c
+}
+
+// Client — реализация API поверх HTTP. Один на процесс: лимитер общий для всех запросов
+type Client struct {
+	cfg     Config
+	http    *http.Client
+	limiter *rate.Limiter
+}
+
+func NewClient(cfg Config) *Client {
+	cfg = cfg.withDefaults()
+	return &Client{
+		cfg:     cfg,
+		http:    &http.Client{Timeout: cfg.Timeout},
+		limiter: rate.NewLimiter(rate.Every(quotaWindow/quotaRequests), quotaRequests),
+	}
+}
+
+func (c *Client) SearchTracks(ctx context.Context, query string, index, limit int) ([]domain.Track, int, error) {
+	return search(ctx, c, "track", query, index, limit, trackPayload.toDomain)
+}
+
+func (c *Client) SearchArtists(ctx context.Context, query string, index, limit int) ([]domain.Artist, int, error) {
+	return search(ctx, c, "artist", query, index, limit, artistPayload.toDomain)
+}
+
+func (c *Client) SearchAlbums(ctx context.Context, query string, index, limit int) ([]domain.Album, int, error) {
+	return search(ctx, c, "album", query, index, limit, albumPayload.toDomain)
+}
+
+func (c *Client) SearchPlaylists(ctx context.Context, query string, index, limit int) ([]domain.CatalogPlaylist, int, error) {
+	return search(ctx, c, "playlist", query, index, limit, playlistPayload.toDomain)
+}
+
+// search — GET /search/<kind>
+func search[T any, D any](ctx context.Context, c *Client, kind, query string, index, limit int, toDomain func(T) D) ([]D, int, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	if index > 0 {
+		params.Set("index", strconv.Itoa(index))
+	}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	var resp page[T]
+	if err := c.get(ctx, "/search/"+kind+"?"+params.Encode(), c.cfg.SearchTTL, &resp); err != nil {
+		return nil, 0, fmt.Errorf("deezer %s search: %w", kind, err)
+	}
+	return mapSlice(resp.Data, toDomain), resp.Total, nil
+}
+
+func (c *Client) Track(ctx context.Context, id int64) (*domain.Track, error) {
+	var d trackPayload
+	if err := c.get(ctx, fmt.Sprintf("/track/%d", id), c.cfg.EntityTTL, &d); err != nil {
+		return nil, fmt.Errorf("deezer track %d: %w", id, err)
+	}
+
+	track := d.toDomain()
+	return &track, nil
+}
+
+func (c *Client) Artist(ctx context.Context, id int64) (*domain.Artist, error) {
+	var a artistPayload
+	if err := c.get(ctx, fmt.Sprintf("/artist/%d", id), c.cfg.EntityTTL, &a); err != nil {
+		return nil, fmt.Errorf("deezer artist %d: %w", id, err)
+	}
+
+	artist := a.toDomain()
+	return &artist, nil
+}
+
+func (c *Client) ArtistTop(ctx context.Context, id int64, limit int) ([]domain.Track, error) {
+	var resp page[trackPayload]
+	if err := c.get(ctx, fmt.Sprintf("/artist/%d/top?limit=%d", id, limit), c.cfg.EntityTTL, &resp); err != nil {
+		return nil, fmt.Errorf("deezer artist %d top: %w", id, err)
+	}
+	return mapSlice(resp.Data, trackPayload.toDomain), nil
+}
+
+func (c *Client) ArtistAlbums(ctx context.Context, id int64, limit int) ([]domain.Album, error) {
+	var resp page[albumPayload]
+	if err := c.get(ctx, fmt.Sprintf("/artist/%d/albums?limit=%d", id, limit), c.cfg.EntityTTL, &resp); err != nil {
+		return nil, fmt.Errorf("deezer artist %d albums: %w", id, err)
+	}
+
+	// В альбомах артиста самого артиста нет — проставляем
+	albums := mapSlice(resp.Data, albumPayload.toDomain)
+	for i := range albums {
+		albums[i].ArtistID = id
+	}
+	return albums, nil
+}
+
+func (c *Client) ArtistRelated(ctx context.Context, id int64, limit int) ([]domain.Artist, error) {
+	var resp page[artistPayload]
+	if err := c.get(ctx, fmt.Sprintf("/artist/%d/related?limit=%d", id, limit), c.cfg.EntityTTL, &resp); err != nil {
+		return nil, fmt.Errorf("deezer artist %d related: %w", id, err)
+	}
+	return mapSlice(resp.Data, artistPayload.toDomain), nil
+}
+
+func (c *Client) Album(ctx context.Context, id int64) (*domain.Album, []domain.Track, error) {
+	var a albumPayload
+	if err := c.get(ctx, fmt.Sprintf("/album/%d", id), c.cfg.EntityTTL, &a); err != nil {
+		return nil, nil, fmt.Errorf("deezer album %d: %w", id, err)
+	}
+	album := a.toDomain()
+
+	// Номера диска и трека есть только в отдельном треклисте
+	var tracks []domain.Track
+	next := fmt.Sprintf("/album/%d/tracks?limit=100", id)
+	for next != "" && len(tracks) < maxAlbumTracks {
+		var resp page[trackPayload]
+		if err := c.get(ctx, next, c.cfg.EntityTTL, &resp); err != nil {
+			return nil, nil, fmt.Errorf("deezer album %d tracks: %w", id, err)
+		}
+		for _, d := range resp.Data {
+			t := d.toDomain()
+			// В треках внутри альбома нет объекта album — берем у самого альбома
+			t.AlbumID = album.ID
+			t.AlbumTitle = album.Title
+			if t.CoverURL == "" {
+				t.CoverURL = album.CoverMedium
+			}
+			tracks = append(tracks, t)
+		}
+		next = c.nextPath(resp.Next)
+	}
+
+	return &album, tracks, nil
+}
+
+func (c *Client) Playlist(ctx context.Context, id int64, maxTracks int) (string, []domain.Track, error) {
+	var playlist struct {
+		Title string `json:"title"`
+	}
+	if err := c.get(ctx, fmt.Sprintf("/playlist/%d", id), c.cfg.EntityTTL, &playlist); err != nil {
+		return "", nil, fmt.Errorf("deezer playlist %d: %w", id, err)
+	}
+
+	var tracks []domain.Track
+	next := fmt.Sprintf("/playlist/%d/tracks?limit=50", id)
+	for next != "" && len(tracks) < maxTracks {
+		var resp page[trackPayload]
+		if err := c.get(ctx, next, c.cfg.EntityTTL, &resp); err != nil {
+			return "", nil, fmt.Errorf("deezer playlist %d tracks: %w", id, err)
+		}
+		tracks = append(tracks, mapSlice(resp.Data, trackPayload.toDomain)...)
+		next = c.nextPath(resp.Next)
+	}
+	if len(tracks) > maxTracks {
+		tracks = tracks[:maxTracks]
+	}
+
+	return playlist.Title, tracks, nil
+}
+
+// nextPath — путь следующей страницы. Deezer отдает абсолютную ссылку на свой хост,
+// а ходить надо на настроенный BaseURL (и ключ кэша не должен зависеть от хоста)
+func (c *Client) nextPath(next string) string {
+	if next == "" {
+		return ""
+	}
+	u, err := url.Parse(next)
+	if err != nil {
+		return ""
+	}
+	return u.RequestURI()
+}
+
+// get — GET пути path (с параметрами) через кэш; ответ раскладывается в dst
+func (c *Client) get(ctx context.Context, path string, ttl time.Duration, dst any) error {
+	body, err := c.fetch(ctx, path, ttl)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, dst)
+}
+
+func (c *Client) fetch(ctx context.Context, path string, ttl time.Duration) ([]byte, error) {
+	if c.cfg.Cache != nil {
+		body, ok, err := c.cfg.Cache.Get(ctx, path)
+		if err != nil {
+			slog.Warn("Deezer cache read failed", "path", path, "error", err)
+		}
+		if ok {
+			return body, nil
+		}
+	}
+
+	var (
+		body []byte
+		err  error
+	)
+	for attempt := 0; ; attempt++ {
+		body, err = c.do(ctx, path)
+		if err == nil || !retryable(err) || attempt >= c.cfg.MaxRetries {
+			break
+		}
+
+		delay := retryBaseDelay << attempt
+		slog.Warn("Deezer request throttled, retrying", "path", path, "attempt", attempt+1, "delay", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// В кэш — только успешные ответы: ошибки квоты и "нет данных" кэшировать нельзя
+	if c.cfg.Cache != nil {
+		if err := c.cfg.Cache.Set(ctx, path, body, ttl); err != nil {
+			slog.Warn("Deezer cache write failed", "path", path, "error", err)
+		}
+	}
+	return body, nil
+}
+
+// do — один запрос к Deezer в пределах общей квоты
+func (c *Client) do(ctx context.Context, path string) ([]byte, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.BaseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// Сеть или таймаут — временно, как и перегрузка Deezer
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: read body: %v", ErrUnavailable, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &APIError{HTTPStatus: resp.StatusCode}
+	}
+
+	// Ошибки Deezer приходят со статусом 200 в поле error
+	var envelope struct {
+		Error *apiError `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err == nil && envelope.Error != nil {
+		return nil, &APIError{
+			HTTPStatus: resp.StatusCode,
+			Type:       envelope.Error.Type,
+			Message:    envelope.Error.Message,
+			Code:       envelope.Error.Code,
+		}
+	}
+	return body, nil
+}
+
+var _ API = (*Client)(nil)
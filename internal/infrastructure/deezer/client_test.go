@@ -0,0 +1,179 @@
+package deezer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// standIn — локальная заглушка Deezer: ответ по пути (с параметрами) и счетчик запросов
+type standIn struct {
+	mu    sync.Mutex
+	hits  map[string]int
+	reply func(uri string, hit int) (status int, body string)
+}
+
+func newStandIn(t *testing.T, reply func(uri string, hit int) (int, string)) (*standIn, *Client) {
+	t.Helper()
+
+	s := &standIn{hits: map[string]int{}, reply: reply}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.hits[r.URL.RequestURI()]++
+		hit := s.hits[r.URL.RequestURI()]
+		s.mu.Unlock()
+
+		status, body := s.reply(r.URL.RequestURI(), hit)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+
+	delay := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	t.Cleanup(func() { retryBaseDelay = delay })
+
+	return s, NewClient(Config{BaseURL: srv.URL, MaxRetries: 2, Cache: NewMemoryCache(0)})
+}
+
+func (s *standIn) count(uri string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits[uri]
+}
+
+func deezerError(code int, typ string) string {
+	return fmt.Sprintf(`{"error":{"type":%q,"message":"stand-in error","code":%d}}`, typ, code)
+}
+
+// Deezer отдает ошибки со статусом 200 объектом error в теле
+func TestClientInBodyErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{"data not found", http.StatusOK, deezerError(800, "DataException"), ErrNotFound},
+		{"quota", http.StatusOK, deezerError(4, "Exception"), ErrQuota},
+		{"invalid query", http.StatusOK, deezerError(600, "InvalidQueryException"), ErrInvalidQuery},
+		{"missing parameter", http.StatusOK, deezerError(501, "MissingParameterException"), ErrInvalidQuery},
+		{"http 404", http.StatusNotFound, "", ErrNotFound},
+		{"http 503", http.StatusServiceUnavailable, "", ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, c := newStandIn(t, func(string, int) (int, string) { return tt.status, tt.body })
+
+			_, err := c.Track(context.Background(), 1)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			for _, other := range []error{ErrNotFound, ErrQuota, ErrInvalidQuery, ErrUnavailable} {
+				if other != tt.want && errors.Is(err, other) {
+					t.Errorf("err %v also matches %v", err, other)
+				}
+			}
+		})
+	}
+}
+
+func TestClientRetriesQuota(t *testing.T) {
+	s, c := newStandIn(t, func(_ string, hit int) (int, string) {
+		if hit == 1 {
+			return http.StatusOK, deezerError(4, "Exception")
+		}
+		return http.StatusOK, `{"id":1,"title":"Группа крови","duration":285,"artist":{"id":7,"name":"Кино"}}`
+	})
+
+	track, err := c.Track(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Track: %v", err)
+	}
+	if track.Title != "Группа крови" || track.Artist != "Кино" || track.ArtistID != 7 {
+		t.Errorf("track = %+v", track)
+	}
+	if n := s.count("/track/1"); n != 2 {
+		t.Errorf("requests = %d, want 2 (quota error, then success)", n)
+	}
+}
+
+func TestClientGivesUpAfterMaxRetries(t *testing.T) {
+	s, c := newStandIn(t, func(string, int) (int, string) { return http.StatusOK, deezerError(4, "Exception") })
+
+	if _, err := c.Track(context.Background(), 1); !errors.Is(err, ErrQuota) {
+		t.Fatalf("err = %v, want ErrQuota", err)
+	}
+	if n := s.count("/track/1"); n != 3 {
+		t.Errorf("requests = %d, want 3 (first try and MaxRetries=2)", n)
+	}
+}
+
+func TestClientCachesOnlySuccess(t *testing.T) {
+	s, c := newStandIn(t, func(uri string, _ int) (int, string) {
+		if uri == "/track/2" {
+			return http.StatusOK, deezerError(800, "DataException")
+		}
+		return http.StatusOK, `{"id":1,"title":"Кукушка"}`
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Track(ctx, 1); err != nil {
+			t.Fatalf("Track(1): %v", err)
+		}
+		if _, err := c.Track(ctx, 2); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Track(2) err = %v, want ErrNotFound", err)
+		}
+	}
+
+	if n := s.count("/track/1"); n != 1 {
+		t.Errorf("successful response fetched %d times, want 1 (cached)", n)
+	}
+	if n := s.count("/track/2"); n != 2 {
+		t.Errorf("error response fetched %d times, want 2 (not cached)", n)
+	}
+}
+
+// Ссылка next у Deezer абсолютная и ведет на его хост — клиент должен идти по ней на BaseURL
+func TestClientAlbumFollowsNextOnStandIn(t *testing.T) {
+	s, c := newStandIn(t, func(uri string, _ int) (int, string) {
+		switch uri {
+		case "/album/10":
+			return http.StatusOK, `{"id":10,"title":"Звезда по имени Солнце","cover_medium":"cover","artist":{"id":7,"name":"Кино"}}`
+		case "/album/10/tracks?limit=100":
+			return http.StatusOK, `{"data":[{"id":1,"title":"Звезда по имени Солнце","disk_number":1,"track_position":1},
+				{"id":2,"title":"Невесёлая песня","disk_number":1,"track_position":2}],
+				"total":3,"next":"https://api.deezer.com/album/10/tracks?limit=100&index=2"}`
+		case "/album/10/tracks?limit=100&index=2":
+			return http.StatusOK, `{"data":[{"id":3,"title":"Место для шага вперёд","disk_number":1,"track_position":3}],"total":3}`
+		}
+		return http.StatusNotFound, ""
+	})
+
+	album, tracks, err := c.Album(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("Album: %v", err)
+	}
+	if album.Title != "Звезда по имени Солнце" || album.ArtistID != 7 {
+		t.Errorf("album = %+v", album)
+	}
+	if len(tracks) != 3 {
+		t.Fatalf("got %d tracks, want 3", len(tracks))
+	}
+	for i, tr := range tracks {
+		if tr.TrackPosition != i+1 || tr.AlbumID != 10 || tr.CoverURL != "cover" {
+			t.Errorf("track %d = %+v", i, tr)
+		}
+	}
+	if n := s.count("/album/10/tracks?limit=100&index=2"); n != 1 {
+		t.Errorf("second page fetched %d times from stand-in, want 1", n)
+	}
+}
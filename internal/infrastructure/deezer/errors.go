@@ -0,0 +1,60 @@
+package deezer
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Классы ошибок Deezer — проверяются через errors.Is на *APIError
+var (
+	ErrNotFound     = errors.New("deezer: not found")
+	ErrQuota        = errors.New("deezer: quota exceeded")
+	ErrInvalidQuery = errors.New("deezer: invalid query")
+	ErrUnavailable  = errors.New("deezer: service unavailable")
+)
+
+// Коды из поля error.code ответа Deezer
+const (
+	codeQuota        = 4
+	codeParameter    = 500
+	codeMissingParam = 501
+	codeQueryInvalid = 600
+	codeServiceBusy  = 700
+	codeDataNotFound = 800
+)
+
+// APIError — ошибка от Deezer: либо объект error в теле (приходит со статусом 200),
+// либо неуспешный HTTP-статус
+type APIError struct {
+	HTTPStatus int
+	Type       string
+	Message    string
+	Code       int
+}
+
+func (e *APIError) Error() string {
+	if e.Code != 0 {
+		return fmt.Sprintf("deezer api error %d (%s): %s", e.Code, e.Type, e.Message)
+	}
+	return fmt.Sprintf("deezer api: unexpected status %d", e.HTTPStatus)
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.Code == codeDataNotFound || e.HTTPStatus == http.StatusNotFound
+	case ErrQuota:
+		return e.Code == codeQuota || e.HTTPStatus == http.StatusTooManyRequests
+	case ErrInvalidQuery:
+		return e.Code == codeParameter || e.Code == codeMissingParam || e.Code == codeQueryInvalid
+	case ErrUnavailable:
+		return e.Code == codeServiceBusy || e.HTTPStatus >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// retryable — квота и перегрузка Deezer проходят сами, есть смысл повторить
+func retryable(err error) bool {
+	return errors.Is(err, ErrQuota) || errors.Is(err, ErrUnavailable)
+}
@@ -0,0 +1,127 @@
+package deezer
+
+import "music-go-bot/internal/domain"
+
+// Объекты в ответах Deezer и их перевод в доменные модели
+
+type apiError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+}
+
+type trackPayload struct {
+	ID       int64  `json:"id"`
+	Title    string `json:"title"`
+	Duration int    `json:"duration"`
+	// Есть только в треклисте альбома (/album/:id/tracks)
+	DiskNumber    int `json:"disk_number"`
+	TrackPosition int `json:"track_position"`
+	Artist        struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	} `json:"artist"`
+	Album struct {
+		ID          int64  `json:"id"`
+		Title       string `json:"title"`
+		CoverMedium string `json:"cover_medium"`
+	} `json:"album"`
+}
+
+func (d trackPayload) toDomain() domain.Track {
+	return domain.Track{
+		DeezerID:      d.ID,
+		Title:         d.Title,
+		Artist:        d.Artist.Name,
+		Duration:      d.Duration,
+		CoverURL:      d.Album.CoverMedium,
+		ArtistID:      d.Artist.ID,
+		AlbumID:       d.Album.ID,
+		AlbumTitle:    d.Album.Title,
+		DiscNumber:    d.DiskNumber,
+		TrackPosition: d.TrackPosition,
+	}
+}
+
+type artistPayload struct {
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	PictureMedium string `json:"picture_medium"`
+	PictureXL     string `json:"picture_xl"`
+	NbAlbum       int    `json:"nb_album"`
+	NbFan         int    `json:"nb_fan"`
+}
+
+func (a artistPayload) toDomain() domain.Artist {
+	return domain.Artist{
+		ID:            a.ID,
+		Name:          a.Name,
+		PictureMedium: a.PictureMedium,
+		PictureXL:     a.PictureXL,
+		NbAlbum:       a.NbAlbum,
+		NbFan:         a.NbFan,
+	}
+}
+
+type albumPayload struct {
+	ID          int64  `json:"id"`
+	Title       string `json:"title"`
+	CoverMedium string `json:"cover_medium"`
+	CoverXL     string `json:"cover_xl"`
+	ReleaseDate string `json:"release_date"`
+	RecordType  string `json:"record_type"`
+	NbTracks    int    `json:"nb_tracks"`
+	Artist      struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	} `json:"artist"`
+}
+
+func (a albumPayload) toDomain() domain.Album {
+	return domain.Album{
+		ID:          a.ID,
+		Title:       a.Title,
+		ArtistID:    a.Artist.ID,
+		ArtistName:  a.Artist.Name,
+		CoverMedium: a.CoverMedium,
+		CoverXL:     a.CoverXL,
+		ReleaseDate: a.ReleaseDate,
+		RecordType:  a.RecordType,
+		NbTracks:    a.NbTracks,
+	}
+}
+
+type playlistPayload struct {
+	ID            int64  `json:"id"`
+	Title         string `json:"title"`
+	PictureMedium string `json:"picture_medium"`
+	NbTracks      int    `json:"nb_tracks"`
+	User          struct {
+		Name string `json:"name"`
+	} `json:"user"`
+}
+
+func (p playlistPayload) toDomain() domain.CatalogPlaylist {
+	return domain.CatalogPlaylist{
+		ID:            p.ID,
+		Title:         p.Title,
+		PictureMedium: p.PictureMedium,
+		NbTracks:      p.NbTracks,
+		Creator:       p.User.Name,
+	}
+}
+
+// page — страница списка: data, общее число и ссылка на следующую страницу
+type page[T any] struct {
+	Data  []T    `json:"data"`
+	Total int    `json:"total"`
+	Next  string `json:"next"`
+}
+
+func mapSlice[T any, D any](items []T, toDomain func(T) D) []D {
+	res := make([]D, 0, len(items))
+	for _, it := range items {
+		res = append(res, toDomain(it))
+	}
+	return res
+}
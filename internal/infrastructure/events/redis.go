@@ -0,0 +1,84 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"music-go-bot/internal/domain"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisTrackEvents разносит события треков через Redis pub/sub,
+// чтобы они доходили от воркеров до API, запущенного в другом процессе.
+type RedisTrackEvents struct {
+	client *redis.Client
+}
+
+func NewRedisTrackEvents(client *redis.Client) *RedisTrackEvents {
+	return &RedisTrackEvents{client: client}
+}
+
+func channelName(deezerID int64) string {
+	return fmt.Sprintf("track:events:%d", deezerID)
+}
+
+func (e *RedisTrackEvents) Publish(ctx context.Context, event domain.TrackEvent) error {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	if err := e.client.Publish(ctx, channelName(event.DeezerID), payload).Err(); err != nil {
+		return fmt.Errorf("redis publish: %w", err)
+	}
+	return nil
+}
+
+func (e *RedisTrackEvents) Subscribe(ctx context.Context, deezerID int64) (<-chan domain.TrackEvent, error) {
+	sub := e.client.Subscribe(ctx, channelName(deezerID))
+
+	// Дожидаемся подтверждения подписки, чтобы не потерять события сразу после возврата
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return nil, fmt.Errorf("redis subscribe: %w", err)
+	}
+
+	out := make(chan domain.TrackEvent, 16)
+	go func() {
+		defer close(out)
+		defer sub.Close()
+
+		messages := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				var event domain.TrackEvent
+				if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+					slog.Warn("Skipping malformed track event", "channel", msg.Channel, "error", err)
+					continue
+				}
+
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
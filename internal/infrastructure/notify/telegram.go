@@ -0,0 +1,33 @@
+package notify
+
+import (
+	"context"
+	"music-go-bot/internal/domain"
+	"music-go-bot/internal/infrastructure/tgerr"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// telegramMessenger пишет пользователям в личку от имени бота (chat_id = user_id)
+type telegramMessenger struct {
+	bot *tgbotapi.BotAPI
+}
+
+func NewTelegramMessenger(bot *tgbotapi.BotAPI) domain.Messenger {
+	return &telegramMessenger{bot: bot}
+}
+
+func (m *telegramMessenger) SendAudio(ctx context.Context, chatID int64, track *domain.Track) error {
+	audio := tgbotapi.NewAudio(chatID, tgbotapi.FileID(track.FileID))
+	audio.Title = track.Title
+	audio.Performer = track.Artist
+	audio.Duration = track.Duration
+
+	_, err := m.bot.Send(audio)
+	return tgerr.Classify(err)
+}
+
+func (m *telegramMessenger) SendText(ctx context.Context, chatID int64, text string) error {
+	_, err := m.bot.Send(tgbotapi.NewMessage(chatID, text))
+	return tgerr.Classify(err)
+}
@@ -0,0 +1,154 @@
+package prefetch
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"music-go-bot/internal/domain"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Состояние живет, пока пользователь слушает: без новых запросов через stateTTL
+// оно пропадает само, а брошенные задачи доведет очередь
+const stateTTL = 6 * time.Hour
+
+// Блокировка обновления состояния: TTL — на случай, если держатель упал, не отпустив ее
+const (
+	lockTTL   = 30 * time.Second
+	lockRetry = 50 * time.Millisecond
+)
+
+// Снимаем блокировку, только если она еще наша (могла истечь и достаться другому)
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// redisRegistry: prefetch:user:<id> — JSON состояния пользователя,
+// prefetch:owners:<deezer_id> — множество пользователей, предзагружающих трек,
+// prefetch:lock:<id> — блокировка обновления состояния пользователя
+type redisRegistry struct {
+	client *redis.Client
+}
+
+func NewRedisRegistry(client *redis.Client) domain.PrefetchRegistry {
+	return &redisRegistry{client: client}
+}
+
+func userKey(userID int64) string {
+	return fmt.Sprintf("prefetch:user:%d", userID)
+}
+
+func ownersKey(deezerID int64) string {
+	return fmt.Sprintf("prefetch:owners:%d", deezerID)
+}
+
+func lockKey(userID int64) string {
+	return fmt.Sprintf("prefetch:lock:%d", userID)
+}
+
+func (r *redisRegistry) Lock(ctx context.Context, userID int64) (func(), error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("failed to generate lock token: %w", err)
+	}
+	value := hex.EncodeToString(token)
+
+	for {
+		ok, err := r.client.SetNX(ctx, lockKey(userID), value, lockTTL).Result()
+		if err != nil {
+			return nil, fmt.Errorf("redis lock prefetch state: %w", err)
+		}
+		if ok {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("redis lock prefetch state: %w", ctx.Err())
+		case <-time.After(lockRetry):
+		}
+	}
+
+	return func() {
+		// Контекст запроса к этому моменту может быть уже отменен
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := unlockScript.Run(unlockCtx, r.client, []string{lockKey(userID)}, value).Err(); err != nil {
+			slog.Warn("Failed to release prefetch lock", "user_id", userID, "error", err)
+		}
+	}, nil
+}
+
+func (r *redisRegistry) Get(ctx context.Context, userID int64) (*domain.PrefetchState, error) {
+	raw, err := r.client.Get(ctx, userKey(userID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis get prefetch state: %w", err)
+	}
+
+	var state domain.PrefetchState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal prefetch state: %w", err)
+	}
+	return &state, nil
+}
+
+func (r *redisRegistry) Save(ctx context.Context, userID int64, state domain.PrefetchState) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal prefetch state: %w", err)
+	}
+	if err := r.client.Set(ctx, userKey(userID), raw, stateTTL).Err(); err != nil {
+		return fmt.Errorf("redis set prefetch state: %w", err)
+	}
+	return nil
+}
+
+func (r *redisRegistry) AddOwner(ctx context.Context, deezerID, userID int64) error {
+	pipe := r.client.TxPipeline()
+	pipe.SAdd(ctx, ownersKey(deezerID), strconv.FormatInt(userID, 10))
+	pipe.Expire(ctx, ownersKey(deezerID), stateTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis add prefetch owner: %w", err)
+	}
+	return nil
+}
+
+func (r *redisRegistry) RemoveOwner(ctx context.Context, deezerID, userID int64) (int64, error) {
+	pipe := r.client.TxPipeline()
+	pipe.SRem(ctx, ownersKey(deezerID), strconv.FormatInt(userID, 10))
+	left := pipe.SCard(ctx, ownersKey(deezerID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("redis remove prefetch owner: %w", err)
+	}
+	return left.Val(), nil
+}
+
+func (r *redisRegistry) Owners(ctx context.Context, deezerID int64) ([]int64, error) {
+	members, err := r.client.SMembers(ctx, ownersKey(deezerID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis get prefetch owners: %w", err)
+	}
+
+	owners := make([]int64, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseInt(m, 10, 64)
+		if err != nil {
+			continue
+		}
+		owners = append(owners, id)
+	}
+	return owners, nil
+}
@@ -2,28 +2,46 @@ package queue
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"music-go-bot/internal/domain"
 	"music-go-bot/internal/tasks"
+	"strings"
+	"time"
 
 	"github.com/hibiken/asynq"
 )
 
+var priorities = []string{domain.PriorityHigh, domain.PriorityLow}
+
+// Сколько ждать выхода отмененной задачи, прежде чем ставить этап заново
+const (
+	cancelWaitTimeout  = 5 * time.Second
+	cancelPollInterval = 100 * time.Millisecond
+)
+
 // AsynqQueue — обертка над клиентом asynq
 type AsynqQueue struct {
 	client    *asynq.Client
 	inspector *asynq.Inspector
+	cfg       Config
+	stats     domain.StageStats // Замеры длительности этапов для оценок ожидания
 }
 
 // TrackQueue — интерфейс, который мы прокидываем в UseCase.
-// Теперь тут два метода для нашей цепочки.
+// userID — кто запустил цепочку, едет через все этапы до уведомления.
+// priority (domain.PriorityHigh/Low) выбирает очередь этапа и тоже едет по цепочке.
 type TrackQueue interface {
-	EnqueueDownload(ctx context.Context, trackID int64, ytID string) error
-	EnqueueUpload(ctx context.Context, trackID int64, filePath string) error
-	GetEstimatedWaitTime() (int, error)
-	EnqueueSearch(ctx context.Context, DeezerID int64) error
+	EnqueueDownload(ctx context.Context, trackID int64, ytID string, userID int64, priority string) error
+	EnqueueUpload(ctx context.Context, trackID int64, filePath string, userID int64, priority string) error
+	GetEstimatedWaitTime(deezerID int64) (int, error)
+	EnqueueSearch(ctx context.Context, DeezerID int64, userID int64, priority string) error
+	EnqueueNotify(ctx context.Context, trackID int64, userID int64) error
+	EnqueueMatchUpload(ctx context.Context, trackID int64) error
+	CancelTrack(ctx context.Context, deezerID int64) (CancelStats, error)
 }
 
-func NewAsynqQueue(redisAddr string) *AsynqQueue {
+func NewAsynqQueue(redisAddr string, cfg Config, stats domain.StageStats) *AsynqQueue {
 	redisOpt := asynq.RedisClientOpt{Addr: redisAddr}
 
 	// Инициализируем оба компонента
@@ -33,44 +51,76 @@ func NewAsynqQueue(redisAddr string) *AsynqQueue {
 	return &AsynqQueue{
 		client:    client,
 		inspector: inspector,
+		cfg:       cfg.WithDefaults(),
+		stats:     stats,
 	}
 }
 
 // 1. Задача на скачивание (вызывается из API/TrackUsecase)
-func (q *AsynqQueue) EnqueueDownload(ctx context.Context, trackID int64, ytID string) error {
-	t, err := tasks.NewDownloadYoutubeTask(trackID, ytID)
+func (q *AsynqQueue) EnqueueDownload(ctx context.Context, trackID int64, ytID string, userID int64, priority string) error {
+	t, err := tasks.NewDownloadYoutubeTask(trackID, ytID, userID, priority)
 	if err != nil {
 		return fmt.Errorf("failed to create download task: %w", err)
 	}
 
-	_, err = q.client.Enqueue(t, asynq.MaxRetry(2)) // YouTube может капризничать, дадим 3 попытки
-	return err
+	return q.enqueueStage(ctx, t, StageDownload, priority, trackID, asynq.MaxRetry(2)) // YouTube может капризничать, дадим 3 попытки
 }
 
 // 2. Задача на загрузку (вызывается из YTDownloaderUsecase)
-func (q *AsynqQueue) EnqueueUpload(ctx context.Context, trackID int64, filePath string) error {
-	// ВАЖНО: Тебе нужно создать функцию NewTelegramUploadTask в пакете tasks
-	t, err := tasks.NewTelegramUploadTask(trackID, filePath)
+func (q *AsynqQueue) EnqueueUpload(ctx context.Context, trackID int64, filePath string, userID int64, priority string) error {
+	t, err := tasks.NewTelegramUploadTask(trackID, filePath, userID, priority)
 	if err != nil {
 		return fmt.Errorf("failed to create upload task: %w", err)
 	}
 
 	// Для Telegram загрузки ставим MaxRetry побольше,
 	// так как файл уже скачан, и мы просто ждем окна в лимитах API
-	_, err = q.client.Enqueue(t, asynq.MaxRetry(5))
-	return err
+	return q.enqueueStage(ctx, t, StageUpload, priority, trackID, asynq.MaxRetry(5))
 }
 
-func (q *AsynqQueue) GetEstimatedWaitTime() (int, error) {
-	info, err := q.inspector.GetQueueInfo("default")
+func (q *AsynqQueue) EnqueueSearch(ctx context.Context, deezerID int64, userID int64, priority string) error {
+	t, err := tasks.NewSearchYoutubeTask(deezerID, userID, priority)
 	if err != nil {
-		return 0, err
+		return fmt.Errorf("failed to create search task: %w", err)
 	}
 
-	totalSubTasks := info.Pending + info.Active + 1
+	// MaxRetry(3), так как поиск — это легкий, но зависящий от сети процесс.
+	return q.enqueueStage(ctx, t, StageSearch, priority, deezerID, asynq.MaxRetry(3))
+}
+
+// EnqueueNotify — финальный этап: рассылка результата тем, кто ждет трек
+func (q *AsynqQueue) EnqueueNotify(ctx context.Context, trackID int64, userID int64) error {
+	t, err := tasks.NewNotifyTask(trackID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to create notify task: %w", err)
+	}
+
+	// Telegram может ответить 429 — ретраи переотправят только тем, кому не дошло
+	return q.enqueueUnique(ctx, t, DefaultQueue, trackID, asynq.MaxRetry(3))
+}
+
+// EnqueueMatchUpload — поиск пары на Deezer для загрузки (trackID — внутренний ID).
+// Не срочно, поэтому в общей очереди; ретраи — на случай, если Deezer недоступен
+func (q *AsynqQueue) EnqueueMatchUpload(ctx context.Context, trackID int64) error {
+	t, err := tasks.NewMatchUploadTask(trackID)
+	if err != nil {
+		return fmt.Errorf("failed to create match upload task: %w", err)
+	}
+
+	return q.enqueueUnique(ctx, t, DefaultQueue, trackID, asynq.MaxRetry(3))
+}
+
+// EnqueueBatchStart — загрузка треклиста пакета и запуск цепочек по трекам.
+// Ретраи — на случай, если источник (Deezer, Spotify) недоступен
+func (q *AsynqQueue) EnqueueBatchStart(ctx context.Context, batchID int64, userID int64) error {
+	t, err := tasks.NewBatchStartTask(batchID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to create batch start task: %w", err)
+	}
 
-	return totalSubTasks * 40, nil
+	return q.enqueueUnique(ctx, t, DefaultQueue, batchID, asynq.MaxRetry(3))
 }
+
 func (q *AsynqQueue) Close() error {
 	var errs []error
 	if q.inspector != nil {
@@ -89,16 +139,217 @@ func (q *AsynqQueue) Close() error {
 	return nil
 }
 
-func (q *AsynqQueue) EnqueueSearch(ctx context.Context, deezerID int64) error {
-	// Создаем задачу на поиск.
-	// Вам нужно будет добавить NewSearchYoutubeTask в пакет tasks
-	t, err := tasks.NewSearchYoutubeTask(deezerID)
-	if err != nil {
-		return fmt.Errorf("failed to create search task: %w", err)
+// HasLiveTask — есть ли в очереди задача этапа stage для трека, которая еще выполнится
+// (ждет, выполняется, отложена или ждет ретрая). Ищется по детерминированному ID в очередях обоих приоритетов
+func (q *AsynqQueue) HasLiveTask(ctx context.Context, status string, deezerID int64) (bool, error) {
+	stage := stageOfStatus(status)
+	if stage == "" {
+		return false, fmt.Errorf("unknown stage %q", status)
 	}
 
-	// Ставим задачу в очередь.
-	// MaxRetry(3), так как поиск — это легкий, но зависящий от сети процесс.
-	_, err = q.client.Enqueue(t, asynq.MaxRetry(3))
+	for _, p := range priorities {
+		info, err := q.inspector.GetTaskInfo(QueueName(stage, p), tasks.TaskID(taskTypeOfStage(stage), deezerID))
+		if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if isLive(info.State) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CancelBackground снимает с очереди фоновую (низкий приоритет) задачу поиска или скачивания трека,
+// пока воркер ее не взял. false — снимать нечего: задачи нет, она уже выполняется
+// или ее забрал в высокий приоритет интерактивный запрос
+func (q *AsynqQueue) CancelBackground(ctx context.Context, deezerID int64) (bool, error) {
+	for _, stage := range []string{StageSearch, StageDownload} {
+		queueName := QueueName(stage, domain.PriorityLow)
+		id := tasks.TaskID(taskTypeOfStage(stage), deezerID)
+
+		info, err := q.inspector.GetTaskInfo(queueName, id)
+		if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if !isLive(info.State) || info.State == asynq.TaskStateActive {
+			continue
+		}
+
+		err = q.inspector.DeleteTask(queueName, id)
+		if errors.Is(err, asynq.ErrTaskNotFound) {
+			continue
+		}
+		if err != nil {
+			// Воркер успел взять задачу — пусть доводит
+			return false, nil
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// CancelStats — что сделала отмена задач трека
+type CancelStats struct {
+	Deleted   int // Ждавшие задачи удалены из очереди
+	Cancelled int // Выполняющимся задачам отправлена отмена (контекст воркера отменяется)
+}
+
+// CancelTrack снимает все задачи цепочки трека во всех очередях этапов:
+// ждущие, отложенные и ждущие ретрая удаляются, выполняющиеся отменяются через CancelProcessing.
+// Отмена выполняющейся задачи асинхронна — воркер увидит ее по отмененному контексту,
+// а повторный запуск этапа дождется ее выхода (см. enqueueStage)
+func (q *AsynqQueue) CancelTrack(ctx context.Context, deezerID int64) (CancelStats, error) {
+	var stats CancelStats
+	for _, stage := range Stages {
+		for _, p := range priorities {
+			queueName := QueueName(stage, p)
+			id := tasks.TaskID(taskTypeOfStage(stage), deezerID)
+
+			info, err := q.inspector.GetTaskInfo(queueName, id)
+			if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+				continue
+			}
+			if err != nil {
+				return stats, err
+			}
+
+			switch {
+			case info.State == asynq.TaskStateActive:
+				if err := q.inspector.CancelProcessing(id); err != nil {
+					return stats, fmt.Errorf("failed to cancel task %s: %w", id, err)
+				}
+				stats.Cancelled++
+			case isLive(info.State):
+				err := q.inspector.DeleteTask(queueName, id)
+				if errors.Is(err, asynq.ErrTaskNotFound) {
+					continue
+				}
+				if err != nil {
+					// Воркер успел взять задачу между проверкой и удалением
+					if cerr := q.inspector.CancelProcessing(id); cerr != nil {
+						return stats, fmt.Errorf("failed to cancel task %s: %w", id, cerr)
+					}
+					stats.Cancelled++
+					continue
+				}
+				stats.Deleted++
+			}
+		}
+	}
+	return stats, nil
+}
+
+// enqueueStage ставит задачу этапа в очередь нужного приоритета.
+// Task ID уникален только внутри очереди, поэтому очередь другого приоритета проверяем сами:
+// если трек уже ждет там — второй задачи не ставим, а интерактивный запрос
+// забирает ожидающую фоновую задачу к себе в высокий приоритет.
+func (q *AsynqQueue) enqueueStage(ctx context.Context, t *asynq.Task, stage, priority string, deezerID int64, opts ...asynq.Option) error {
+	priority = domain.NormalizePriority(priority)
+	id := tasks.TaskID(t.Type(), deezerID)
+
+	// Этап ставят, только когда трек до него еще не дошел, так что выполняющаяся задача
+	// этапа — почти всегда отмененная (CancelTrack): CancelProcessing асинхронный, и ID она
+	// держит, пока не выйдет. Без ожидания новая задача не встала бы, а старая тихо
+	// завершилась бы в stageCancelled — трек висел бы в queued до реконсайлера
+	for _, p := range priorities {
+		q.waitInactive(ctx, QueueName(stage, p), id)
+	}
+
+	for _, other := range priorities {
+		if other == priority {
+			continue
+		}
+		otherQueue := QueueName(stage, other)
+		info, err := q.inspector.GetTaskInfo(otherQueue, id)
+		if err != nil || !isLive(info.State) {
+			continue
+		}
+
+		// Уже выполняется или ее нельзя повысить — она и так доведет цепочку
+		if priority != domain.PriorityHigh || info.State == asynq.TaskStateActive {
+			return nil
+		}
+		if err := q.inspector.DeleteTask(otherQueue, id); err != nil && !errors.Is(err, asynq.ErrTaskNotFound) {
+			// Воркер успел ее взять — значит, трек уже обрабатывается
+			return nil
+		}
+	}
+
+	return q.enqueueUnique(ctx, t, QueueName(stage, priority), deezerID, opts...)
+}
+
+// enqueueUnique ставит задачу с ID tasks.TaskID(тип, deezer_id): один этап одного трека —
+// одна задача, сколько бы запросов и инстансов API ее ни ставили.
+// ErrTaskIDConflict значит, что такая задача уже ждет или выполняется, — это успех.
+func (q *AsynqQueue) enqueueUnique(ctx context.Context, t *asynq.Task, queue string, deezerID int64, opts ...asynq.Option) error {
+	id := tasks.TaskID(t.Type(), deezerID)
+	opts = append(opts, asynq.TaskID(id), asynq.Queue(queue))
+
+	_, err := q.client.EnqueueContext(ctx, t, opts...)
+	if !errors.Is(err, asynq.ErrTaskIDConflict) {
+		return err
+	}
+
+	// ID занят и задачей, которая уже никогда не выполнится: провалившаяся окончательно
+	// лежит в archived. Трек при этом ушел в failed и запускается заново — убираем ее
+	info, infoErr := q.inspector.GetTaskInfo(queue, id)
+	if infoErr != nil || isLive(info.State) {
+		return nil
+	}
+	if err := q.inspector.DeleteTask(queue, id); err != nil && !errors.Is(err, asynq.ErrTaskNotFound) {
+		return fmt.Errorf("failed to delete stale task %s: %w", id, err)
+	}
+
+	_, err = q.client.EnqueueContext(ctx, t, opts...)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		return nil // Параллельный запрос успел поставить задачу раньше
+	}
 	return err
 }
+
+// waitInactive ждет (не дольше cancelWaitTimeout), пока задача перестанет выполняться.
+// Не дождались — решает обычная логика: живая задача сама доведет цепочку
+func (q *AsynqQueue) waitInactive(ctx context.Context, queue, id string) {
+	ctx, cancel := context.WithTimeout(ctx, cancelWaitTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(cancelPollInterval)
+	defer ticker.Stop()
+
+	for {
+		info, err := q.inspector.GetTaskInfo(queue, id)
+		if err != nil || info.State != asynq.TaskStateActive {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// isLive — задача еще выполнится (ждет, выполняется, отложена или ждет ретрая)
+func isLive(state asynq.TaskState) bool {
+	switch state {
+	case asynq.TaskStatePending, asynq.TaskStateActive, asynq.TaskStateScheduled, asynq.TaskStateRetry, asynq.TaskStateAggregating:
+		return true
+	}
+	return false
+}
+
+// queuePriority — приоритет по имени очереди этапа
+func queuePriority(queue string) string {
+	for _, p := range priorities {
+		if strings.HasSuffix(queue, ":"+p) {
+			return p
+		}
+	}
+	return domain.PriorityHigh
+}
@@ -0,0 +1,95 @@
+package queue
+
+import (
+	"music-go-bot/internal/domain"
+	"music-go-bot/internal/tasks"
+	"time"
+)
+
+// Этапы цепочки. У каждого свои очереди и свой воркер-сервер со своей конкуренцией:
+// долгие скачивания не занимают слоты поиска и загрузки
+const (
+	StageSearch   = "search"
+	StageDownload = "download"
+	StageUpload   = "upload"
+)
+
+var Stages = []string{StageSearch, StageDownload, StageUpload}
+
+// DefaultQueue — все, что не относится к этапам: уведомления, реконсайлер
+const DefaultQueue = "default"
+
+// StageConfig — настройки очередей одного этапа
+type StageConfig struct {
+	Concurrency int           // Сколько задач этапа выполняется параллельно
+	AvgDuration time.Duration // Типичная длительность задачи — для оценки ожидания
+}
+
+// Config — настройки по этапам (StageSearch, StageDownload, StageUpload)
+type Config map[string]StageConfig
+
+var defaultStageConfig = Config{
+	StageSearch:   {Concurrency: 4, AvgDuration: 10 * time.Second},
+	StageDownload: {Concurrency: 3, AvgDuration: 40 * time.Second},
+	StageUpload:   {Concurrency: 2, AvgDuration: 15 * time.Second},
+}
+
+// WithDefaults дополняет незаданные значения дефолтами
+func (c Config) WithDefaults() Config {
+	res := make(Config, len(defaultStageConfig))
+	for stage, def := range defaultStageConfig {
+		sc := c[stage]
+		if sc.Concurrency <= 0 {
+			sc.Concurrency = def.Concurrency
+		}
+		if sc.AvgDuration <= 0 {
+			sc.AvgDuration = def.AvgDuration
+		}
+		res[stage] = sc
+	}
+	return res
+}
+
+// QueueName — очередь этапа с приоритетом: "download:high", "search:low"
+func QueueName(stage, priority string) string {
+	return stage + ":" + domain.NormalizePriority(priority)
+}
+
+// StageQueues — очереди, которые слушает сервер этапа (с StrictPriority: high всегда первым)
+func StageQueues(stage string) map[string]int {
+	return map[string]int{
+		QueueName(stage, domain.PriorityHigh): 2,
+		QueueName(stage, domain.PriorityLow):  1,
+	}
+}
+
+// StageOfTask — этап, к которому относится тип задачи ("" — не этап цепочки)
+func StageOfTask(taskType string) string {
+	switch taskType {
+	case tasks.TypeYoutubeSearch:
+		return StageSearch
+	case tasks.TypeDownloadYoutube:
+		return StageDownload
+	case tasks.TypeTelegramUpload:
+		return StageUpload
+	}
+	return ""
+}
+
+// taskTypeOfStage — тип задачи этапа (обратная к StageOfTask)
+func taskTypeOfStage(stage string) string {
+	switch stage {
+	case StageSearch:
+		return tasks.TypeYoutubeSearch
+	case StageDownload:
+		return tasks.TypeDownloadYoutube
+	case StageUpload:
+		return tasks.TypeTelegramUpload
+	}
+	return ""
+}
+
+// stageOfStatus — этап по статусу трека (searching -> search)
+func stageOfStatus(status string) string {
+	return StageOfTask(tasks.TypeOfStage(status))
+}
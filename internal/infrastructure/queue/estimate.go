@@ -0,0 +1,233 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"music-go-bot/internal/domain"
+	"music-go-bot/internal/tasks"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// Меньше замеров — статистике не доверяем и берем AvgDuration из конфига
+const minDurationSamples = 5
+
+// Estimate — оценка ожидания для конкретного трека
+type Estimate struct {
+	Found     bool          // Задача трека найдена в очереди (иначе — оценка для нового запроса)
+	Stage     string        // Этап, на котором задача сейчас
+	Priority  string        // Очередь какого приоритета
+	State     string        // pending / active / retry / scheduled
+	Ahead     int           // Задач этапа, которые выполнятся раньше
+	Remaining time.Duration // До готовности трека, с учетом оставшихся этапов
+}
+
+// StageInfo — состояние очередей этапа
+type StageInfo struct {
+	Stage       string         `json:"stage"`
+	Concurrency int            `json:"concurrency"`
+	Pending     map[string]int `json:"pending"` // По приоритетам
+	Active      int            `json:"active"`
+	Retry       int            `json:"retry"`
+	Scheduled   int            `json:"scheduled"`
+	Samples     int            `json:"samples"`
+	P50Seconds  float64        `json:"p50_seconds"`
+	P95Seconds  float64        `json:"p95_seconds"`
+}
+
+// GetEstimatedWaitTime — примерное время в секундах до готовности трека (см. EstimateTrack)
+func (q *AsynqQueue) GetEstimatedWaitTime(deezerID int64) (int, error) {
+	est, err := q.EstimateTrack(context.Background(), deezerID)
+	if err != nil {
+		return 0, err
+	}
+	return int(est.Remaining.Seconds()), nil
+}
+
+// EstimateTrack ищет задачу трека в очередях этапов и считает, сколько ей ждать:
+// сколько задач перед ней, сколько параллельных слотов у этапа, сколько по замерам
+// длится этот этап и все следующие. Если задачи нет (или deezerID == 0) —
+// оценка для нового интерактивного запроса, который начнется с поиска.
+func (q *AsynqQueue) EstimateTrack(ctx context.Context, deezerID int64) (Estimate, error) {
+	est := Estimate{Stage: StageSearch, Priority: domain.PriorityHigh, State: asynq.TaskStatePending.String()}
+
+	var info *asynq.TaskInfo
+	if deezerID != 0 {
+		info = q.findStageTask(deezerID)
+	}
+	if info != nil {
+		est.Found = true
+		est.Stage = StageOfTask(info.Type)
+		est.Priority = queuePriority(info.Queue)
+		est.State = info.State.String()
+	}
+
+	stageTime := q.stageDuration(ctx, est.Stage)
+	switch {
+	case info != nil && info.State == asynq.TaskStateActive:
+		// Когда задача началась, asynq не говорит — считаем, что прошла половина
+		est.Remaining = stageTime / 2
+
+	case info != nil && (info.State == asynq.TaskStateRetry || info.State == asynq.TaskStateScheduled):
+		est.Remaining = max(time.Until(info.NextProcessAt), 0) + stageTime
+
+	default:
+		var taskID string
+		if info != nil {
+			taskID = info.ID
+		}
+		ahead, err := q.aheadOf(est.Stage, est.Priority, taskID)
+		if err != nil {
+			return Estimate{}, err
+		}
+		est.Ahead = ahead
+		waves := ahead/q.cfg[est.Stage].Concurrency + 1
+		est.Remaining = time.Duration(waves) * stageTime
+	}
+
+	// Следующие этапы: очередь перед ними не учитываем — к тому времени она сменится
+	after := false
+	for _, s := range Stages {
+		if after {
+			est.Remaining += q.stageDuration(ctx, s)
+		}
+		after = after || s == est.Stage
+	}
+
+	return est, nil
+}
+
+// StageStats — глубина очередей, занятые воркеры и перцентили длительности по этапам
+func (q *AsynqQueue) StageStats(ctx context.Context) ([]StageInfo, error) {
+	res := make([]StageInfo, 0, len(Stages))
+	for _, stage := range Stages {
+		si := StageInfo{
+			Stage:       stage,
+			Concurrency: q.cfg[stage].Concurrency,
+			Pending:     make(map[string]int, len(priorities)),
+		}
+		for _, p := range priorities {
+			info, err := q.inspector.GetQueueInfo(QueueName(stage, p))
+			if errors.Is(err, asynq.ErrQueueNotFound) {
+				si.Pending[p] = 0
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			si.Pending[p] = info.Pending
+			si.Active += info.Active
+			si.Retry += info.Retry
+			si.Scheduled += info.Scheduled
+		}
+
+		if q.stats != nil {
+			d, err := q.stats.Durations(ctx, stage)
+			if err != nil {
+				return nil, err
+			}
+			si.Samples = d.Samples
+			si.P50Seconds = d.P50.Seconds()
+			si.P95Seconds = d.P95.Seconds()
+		}
+		res = append(res, si)
+	}
+	return res, nil
+}
+
+// Сколько ожидающих задач читаем из очереди за раз, пока ищем позицию трека
+const pendingPageSize = 500
+
+// aheadOf — сколько задач этапа выполнится раньше задачи taskID из очереди приоритета priority:
+// все задачи очередей выше приоритетом, занятые воркеры своей очереди и ожидающие
+// в ней перед taskID. Пустой taskID — новая задача, она встанет в конец очереди
+func (q *AsynqQueue) aheadOf(stage, priority, taskID string) (int, error) {
+	var ahead int
+	// priorities упорядочены от высокого к низкому
+	for _, p := range priorities {
+		queue := QueueName(stage, p)
+		info, err := q.inspector.GetQueueInfo(queue)
+		if errors.Is(err, asynq.ErrQueueNotFound) {
+			if p == priority {
+				break
+			}
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		if p != priority {
+			ahead += info.Pending + info.Active
+			continue
+		}
+
+		ahead += info.Active
+		if taskID == "" {
+			ahead += info.Pending
+			break
+		}
+		pos, err := q.pendingPosition(queue, taskID)
+		if err != nil {
+			return 0, err
+		}
+		ahead += pos
+		break
+	}
+	return ahead, nil
+}
+
+// pendingPosition — сколько ожидающих задач очереди выполнится раньше taskID.
+// asynq отдает ожидающие в порядке выполнения. Если задачу уже забрал воркер —
+// перед ней вся очередь (такого почти не бывает: вызывающий только что видел ее pending)
+func (q *AsynqQueue) pendingPosition(queue, taskID string) (int, error) {
+	var pos int
+	for page := 1; ; page++ {
+		pending, err := q.inspector.ListPendingTasks(queue, asynq.PageSize(pendingPageSize), asynq.Page(page))
+		if errors.Is(err, asynq.ErrQueueNotFound) {
+			return pos, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		for i, t := range pending {
+			if t.ID == taskID {
+				return pos + i, nil
+			}
+		}
+		pos += len(pending)
+
+		if len(pending) < pendingPageSize {
+			return pos, nil
+		}
+	}
+}
+
+// stageDuration — типичная (p50) длительность этапа по замерам, пока их мало — из конфига
+func (q *AsynqQueue) stageDuration(ctx context.Context, stage string) time.Duration {
+	if q.stats != nil {
+		d, err := q.stats.Durations(ctx, stage)
+		if err != nil {
+			slog.Warn("Failed to read stage durations", "stage", stage, "error", err)
+		} else if d.Samples >= minDurationSamples {
+			return d.P50
+		}
+	}
+	return q.cfg[stage].AvgDuration
+}
+
+// findStageTask ищет живую задачу трека в очередях всех этапов
+func (q *AsynqQueue) findStageTask(deezerID int64) *asynq.TaskInfo {
+	for _, stage := range Stages {
+		for _, p := range priorities {
+			info, err := q.inspector.GetTaskInfo(QueueName(stage, p), tasks.TaskID(taskTypeOfStage(stage), deezerID))
+			if err == nil && isLive(info.State) {
+				return info
+			}
+		}
+	}
+	return nil
+}
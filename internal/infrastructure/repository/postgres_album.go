@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"music-go-bot/internal/domain"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// albumRepo реализует интерфейс domain.AlbumRepository
+type albumRepo struct {
+	db   *sql.DB
+	psql sq.StatementBuilderType
+}
+
+func NewAlbumRepo(db *sql.DB) domain.AlbumRepository {
+	return &albumRepo{
+		db:   db,
+		psql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+func (r *albumRepo) Upsert(ctx context.Context, albums []domain.Album) error {
+	if len(albums) == 0 {
+		return nil
+	}
+
+	insert := r.psql.Insert("albums").
+		Columns("id", "artist_id", "title", "cover_medium", "cover_xl", "release_date", "record_type", "nb_tracks")
+	// Одна строка дважды в одном INSERT ... ON CONFLICT DO UPDATE — ошибка Postgres
+	seen := make(map[int64]bool, len(albums))
+	for _, a := range albums {
+		if a.ID == 0 || seen[a.ID] {
+			continue
+		}
+		seen[a.ID] = true
+		insert = insert.Values(
+			a.ID,
+			sq.Expr("NULLIF(?::BIGINT, 0)", a.ArtistID),
+			a.Title,
+			a.CoverMedium,
+			a.CoverXL,
+			// Deezer отдает "0000-00-00", если дата неизвестна
+			sq.Expr("NULLIF(NULLIF(?, ''), '0000-00-00')::DATE", a.ReleaseDate),
+			a.RecordType,
+			sq.Expr("NULLIF(?::INTEGER, 0)", a.NbTracks),
+		)
+	}
+	if len(seen) == 0 {
+		return nil
+	}
+
+	query, args, err := insert.Suffix(`ON CONFLICT (id) DO UPDATE SET
+            artist_id = COALESCE(EXCLUDED.artist_id, albums.artist_id),
+            title = COALESCE(NULLIF(EXCLUDED.title, ''), albums.title),
+            cover_medium = COALESCE(NULLIF(EXCLUDED.cover_medium, ''), albums.cover_medium),
+            cover_xl = COALESCE(NULLIF(EXCLUDED.cover_xl, ''), albums.cover_xl),
+            release_date = COALESCE(EXCLUDED.release_date, albums.release_date),
+            record_type = COALESCE(NULLIF(EXCLUDED.record_type, ''), albums.record_type),
+            nb_tracks = COALESCE(EXCLUDED.nb_tracks, albums.nb_tracks),
+            updated_at = NOW()`).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to upsert albums: %w", err)
+	}
+	return nil
+}
+
+func (r *albumRepo) GetByID(ctx context.Context, id int64) (*domain.Album, error) {
+	query, args, err := r.psql.Select(
+		"al.id",
+		"al.title",
+		"COALESCE(al.artist_id, 0)",
+		"COALESCE(ar.name, '')",
+		"COALESCE(al.cover_medium, '')",
+		"COALESCE(al.cover_xl, '')",
+		"COALESCE(TO_CHAR(al.release_date, 'YYYY-MM-DD'), '')",
+		"COALESCE(al.record_type, '')",
+		"COALESCE(al.nb_tracks, 0)",
+		"al.updated_at",
+	).
+		From("albums al").
+		LeftJoin("artists ar ON ar.id = al.artist_id").
+		Where(sq.Eq{"al.id": id}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var a domain.Album
+	err = r.db.QueryRowContext(ctx, query, args...).Scan(
+		&a.ID,
+		&a.Title,
+		&a.ArtistID,
+		&a.ArtistName,
+		&a.CoverMedium,
+		&a.CoverXL,
+		&a.ReleaseDate,
+		&a.RecordType,
+		&a.NbTracks,
+		&a.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("repository.GetAlbum scan error: %w", err)
+	}
+	return &a, nil
+}
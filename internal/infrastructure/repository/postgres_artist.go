@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"music-go-bot/internal/domain"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// artistRepo реализует интерфейс domain.ArtistRepository
+type artistRepo struct {
+	db   *sql.DB
+	psql sq.StatementBuilderType
+}
+
+func NewArtistRepo(db *sql.DB) domain.ArtistRepository {
+	return &artistRepo{
+		db:   db,
+		psql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+func (r *artistRepo) Upsert(ctx context.Context, artists []domain.Artist) error {
+	if len(artists) == 0 {
+		return nil
+	}
+
+	insert := r.psql.Insert("artists").
+		Columns("id", "name", "picture_medium", "picture_xl", "nb_album", "nb_fan")
+	// Одна строка дважды в одном INSERT ... ON CONFLICT DO UPDATE — ошибка Postgres
+	seen := make(map[int64]bool, len(artists))
+	for _, a := range artists {
+		if a.ID == 0 || seen[a.ID] {
+			continue
+		}
+		seen[a.ID] = true
+		insert = insert.Values(a.ID, a.Name, a.PictureMedium, a.PictureXL, sq.Expr("NULLIF(?::INTEGER, 0)", a.NbAlbum), sq.Expr("NULLIF(?::INTEGER, 0)", a.NbFan))
+	}
+	if len(seen) == 0 {
+		return nil
+	}
+
+	query, args, err := insert.Suffix(`ON CONFLICT (id) DO UPDATE SET
+            name = COALESCE(NULLIF(EXCLUDED.name, ''), artists.name),
+            picture_medium = COALESCE(NULLIF(EXCLUDED.picture_medium, ''), artists.picture_medium),
+            picture_xl = COALESCE(NULLIF(EXCLUDED.picture_xl, ''), artists.picture_xl),
+            nb_album = COALESCE(EXCLUDED.nb_album, artists.nb_album),
+            nb_fan = COALESCE(EXCLUDED.nb_fan, artists.nb_fan),
+            updated_at = NOW()`).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to upsert artists: %w", err)
+	}
+	return nil
+}
+
+func (r *artistRepo) GetByID(ctx context.Context, id int64) (*domain.Artist, error) {
+	query, args, err := r.psql.Select(
+		"id",
+		"name",
+		"COALESCE(picture_medium, '')",
+		"COALESCE(picture_xl, '')",
+		"COALESCE(nb_album, 0)",
+		"COALESCE(nb_fan, 0)",
+		"updated_at",
+	).
+		From("artists").
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var a domain.Artist
+	err = r.db.QueryRowContext(ctx, query, args...).Scan(&a.ID, &a.Name, &a.PictureMedium, &a.PictureXL, &a.NbAlbum, &a.NbFan, &a.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("repository.GetArtist scan error: %w", err)
+	}
+	return &a, nil
+}
@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"music-go-bot/internal/domain"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// batchRepo реализует интерфейс domain.BatchRepository
+type batchRepo struct {
+	db   *sql.DB
+	psql sq.StatementBuilderType
+}
+
+func NewBatchRepo(db *sql.DB) domain.BatchRepository {
+	return &batchRepo{
+		db:   db,
+		psql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+var batchColumns = []string{
+	"b.id",
+	"b.user_id",
+	"b.kind",
+	"b.source",
+	"b.source_id",
+	"b.title",
+	"b.missed",
+	"b.add_to_library",
+	"b.status",
+	"b.created_at",
+	"b.completed_at",
+}
+
+func scanBatch(row interface{ Scan(...any) error }, b *domain.Batch) error {
+	var completedAt sql.NullTime
+	err := row.Scan(&b.ID, &b.UserID, &b.Kind, &b.Source, &b.SourceID, &b.Title, &b.Missed, &b.AddToLibrary, &b.Status, &b.CreatedAt, &completedAt)
+	if err != nil {
+		return err
+	}
+	if completedAt.Valid {
+		b.CompletedAt = &completedAt.Time
+	}
+	return nil
+}
+
+func (r *batchRepo) Create(ctx context.Context, b *domain.Batch) error {
+	if b.Status == "" {
+		b.Status = domain.BatchRunning
+	}
+
+	query, args, err := r.psql.Insert("batches").
+		Columns("user_id", "kind", "source", "source_id", "title", "add_to_library", "status").
+		Values(b.UserID, b.Kind, b.Source, b.SourceID, b.Title, b.AddToLibrary, b.Status).
+		Suffix("RETURNING id, created_at").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&b.ID, &b.CreatedAt); err != nil {
+		return fmt.Errorf("failed to insert batch: %w", err)
+	}
+	return nil
+}
+
+func (r *batchRepo) AddTracks(ctx context.Context, batchID int64, trackIDs []int64) error {
+	if len(trackIDs) == 0 {
+		return nil
+	}
+
+	insert := r.psql.Insert("batch_tracks").Columns("batch_id", "track_id", "position")
+	for i, id := range trackIDs {
+		insert = insert.Values(batchID, id, i)
+	}
+	// Один трек дважды в плейлисте — в пакете он один
+	query, args, err := insert.Suffix("ON CONFLICT DO NOTHING").ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to add batch tracks: %w", err)
+	}
+	return nil
+}
+
+func (r *batchRepo) GetByID(ctx context.Context, userID, batchID int64) (*domain.Batch, error) {
+	query, args, err := r.psql.Select(batchColumns...).
+		From("batches b").
+		Where(sq.Eq{"b.id": batchID, "b.user_id": userID}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var b domain.Batch
+	if err := scanBatch(r.db.QueryRowContext(ctx, query, args...), &b); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("repository.GetBatch scan error: %w", err)
+	}
+
+	b.Progress, err = r.GetProgress(ctx, b.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// GetTracks возвращает треки пакета по порядку позиций
+func (r *batchRepo) GetTracks(ctx context.Context, batchID int64) ([]domain.BatchTrack, error) {
+	query, args, err := r.psql.Select(
+		"bt.position",
+		"t.id",
+		"COALESCE(t.deezer_id, 0)",
+		"COALESCE(t.youtube_id, '')",
+		"t.title",
+		"t.artist",
+		"COALESCE(t.duration, 0)",
+		"COALESCE(t.cover_url, '')",
+		"COALESCE(t.file_id, '')",
+		"COALESCE(t.file_unique_id, '')",
+		"t.created_at",
+		"COALESCE(t.status, '')",
+		"COALESCE(t.last_error, '')",
+		"COALESCE(t.storage_backend, '')",
+	).
+		From("batch_tracks bt").
+		Join("tracks t ON t.id = bt.track_id").
+		Where(sq.Eq{"bt.batch_id": batchID}).
+		OrderBy("bt.position").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var tracks []domain.BatchTrack
+	for rows.Next() {
+		var t domain.BatchTrack
+		err := rows.Scan(
+			&t.Position,
+			&t.ID,
+			&t.DeezerID,
+			&t.YoutubeID,
+			&t.Title,
+			&t.Artist,
+			&t.Duration,
+			&t.CoverURL,
+			&t.FileID,
+			&t.FileUniqueID,
+			&t.CreatedAt,
+			&t.Status,
+			&t.LastError,
+			&t.StorageBackend,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		tracks = append(tracks, t)
+	}
+
+	return tracks, rows.Err()
+}
+
+func (r *batchRepo) GetProgress(ctx context.Context, batchID int64) (domain.BatchProgress, error) {
+	failed := []string{domain.StatusFailed, domain.StatusLowConfidence, domain.StatusBlocked}
+	inProgress := []string{domain.StatusQueued, domain.StatusSearching, domain.StatusDownloading, domain.StatusUploading}
+
+	query, args, err := r.psql.Select("COUNT(*)").
+		Column(sq.Expr("COUNT(*) FILTER (WHERE ?)", sq.Eq{"t.status": domain.StatusReady})).
+		Column(sq.Expr("COUNT(*) FILTER (WHERE ?)", sq.Eq{"t.status": failed})).
+		Column(sq.Expr("COUNT(*) FILTER (WHERE ?)", sq.Eq{"t.status": inProgress})).
+		Column(sq.Expr("COUNT(*) FILTER (WHERE ?)", sq.Eq{"t.status": domain.StatusIdle})).
+		From("batch_tracks bt").
+		Join("tracks t ON t.id = bt.track_id").
+		Where(sq.Eq{"bt.batch_id": batchID}).
+		ToSql()
+	if err != nil {
+		return domain.BatchProgress{}, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var p domain.BatchProgress
+	err = r.db.QueryRowContext(ctx, query, args...).Scan(&p.Total, &p.Ready, &p.Failed, &p.InProgress, &p.Cancelled)
+	if err != nil {
+		return domain.BatchProgress{}, fmt.Errorf("failed to get batch progress: %w", err)
+	}
+	return p, nil
+}
+
+func (r *batchRepo) GetRunningByTrack(ctx context.Context, trackID int64) ([]domain.Batch, error) {
+	query, args, err := r.psql.Select(batchColumns...).
+		From("batches b").
+		Join("batch_tracks bt ON bt.batch_id = b.id").
+		Where(sq.Eq{"bt.track_id": trackID, "b.status": domain.BatchRunning}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var batches []domain.Batch
+	for rows.Next() {
+		var b domain.Batch
+		if err := scanBatch(rows, &b); err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		batches = append(batches, b)
+	}
+
+	return batches, rows.Err()
+}
+
+func (r *batchRepo) Start(ctx context.Context, batchID int64, title string, missed int) (bool, error) {
+	query, args, err := r.psql.Update("batches").
+		Set("status", domain.BatchRunning).
+		Set("title", title).
+		Set("missed", missed).
+		Where(sq.Eq{"id": batchID, "status": domain.BatchPending}).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to start batch: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *batchRepo) Fail(ctx context.Context, batchID int64) error {
+	query, args, err := r.psql.Update("batches").
+		Set("status", domain.BatchFailed).
+		Set("completed_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": batchID, "status": domain.BatchPending}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to fail batch: %w", err)
+	}
+	return nil
+}
+
+func (r *batchRepo) Complete(ctx context.Context, batchID int64) (bool, error) {
+	query, args, err := r.psql.Update("batches").
+		Set("status", domain.BatchCompleted).
+		Set("completed_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": batchID, "status": domain.BatchRunning}).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	// Условие на status: из двух воркеров, закончивших последние треки, завершит один
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to complete batch: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
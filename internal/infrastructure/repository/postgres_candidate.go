@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"music-go-bot/internal/domain"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// candidateRepo реализует интерфейс domain.CandidateRepository
+type candidateRepo struct {
+	db   *sql.DB
+	psql sq.StatementBuilderType
+}
+
+func NewCandidateRepo(db *sql.DB) domain.CandidateRepository {
+	return &candidateRepo{
+		db:   db,
+		psql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+func (r *candidateRepo) Replace(ctx context.Context, trackID int64, candidates []domain.TrackCandidate) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM track_candidates WHERE track_id = $1`, trackID); err != nil {
+		return fmt.Errorf("failed to clear candidates: %w", err)
+	}
+
+	if len(candidates) > 0 {
+		insert := r.psql.Insert("track_candidates").
+			Columns("track_id", "youtube_id", "title", "channel", "duration", "score", "rank")
+		for i, c := range candidates {
+			insert = insert.Values(trackID, c.YoutubeID, c.Title, c.Channel, c.Duration, c.Score, i)
+		}
+
+		// В выдаче YouTube одно видео может встретиться дважды — оставляем первое (лучшее)
+		query, args, err := insert.Suffix("ON CONFLICT (track_id, youtube_id) DO NOTHING").ToSql()
+		if err != nil {
+			return fmt.Errorf("failed to build query: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to insert candidates: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *candidateRepo) GetByTrackID(ctx context.Context, trackID int64) ([]domain.TrackCandidate, error) {
+	query, args, err := r.psql.Select(
+		"id",
+		"track_id",
+		"youtube_id",
+		"title",
+		"COALESCE(channel, '')",
+		"COALESCE(duration, 0)",
+		"score",
+		"rank",
+		"created_at",
+	).
+		From("track_candidates").
+		Where(sq.Eq{"track_id": trackID}).
+		OrderBy("rank").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []domain.TrackCandidate
+	for rows.Next() {
+		var c domain.TrackCandidate
+		err := rows.Scan(&c.ID, &c.TrackID, &c.YoutubeID, &c.Title, &c.Channel, &c.Duration, &c.Score, &c.Rank, &c.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		candidates = append(candidates, c)
+	}
+
+	return candidates, rows.Err()
+}
+
+func (r *candidateRepo) Reject(ctx context.Context, trackID int64, youtubeID string, userID int64) error {
+	// user_id = NULL, если отказ пришел не от пользователя
+	var by interface{}
+	if userID != 0 {
+		by = userID
+	}
+
+	query, args, err := r.psql.Insert("track_rejections").
+		Columns("track_id", "youtube_id", "user_id").
+		Values(trackID, youtubeID, by).
+		Suffix("ON CONFLICT DO NOTHING").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to reject video: %w", err)
+	}
+	return nil
+}
+
+func (r *candidateRepo) GetRejected(ctx context.Context, trackID int64) (map[string]bool, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT youtube_id FROM track_rejections WHERE track_id = $1`, trackID)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	rejected := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		rejected[id] = true
+	}
+
+	return rejected, rows.Err()
+}
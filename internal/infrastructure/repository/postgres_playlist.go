@@ -0,0 +1,337 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"music-go-bot/internal/domain"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// playlistRepo реализует интерфейс domain.PlaylistRepository
+type playlistRepo struct {
+	db   *sql.DB
+	psql sq.StatementBuilderType
+}
+
+func NewPlaylistRepo(db *sql.DB) domain.PlaylistRepository {
+	return &playlistRepo{
+		db:   db,
+		psql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+func (r *playlistRepo) Create(ctx context.Context, p *domain.Playlist) error {
+	query, args, err := r.psql.Insert("playlists").
+		Columns("user_id", "title").
+		Values(p.UserID, p.Title).
+		Suffix("RETURNING id, created_at, updated_at").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to insert playlist: %w", err)
+	}
+	return nil
+}
+
+func (r *playlistRepo) GetByUserID(ctx context.Context, userID int64) ([]domain.Playlist, error) {
+	query, args, err := r.psql.Select(
+		"p.id",
+		"p.user_id",
+		"p.title",
+		"p.created_at",
+		"p.updated_at",
+		"(SELECT COUNT(*) FROM playlist_tracks pt WHERE pt.playlist_id = p.id)",
+	).
+		From("playlists p").
+		Where(sq.Eq{"p.user_id": userID}).
+		OrderBy("p.updated_at DESC").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var playlists []domain.Playlist
+	for rows.Next() {
+		var p domain.Playlist
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Title, &p.CreatedAt, &p.UpdatedAt, &p.TrackCount); err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		playlists = append(playlists, p)
+	}
+
+	return playlists, rows.Err()
+}
+
+func (r *playlistRepo) GetByID(ctx context.Context, userID, playlistID int64) (*domain.Playlist, error) {
+	query, args, err := r.psql.Select(
+		"p.id",
+		"p.user_id",
+		"p.title",
+		"p.created_at",
+		"p.updated_at",
+		"(SELECT COUNT(*) FROM playlist_tracks pt WHERE pt.playlist_id = p.id)",
+	).
+		From("playlists p").
+		Where(sq.Eq{"p.id": playlistID, "p.user_id": userID}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var p domain.Playlist
+	err = r.db.QueryRowContext(ctx, query, args...).
+		Scan(&p.ID, &p.UserID, &p.Title, &p.CreatedAt, &p.UpdatedAt, &p.TrackCount)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("repository.GetByID scan error: %w", err)
+	}
+
+	return &p, nil
+}
+
+// GetEntries возвращает треки плейлиста по порядку позиций
+func (r *playlistRepo) GetEntries(ctx context.Context, playlistID int64) ([]domain.PlaylistEntry, error) {
+	query, args, err := r.psql.Select(
+		"pt.position",
+		"pt.added_at",
+		"t.id",
+		"COALESCE(t.deezer_id, 0)",
+		"COALESCE(t.youtube_id, '')",
+		"t.title",
+		"t.artist",
+		"COALESCE(t.duration, 0)",
+		"COALESCE(t.cover_url, '')",
+		"COALESCE(t.file_id, '')",
+		"COALESCE(t.file_unique_id, '')",
+		"t.created_at",
+		"COALESCE(t.status, '')",
+		"COALESCE(t.storage_backend, '')",
+		"COALESCE(t.storage_key, '')",
+		"t.source",
+	).
+		From("playlist_tracks pt").
+		Join("tracks t ON t.id = pt.track_id").
+		Where(sq.Eq{"pt.playlist_id": playlistID}).
+		OrderBy("pt.position").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []domain.PlaylistEntry
+	for rows.Next() {
+		var e domain.PlaylistEntry
+		err := rows.Scan(
+			&e.Position,
+			&e.AddedAt,
+			&e.ID,
+			&e.DeezerID,
+			&e.YoutubeID,
+			&e.Title,
+			&e.Artist,
+			&e.Duration,
+			&e.CoverURL,
+			&e.FileID,
+			&e.FileUniqueID,
+			&e.CreatedAt,
+			&e.Status,
+			&e.StorageBackend,
+			&e.StorageKey,
+			&e.Source,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+func (r *playlistRepo) Rename(ctx context.Context, userID, playlistID int64, title string) error {
+	query, args, err := r.psql.Update("playlists").
+		Set("title", title).
+		Set("updated_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": playlistID, "user_id": userID}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	return r.execOwned(ctx, query, args...)
+}
+
+func (r *playlistRepo) Delete(ctx context.Context, userID, playlistID int64) error {
+	query, args, err := r.psql.Delete("playlists").
+		Where(sq.Eq{"id": playlistID, "user_id": userID}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	return r.execOwned(ctx, query, args...)
+}
+
+func (r *playlistRepo) AddTrack(ctx context.Context, userID, playlistID, trackID int64) (int, error) {
+	var position int
+	err := r.withLockedPlaylist(ctx, userID, playlistID, func(tx *sql.Tx) error {
+		// Плейлист заблокирован, поэтому MAX(position) не гоняется с параллельными вставками
+		return tx.QueryRowContext(ctx, `
+            INSERT INTO playlist_tracks (playlist_id, track_id, position)
+            SELECT $1, $2, COALESCE(MAX(position) + 1, 0)
+            FROM playlist_tracks WHERE playlist_id = $1
+            RETURNING position
+        `, playlistID, trackID).Scan(&position)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return position, nil
+}
+
+func (r *playlistRepo) RemoveTrack(ctx context.Context, userID, playlistID int64, position int) error {
+	return r.withLockedPlaylist(ctx, userID, playlistID, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			`DELETE FROM playlist_tracks WHERE playlist_id = $1 AND position = $2`,
+			playlistID, position,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to delete playlist entry: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return domain.ErrInvalidPosition
+		}
+
+		// Закрываем дырку: хвост сдвигается на одну позицию вверх
+		_, err = tx.ExecContext(ctx,
+			`UPDATE playlist_tracks SET position = position - 1 WHERE playlist_id = $1 AND position > $2`,
+			playlistID, position,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to shift playlist entries: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *playlistRepo) MoveTrack(ctx context.Context, userID, playlistID int64, from, to int) error {
+	return r.withLockedPlaylist(ctx, userID, playlistID, func(tx *sql.Tx) error {
+		var count int
+		err := tx.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM playlist_tracks WHERE playlist_id = $1`, playlistID,
+		).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to count playlist entries: %w", err)
+		}
+		if from < 0 || from >= count || to < 0 || to >= count {
+			return domain.ErrInvalidPosition
+		}
+		if from == to {
+			return nil
+		}
+
+		var entryID int64
+		err = tx.QueryRowContext(ctx,
+			`SELECT id FROM playlist_tracks WHERE playlist_id = $1 AND position = $2`,
+			playlistID, from,
+		).Scan(&entryID)
+		if err != nil {
+			return fmt.Errorf("failed to find playlist entry: %w", err)
+		}
+
+		// Сдвигаем соседей между from и to, уникальность позиций проверится на COMMIT
+		if from < to {
+			_, err = tx.ExecContext(ctx,
+				`UPDATE playlist_tracks SET position = position - 1
+                 WHERE playlist_id = $1 AND position > $2 AND position <= $3`,
+				playlistID, from, to,
+			)
+		} else {
+			_, err = tx.ExecContext(ctx,
+				`UPDATE playlist_tracks SET position = position + 1
+                 WHERE playlist_id = $1 AND position >= $2 AND position < $3`,
+				playlistID, to, from,
+			)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to shift playlist entries: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE playlist_tracks SET position = $1 WHERE id = $2`, to, entryID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to move playlist entry: %w", err)
+		}
+		return nil
+	})
+}
+
+// withLockedPlaylist открывает транзакцию, блокирует строку плейлиста (SELECT ... FOR UPDATE),
+// проверяет владельца и обновляет updated_at после успешного fn.
+func (r *playlistRepo) withLockedPlaylist(ctx context.Context, userID, playlistID int64, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx,
+		`SELECT id FROM playlists WHERE id = $1 AND user_id = $2 FOR UPDATE`,
+		playlistID, userID,
+	).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrPlaylistNotFound
+		}
+		return fmt.Errorf("failed to lock playlist: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE playlists SET updated_at = NOW() WHERE id = $1`, playlistID); err != nil {
+		return fmt.Errorf("failed to touch playlist: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// execOwned выполняет UPDATE/DELETE по (id, user_id) и превращает 0 строк в ErrPlaylistNotFound
+func (r *playlistRepo) execOwned(ctx context.Context, query string, args ...interface{}) error {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to execute playlist query: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrPlaylistNotFound
+	}
+	return nil
+}
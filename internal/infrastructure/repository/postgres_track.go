@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"music-go-bot/internal/domain"
+	"time"
 
 	"github.com/Masterminds/squirrel"
 	sq "github.com/Masterminds/squirrel"
@@ -42,47 +43,146 @@ func (r *trackRepo) AddToUser(ctx context.Context, userID int64, trackID int64)
 
 // Save записывает новый трек в базу данных.
 // Используется ON CONFLICT DO NOTHING, чтобы не плодить ошибки, если юзер прислал один и тот же файл дважды.
+// Статус пишется только при вставке — дальше он меняется исключительно через Transition.
 func (r *trackRepo) Save(ctx context.Context, t *domain.Track) error {
-	// Убираем транзакцию, если это единственный запрос
+	// Без deezer_id ON CONFLICT (deezer_id) не сработает, и каждый вызов вставлял бы новую строку
+	if t.DeezerID == 0 {
+		return errors.New("track without deezer_id must be saved with SaveUpload")
+	}
+	if t.Status == "" {
+		t.Status = domain.StatusIdle
+	}
+
 	query, args, err := r.psql.Insert("tracks").
-		Columns("deezer_id", "youtube_id", "file_id", "file_unique_id", "title", "artist", "duration", "cover_url", "status").
-		Values(t.DeezerID, t.YoutubeID, t.FileID, t.FileUniqueID, t.Title, t.Artist, t.Duration, t.CoverURL, t.Status).
+		Columns("deezer_id", "youtube_id", "file_id", "file_unique_id", "title", "artist", "duration", "cover_url", "status", "storage_backend", "storage_key",
+			"artist_id", "album_id", "disc_number", "track_position").
+		Values(t.DeezerID, t.YoutubeID, t.FileID, t.FileUniqueID, t.Title, t.Artist, t.Duration, t.CoverURL, t.Status, t.StorageBackend, t.StorageKey,
+			sq.Expr("NULLIF(?::BIGINT, 0)", t.ArtistID),
+			sq.Expr("NULLIF(?::BIGINT, 0)", t.AlbumID),
+			sq.Expr("NULLIF(?::INTEGER, 0)", t.DiscNumber),
+			sq.Expr("NULLIF(?::INTEGER, 0)", t.TrackPosition),
+		).
 		Suffix(`ON CONFLICT (deezer_id) DO UPDATE SET 
             youtube_id = COALESCE(NULLIF(EXCLUDED.youtube_id, ''), tracks.youtube_id),
             file_id = COALESCE(NULLIF(EXCLUDED.file_id, ''), tracks.file_id),
             file_unique_id = COALESCE(NULLIF(EXCLUDED.file_unique_id, ''), tracks.file_unique_id),
-            status = CASE 
-                WHEN tracks.status = 'ready' AND EXCLUDED.status = 'processing' THEN tracks.status 
-                ELSE EXCLUDED.status 
-            END
-            RETURNING id`).
+            storage_backend = COALESCE(NULLIF(EXCLUDED.storage_backend, ''), tracks.storage_backend),
+            storage_key = COALESCE(NULLIF(EXCLUDED.storage_key, ''), tracks.storage_key),
+            artist_id = COALESCE(EXCLUDED.artist_id, tracks.artist_id),
+            album_id = COALESCE(EXCLUDED.album_id, tracks.album_id),
+            disc_number = COALESCE(EXCLUDED.disc_number, tracks.disc_number),
+            track_position = COALESCE(EXCLUDED.track_position, tracks.track_position),
+            updated_at = NOW()
+            RETURNING id, status`).
 		ToSql()
 
 	if err != nil {
 		return fmt.Errorf("failed to build query: %w", err)
 	}
 
-	// Записываем ID обратно в структуру
-	err = r.db.QueryRowContext(ctx, query, args...).Scan(&t.ID)
+	// Трек ссылается на артиста и альбом — заготовки для них пишем в той же транзакции
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.saveCatalogStubs(ctx, tx, t); err != nil {
+		return err
+	}
+
+	// Записываем ID и фактический статус обратно в структуру
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&t.ID, &t.Status)
 	if err != nil {
 		return fmt.Errorf("failed to upsert track: %w", err)
 	}
 
+	return tx.Commit()
+}
+
+// saveCatalogStubs создает артиста и альбом трека, если их еще нет. Из трека известны
+// только имя и название — остальное запишет ArtistRepository/AlbumRepository.Upsert
+func (r *trackRepo) saveCatalogStubs(ctx context.Context, tx *sql.Tx, t *domain.Track) error {
+	if t.ArtistID != 0 {
+		query, args, err := r.psql.Insert("artists").
+			Columns("id", "name").
+			Values(t.ArtistID, t.Artist).
+			Suffix("ON CONFLICT DO NOTHING").
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("failed to build query: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to save artist stub: %w", err)
+		}
+	}
+
+	if t.AlbumID != 0 {
+		// Артиста альбома трек не знает (у сборников он свой), поэтому artist_id не пишем
+		query, args, err := r.psql.Insert("albums").
+			Columns("id", "title", "cover_medium").
+			Values(t.AlbumID, t.AlbumTitle, t.CoverURL).
+			Suffix("ON CONFLICT DO NOTHING").
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("failed to build query: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to save album stub: %w", err)
+		}
+	}
 	return nil
 }
+
+// SaveUpload записывает присланный пользователем файл. deezer_id у загрузки NULL,
+// поэтому дубли ловим по file_unique_id: тот же файл от другого пользователя — та же строка.
+// Если файл уже известен (в том числе как трек Deezer, загруженный ботом), запись не меняется
+func (r *trackRepo) SaveUpload(ctx context.Context, t *domain.Track) (bool, error) {
+	if t.FileUniqueID == "" {
+		return false, errors.New("upload without file_unique_id")
+	}
+	if t.Status == "" {
+		t.Status = domain.StatusReady
+	}
+
+	query, args, err := r.psql.Insert("tracks").
+		Columns("youtube_id", "file_id", "file_unique_id", "title", "artist", "duration", "cover_url", "status", "source", "match_status").
+		Values("", t.FileID, t.FileUniqueID, t.Title, t.Artist, t.Duration, t.CoverURL, t.Status, domain.SourceUpload, domain.MatchPending).
+		// Пустой апдейт нужен, чтобы RETURNING вернул и уже существующую строку.
+		// xmax = 0 только у строки, которую вставил этот запрос
+		Suffix(`ON CONFLICT (file_unique_id) WHERE file_unique_id IS NOT NULL AND file_unique_id <> ''
+            DO UPDATE SET updated_at = tracks.updated_at
+            RETURNING id, COALESCE(deezer_id, 0), status, source, COALESCE(match_status, ''), (xmax = 0)`).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var created bool
+	err = r.db.QueryRowContext(ctx, query, args...).Scan(&t.ID, &t.DeezerID, &t.Status, &t.Source, &t.MatchStatus, &created)
+	if err != nil {
+		return false, fmt.Errorf("failed to upsert upload: %w", err)
+	}
+	return created, nil
+}
 func (r *trackRepo) GetByFileUniqueID(ctx context.Context, fileUniqueID string) (*domain.Track, error) {
 	// 1. Формируем запрос через Squirrel
 	query, args, err := r.psql.Select(
 		"id",
-		"deezer_id",
-		"youtube_id",
+		"COALESCE(deezer_id, 0)",
+		"COALESCE(youtube_id, '')",
 		"title",
 		"artist",
-		"duration",
-		"cover_url",
-		"file_id",
-		"file_unique_id",
+		"COALESCE(duration, 0)",
+		"COALESCE(cover_url, '')",
+		"COALESCE(file_id, '')",
+		"COALESCE(file_unique_id, '')",
 		"created_at",
+		"COALESCE(storage_backend, '')",
+		"COALESCE(storage_key, '')",
+		"source",
+		"COALESCE(match_status, '')",
+		"COALESCE(matched_deezer_id, 0)",
 	).
 		From("tracks").
 		Where(sq.Eq{"file_unique_id": fileUniqueID}).
@@ -105,6 +205,11 @@ func (r *trackRepo) GetByFileUniqueID(ctx context.Context, fileUniqueID string)
 		&track.FileID,
 		&track.FileUniqueID,
 		&track.CreatedAt,
+		&track.StorageBackend,
+		&track.StorageKey,
+		&track.Source,
+		&track.MatchStatus,
+		&track.MatchedDeezerID,
 	)
 
 	if err != nil {
@@ -119,30 +224,74 @@ func (r *trackRepo) GetByFileUniqueID(ctx context.Context, fileUniqueID string)
 
 // GetByUserID возвращает все треки конкретного пользователя, отсортированные от новых к старым.
 func (r *trackRepo) GetByUserID(ctx context.Context, userID int64) ([]domain.Track, error) {
-	// 1. Строим запрос с JOIN, так как треки теперь связаны через user_tracks
-	query, args, err := r.psql.Select(
+	query, args, err := r.userTracks(userID).
+		OrderBy("ut.added_at DESC"). // Сортируем по дате добавления пользователем
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	return r.queryUserTracks(ctx, query, args)
+}
+
+// SearchByUserID — поиск в библиотеке по названию и артисту, самые релевантные первыми.
+// Строка поиска транслитерируется так же, как search_translit (см. music_translit
+// в миграции 000011), поэтому кириллица и латиница находят друг друга. Совпадение — по
+// полнотекстовому вектору или нечетко по триграммам (опечатки, начало слова при наборе)
+func (r *trackRepo) SearchByUserID(ctx context.Context, userID int64, q string) ([]domain.Track, error) {
+	query, args, err := r.userTracks(userID).
+		Where(sq.Or{
+			sq.Expr("t.search_vector @@ plainto_tsquery('simple', music_translit(?))", q),
+			sq.Expr("music_translit(?) <% t.search_translit", q),
+		}).
+		OrderByClause(
+			"ts_rank(t.search_vector, plainto_tsquery('simple', ?)) + "+
+				"ts_rank(t.search_vector, plainto_tsquery('simple', music_translit(?))) + "+
+				"word_similarity(music_translit(?), t.search_translit) DESC",
+			q, q, q,
+		).
+		OrderBy("ut.added_at DESC").
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	return r.queryUserTracks(ctx, query, args)
+}
+
+// userTracks — выборка треков из библиотеки пользователя (сортировку добавляет вызывающий)
+func (r *trackRepo) userTracks(userID int64) sq.SelectBuilder {
+	// Треки связаны с пользователем через user_tracks
+	return r.psql.Select(
 		"t.id",
-		"t.deezer_id",
-		"t.youtube_id",
+		"COALESCE(t.deezer_id, 0)",
+		"COALESCE(t.youtube_id, '')",
 		"t.title",
 		"t.artist",
-		"t.duration",
-		"t.cover_url",
-		"t.file_id",
-		"t.file_unique_id",
+		"COALESCE(t.duration, 0)",
+		"COALESCE(t.cover_url, '')",
+		"COALESCE(t.file_id, '')",
+		"COALESCE(t.file_unique_id, '')",
 		"t.created_at",
+		"COALESCE(t.storage_backend, '')",
+		"COALESCE(t.storage_key, '')",
+		"t.source",
+		"COALESCE(t.match_status, '')",
+		"COALESCE(t.matched_deezer_id, 0)",
+		"COALESCE(t.artist_id, 0)",
+		"COALESCE(t.album_id, 0)",
+		"COALESCE(t.disc_number, 0)",
+		"COALESCE(t.track_position, 0)",
 	).
 		From("tracks t").
 		Join("user_tracks ut ON t.id = ut.track_id").
-		Where(sq.Eq{"ut.user_id": userID}).
-		OrderBy("ut.added_at DESC"). // Сортируем по дате добавления пользователем
-		ToSql()
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to build query: %w", err)
-	}
+		Where(sq.Eq{"ut.user_id": userID})
+}
 
-	// 2. Выполняем запрос с контекстом
+// queryUserTracks выполняет запрос, построенный от userTracks
+func (r *trackRepo) queryUserTracks(ctx context.Context, query string, args []interface{}) ([]domain.Track, error) {
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("query error: %w", err)
@@ -152,7 +301,7 @@ func (r *trackRepo) GetByUserID(ctx context.Context, userID int64) ([]domain.Tra
 	var tracks []domain.Track
 	for rows.Next() {
 		var t domain.Track
-		// 3. Сканируем все поля согласно обновленной структуре
+		// Сканируем поля в порядке колонок userTracks
 		err := rows.Scan(
 			&t.ID,
 			&t.DeezerID,
@@ -164,6 +313,15 @@ func (r *trackRepo) GetByUserID(ctx context.Context, userID int64) ([]domain.Tra
 			&t.FileID,
 			&t.FileUniqueID,
 			&t.CreatedAt,
+			&t.StorageBackend,
+			&t.StorageKey,
+			&t.Source,
+			&t.MatchStatus,
+			&t.MatchedDeezerID,
+			&t.ArtistID,
+			&t.AlbumID,
+			&t.DiscNumber,
+			&t.TrackPosition,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("scan error: %w", err)
@@ -210,6 +368,36 @@ func (r *trackRepo) DeleteFromUser(ctx context.Context, userID int64, trackID in
 	return nil
 }
 
+func (r *trackRepo) GetLikedIDs(ctx context.Context, userID int64, trackIDs []int64) (map[int64]bool, error) {
+	liked := make(map[int64]bool, len(trackIDs))
+	if len(trackIDs) == 0 {
+		return liked, nil
+	}
+
+	query, args, err := r.psql.Select("track_id").
+		From("user_tracks").
+		Where(sq.Eq{"user_id": userID, "track_id": trackIDs}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		liked[id] = true
+	}
+	return liked, rows.Err()
+}
+
 func (r *trackRepo) GetByYoutubeID(ctx context.Context, youtubeID string) (*domain.Track, error) {
 	// Строим запрос
 	query, args, err := r.psql.Select(
@@ -246,19 +434,105 @@ func (r *trackRepo) GetByYoutubeID(ctx context.Context, youtubeID string) (*doma
 
 	return &t, nil
 }
+func (r *trackRepo) GetByID(ctx context.Context, id int64) (*domain.Track, error) {
+	query, args, err := r.psql.Select(
+		"id",
+		"COALESCE(deezer_id, 0)",
+		"COALESCE(youtube_id, '')",
+		"title",
+		"artist",
+		"COALESCE(duration, 0)",
+		"COALESCE(cover_url, '')",
+		"COALESCE(file_id, '')",
+		"COALESCE(file_unique_id, '')",
+		"created_at",
+		"status",
+		"COALESCE(storage_backend, '')",
+		"COALESCE(storage_key, '')",
+		"source",
+		"COALESCE(match_status, '')",
+		"COALESCE(matched_deezer_id, 0)",
+		"COALESCE(artist_id, 0)",
+		"COALESCE(album_id, 0)",
+		"COALESCE(disc_number, 0)",
+		"COALESCE(track_position, 0)",
+		"COALESCE(last_error, '')",
+		"COALESCE(failed_stage, '')",
+		"attempts",
+		"updated_at",
+	).
+		From("tracks").
+		Where(sq.Eq{"id": id}).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var t domain.Track
+	err = r.db.QueryRowContext(ctx, query, args...).Scan(
+		&t.ID,
+		&t.DeezerID,
+		&t.YoutubeID,
+		&t.Title,
+		&t.Artist,
+		&t.Duration,
+		&t.CoverURL,
+		&t.FileID,
+		&t.FileUniqueID,
+		&t.CreatedAt,
+		&t.Status,
+		&t.StorageBackend,
+		&t.StorageKey,
+		&t.Source,
+		&t.MatchStatus,
+		&t.MatchedDeezerID,
+		&t.ArtistID,
+		&t.AlbumID,
+		&t.DiscNumber,
+		&t.TrackPosition,
+		&t.LastError,
+		&t.FailedStage,
+		&t.Attempts,
+		&t.UpdatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("repository.GetByID scan error: %w", err)
+	}
+
+	return &t, nil
+}
+
 func (r *trackRepo) GetByDeezerID(ctx context.Context, deezerID int64) (*domain.Track, error) {
 	query, args, err := r.psql.Select(
 		"id",
-		"deezer_id",
-		"youtube_id",
+		"COALESCE(deezer_id, 0)",
+		"COALESCE(youtube_id, '')",
 		"title",
 		"artist",
-		"duration",
-		"cover_url",
-		"file_id",
-		"file_unique_id",
+		"COALESCE(duration, 0)",
+		"COALESCE(cover_url, '')",
+		"COALESCE(file_id, '')",
+		"COALESCE(file_unique_id, '')",
 		"created_at",
 		"status",
+		"COALESCE(storage_backend, '')",
+		"COALESCE(storage_key, '')",
+		"source",
+		"COALESCE(match_status, '')",
+		"COALESCE(matched_deezer_id, 0)",
+		"COALESCE(artist_id, 0)",
+		"COALESCE(album_id, 0)",
+		"COALESCE(disc_number, 0)",
+		"COALESCE(track_position, 0)",
+		"COALESCE(last_error, '')",
+		"COALESCE(failed_stage, '')",
+		"attempts",
+		"updated_at",
 	).
 		From("tracks").
 		Where(sq.Eq{"deezer_id": deezerID}).
@@ -282,6 +556,19 @@ func (r *trackRepo) GetByDeezerID(ctx context.Context, deezerID int64) (*domain.
 		&t.FileUniqueID,
 		&t.CreatedAt,
 		&t.Status,
+		&t.StorageBackend,
+		&t.StorageKey,
+		&t.Source,
+		&t.MatchStatus,
+		&t.MatchedDeezerID,
+		&t.ArtistID,
+		&t.AlbumID,
+		&t.DiscNumber,
+		&t.TrackPosition,
+		&t.LastError,
+		&t.FailedStage,
+		&t.Attempts,
+		&t.UpdatedAt,
 	)
 
 	if err != nil {
@@ -294,27 +581,307 @@ func (r *trackRepo) GetByDeezerID(ctx context.Context, deezerID int64) (*domain.
 	return &t, nil
 }
 
-func (r *trackRepo) UpdateStatus(ctx context.Context, deezerID int64, status string) error {
-	// Используем deezer_id как уникальный ключ для поиска
-	query := `
-        UPDATE tracks 
-        SET status = $1, updated_at = NOW() 
-        WHERE deezer_id = $2
-    `
+// GetByDeezerIDs — пакетная версия GetByDeezerID для разметки выдачи поиска.
+// Треков, которых нет в базе, в результате просто нет.
+func (r *trackRepo) GetByDeezerIDs(ctx context.Context, deezerIDs []int64) (map[int64]*domain.Track, error) {
+	tracks := make(map[int64]*domain.Track, len(deezerIDs))
+	if len(deezerIDs) == 0 {
+		return tracks, nil
+	}
+
+	query, args, err := r.psql.Select(
+		"id",
+		"deezer_id",
+		"COALESCE(youtube_id, '')",
+		"title",
+		"artist",
+		"COALESCE(duration, 0)",
+		"COALESCE(cover_url, '')",
+		"COALESCE(file_id, '')",
+		"COALESCE(file_unique_id, '')",
+		"created_at",
+		"status",
+		"COALESCE(storage_backend, '')",
+		"COALESCE(storage_key, '')",
+	).
+		From("tracks").
+		Where(sq.Eq{"deezer_id": deezerIDs}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t domain.Track
+		err := rows.Scan(
+			&t.ID,
+			&t.DeezerID,
+			&t.YoutubeID,
+			&t.Title,
+			&t.Artist,
+			&t.Duration,
+			&t.CoverURL,
+			&t.FileID,
+			&t.FileUniqueID,
+			&t.CreatedAt,
+			&t.Status,
+			&t.StorageBackend,
+			&t.StorageKey,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		tracks[t.DeezerID] = &t
+	}
+
+	return tracks, rows.Err()
+}
+
+// GetStale — самые давно не менявшиеся треки в статусе status
+func (r *trackRepo) GetStale(ctx context.Context, status string, updatedBefore time.Time, limit int) ([]domain.Track, error) {
+	query, args, err := r.psql.Select(
+		"id",
+		"COALESCE(deezer_id, 0)",
+		"COALESCE(youtube_id, '')",
+		"status",
+		"attempts",
+		"updated_at",
+	).
+		From("tracks").
+		Where(sq.Eq{"status": status}).
+		Where(sq.Lt{"updated_at": updatedBefore}).
+		OrderBy("updated_at").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var tracks []domain.Track
+	for rows.Next() {
+		var t domain.Track
+		if err := rows.Scan(&t.ID, &t.DeezerID, &t.YoutubeID, &t.Status, &t.Attempts, &t.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		tracks = append(tracks, t)
+	}
+
+	return tracks, rows.Err()
+}
+
+func (r *trackRepo) Transition(ctx context.Context, deezerID int64, to string) error {
+	sources := domain.TransitionSources(to)
+
+	update := r.psql.Update("tracks").
+		Set("status", to).
+		Set("updated_at", sq.Expr("NOW()"))
+
+	// Новый запуск цепочки или успешный финал — прошлые ошибки больше не актуальны
+	if to == domain.StatusQueued || to == domain.StatusReady {
+		update = update.
+			Set("attempts", 0).
+			Set("last_error", nil).
+			Set("failed_stage", nil)
+	}
+
+	// Проверка текущего статуса и запись — один UPDATE, гонок между "прочитал" и "записал" нет
+	query, args, err := update.
+		Where(sq.Eq{"deezer_id": deezerID, "status": sources}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
 
-	result, err := r.db.ExecContext(ctx, query, status, deezerID)
+	result, err := r.db.ExecContext(ctx, query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to update track status: %w", err)
 	}
 
-	// Проверяем, была ли обновлена хоть одна строка
 	rows, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
 	if rows == 0 {
+		return r.transitionError(ctx, deezerID, to)
+	}
+
+	return nil
+}
+
+func (r *trackRepo) RecordError(ctx context.Context, deezerID int64, stage string, reason string) error {
+	query, args, err := r.psql.Update("tracks").
+		Set("last_error", reason).
+		Set("failed_stage", stage).
+		Set("attempts", sq.Expr("attempts + 1")).
+		Set("updated_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"deezer_id": deezerID}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to record track error: %w", err)
+	}
+	return nil
+}
+
+func (r *trackRepo) MarkFailed(ctx context.Context, deezerID int64, stage string, reason string) error {
+	query, args, err := r.psql.Update("tracks").
+		Set("status", domain.StatusFailed).
+		Set("last_error", reason).
+		Set("failed_stage", stage).
+		Set("attempts", sq.Expr("attempts + 1")).
+		Set("updated_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"deezer_id": deezerID, "status": domain.TransitionSources(domain.StatusFailed)}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to mark track failed: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return r.transitionError(ctx, deezerID, domain.StatusFailed)
+	}
+
+	return nil
+}
+
+func (r *trackRepo) ResetAudio(ctx context.Context, deezerID int64, youtubeID string) error {
+	// Save не умеет обнулять поля (COALESCE), поэтому отдельный UPDATE.
+	// Пустые строки, а не NULL: GetBy* сканируют эти колонки прямо в string
+	query, args, err := r.psql.Update("tracks").
+		Set("youtube_id", youtubeID).
+		Set("file_id", "").
+		Set("file_unique_id", "").
+		Set("storage_backend", nil).
+		Set("storage_key", nil).
+		Set("updated_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"deezer_id": deezerID}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to reset track audio: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
 		return fmt.Errorf("track with deezer_id %d not found", deezerID)
 	}
+	return nil
+}
+
+// transitionError объясняет, почему UPDATE не задел ни одной строки
+func (r *trackRepo) transitionError(ctx context.Context, deezerID int64, to string) error {
+	var current string
+	err := r.db.QueryRowContext(ctx, `SELECT status FROM tracks WHERE deezer_id = $1`, deezerID).Scan(&current)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("track with deezer_id %d not found", deezerID)
+		}
+		return fmt.Errorf("failed to read track status: %w", err)
+	}
+	return fmt.Errorf("%w: %s -> %s", domain.ErrInvalidTransition, current, to)
+}
 
+func (r *trackRepo) GetPendingUploads(ctx context.Context, afterID int64, limit int) ([]domain.Track, error) {
+	query, args, err := r.psql.Select(
+		"id",
+		"title",
+		"artist",
+		"COALESCE(duration, 0)",
+	).
+		From("tracks").
+		Where(sq.Eq{"source": domain.SourceUpload, "match_status": domain.MatchPending}).
+		Where(sq.Gt{"id": afterID}).
+		OrderBy("id").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var tracks []domain.Track
+	for rows.Next() {
+		t := domain.Track{Source: domain.SourceUpload, MatchStatus: domain.MatchPending}
+		if err := rows.Scan(&t.ID, &t.Title, &t.Artist, &t.Duration); err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		tracks = append(tracks, t)
+	}
+
+	return tracks, rows.Err()
+}
+
+func (r *trackRepo) LinkUpload(ctx context.Context, trackID int64, match *domain.Track) (bool, error) {
+	// Занятость deezer_id проверяем в том же UPDATE. Если параллельно трек с этим deezer_id
+	// успели создать, упадем на уникальном индексе — ретрай задачи просто свяжет без захвата
+	query, args, err := r.psql.Update("tracks").
+		Set("match_status", domain.MatchMatched).
+		Set("matched_deezer_id", match.DeezerID).
+		Set("deezer_id", sq.Expr(
+			"COALESCE(deezer_id, CASE WHEN NOT EXISTS (SELECT 1 FROM tracks o WHERE o.deezer_id = ?) THEN ?::BIGINT END)",
+			match.DeezerID, match.DeezerID,
+		)).
+		Set("cover_url", sq.Expr("COALESCE(NULLIF(cover_url, ''), ?)", match.CoverURL)).
+		Set("updated_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": trackID, "source": domain.SourceUpload}).
+		Suffix("RETURNING COALESCE(deezer_id, 0)").
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	var deezerID int64
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&deezerID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, fmt.Errorf("upload %d not found", trackID)
+		}
+		return false, fmt.Errorf("failed to link upload: %w", err)
+	}
+	return deezerID == match.DeezerID, nil
+}
+
+func (r *trackRepo) SetMatchStatus(ctx context.Context, trackID int64, status string) error {
+	query, args, err := r.psql.Update("tracks").
+		Set("match_status", status).
+		Set("updated_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": trackID, "source": domain.SourceUpload}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to set match status: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"music-go-bot/internal/domain"
+	"testing"
+)
+
+// Загрузка без youtube_id, обложки и deezer_id должна читаться всеми GetBy*:
+// и пока пары на Deezer нет, и после того как LinkUpload отдал ей deezer_id
+func TestTrackRepo_UploadRoundTrip(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	repo := NewTrackRepo(db)
+
+	const userID, deezerID = 42, 3135556
+	if err := NewUserRepo(db).Upsert(&domain.User{ID: userID, Username: "test"}); err != nil {
+		t.Fatalf("Upsert user: %v", err)
+	}
+
+	upload := &domain.Track{FileID: "file-1", FileUniqueID: "uniq-1", Title: "Группа крови", Artist: "Кино", Duration: 285}
+	created, err := repo.SaveUpload(ctx, upload)
+	if err != nil || !created {
+		t.Fatalf("SaveUpload: created=%v err=%v", created, err)
+	}
+	if err := repo.AddToUser(ctx, userID, upload.ID); err != nil {
+		t.Fatalf("AddToUser: %v", err)
+	}
+
+	library, err := repo.GetByUserID(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetByUserID: %v", err)
+	}
+	if len(library) != 1 || library[0].ID != upload.ID || library[0].Source != domain.SourceUpload {
+		t.Fatalf("GetByUserID = %+v, want the upload", library)
+	}
+
+	found, err := repo.SearchByUserID(ctx, userID, "kino")
+	if err != nil {
+		t.Fatalf("SearchByUserID: %v", err)
+	}
+	if len(found) != 1 || found[0].ID != upload.ID {
+		t.Fatalf("SearchByUserID(kino) = %+v, want the upload", found)
+	}
+
+	byFile, err := repo.GetByFileUniqueID(ctx, upload.FileUniqueID)
+	if err != nil || byFile == nil || byFile.ID != upload.ID {
+		t.Fatalf("GetByFileUniqueID = %+v, %v", byFile, err)
+	}
+
+	claimed, err := repo.LinkUpload(ctx, upload.ID, &domain.Track{DeezerID: deezerID, CoverURL: "https://cover"})
+	if err != nil || !claimed {
+		t.Fatalf("LinkUpload: claimed=%v err=%v", claimed, err)
+	}
+
+	byDeezer, err := repo.GetByDeezerID(ctx, deezerID)
+	if err != nil {
+		t.Fatalf("GetByDeezerID: %v", err)
+	}
+	if byDeezer == nil || byDeezer.ID != upload.ID || byDeezer.YoutubeID != "" {
+		t.Fatalf("GetByDeezerID = %+v, want the linked upload", byDeezer)
+	}
+
+	byIDs, err := repo.GetByDeezerIDs(ctx, []int64{deezerID})
+	if err != nil {
+		t.Fatalf("GetByDeezerIDs: %v", err)
+	}
+	if got := byIDs[deezerID]; got == nil || got.ID != upload.ID {
+		t.Fatalf("GetByDeezerIDs = %+v, want the linked upload", byIDs)
+	}
+
+	library, err = repo.GetByUserID(ctx, userID)
+	if err != nil || len(library) != 1 || library[0].DeezerID != deezerID {
+		t.Fatalf("GetByUserID after link = %+v, %v", library, err)
+	}
+}
@@ -35,9 +35,25 @@ func (r *userRepo) Upsert(u *domain.User) error {
 	return err
 }
 
+// Ensure — создает пользователя только с id, если его еще нет. Имена существующего не трогает
+func (r *userRepo) Ensure(id int64) error {
+	query, args, err := r.psql.Insert("users").
+		Columns("id", "username", "first_name").
+		Values(id, "", "").
+		Suffix("ON CONFLICT (id) DO NOTHING").
+		ToSql()
+
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(query, args...)
+	return err
+}
+
 // GetByID — просто тянет юзера из БД
 func (r *userRepo) GetByID(id int64) (*domain.User, error) {
-	query, args, err := r.psql.Select("id", "username", "first_name", "created_at").
+	query, args, err := r.psql.Select("id", "username", "first_name", "created_at", "notify_ready").
 		From("users").
 		Where(sq.Eq{"id": id}).
 		ToSql()
@@ -47,6 +63,21 @@ func (r *userRepo) GetByID(id int64) (*domain.User, error) {
 	}
 
 	u := &domain.User{}
-	err = r.db.QueryRow(query, args...).Scan(&u.ID, &u.Username, &u.FirstName, &u.CreatedAt)
+	err = r.db.QueryRow(query, args...).Scan(&u.ID, &u.Username, &u.FirstName, &u.CreatedAt, &u.NotifyReady)
 	return u, err
 }
+
+// SetNotifyReady — включает/выключает уведомления о готовых треках
+func (r *userRepo) SetNotifyReady(id int64, enabled bool) error {
+	query, args, err := r.psql.Update("users").
+		Set("notify_ready", enabled).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(query, args...)
+	return err
+}
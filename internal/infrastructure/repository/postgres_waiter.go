@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"music-go-bot/internal/domain"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// waiterRepo реализует интерфейс domain.WaiterRepository
+type waiterRepo struct {
+	db   *sql.DB
+	psql sq.StatementBuilderType
+}
+
+func NewWaiterRepo(db *sql.DB) domain.WaiterRepository {
+	return &waiterRepo{
+		db:   db,
+		psql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+func (r *waiterRepo) Add(ctx context.Context, trackID, userID int64) error {
+	query, args, err := r.psql.Insert("track_waiters").
+		Columns("track_id", "user_id").
+		Values(trackID, userID).
+		Suffix("ON CONFLICT DO NOTHING"). // Повторный запрос того же трека — одно уведомление
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to add track waiter: %w", err)
+	}
+	return nil
+}
+
+func (r *waiterRepo) Remove(ctx context.Context, trackID, userID int64) error {
+	query, args, err := r.psql.Delete("track_waiters").
+		Where(sq.Eq{"track_id": trackID, "user_id": userID}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build query: %w", err)
+	}
+
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to remove track waiter: %w", err)
+	}
+	return nil
+}
+
+func (r *waiterRepo) List(ctx context.Context, trackID int64) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT user_id FROM track_waiters WHERE track_id = $1 ORDER BY created_at`, trackID)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var users []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		users = append(users, id)
+	}
+
+	return users, rows.Err()
+}
+
+func (r *waiterRepo) Claim(ctx context.Context, trackID, userID int64) (bool, error) {
+	query, args, err := r.psql.Delete("track_waiters").
+		Where(sq.Eq{"track_id": trackID, "user_id": userID}).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	// DELETE удаляет строку только в одной из конкурирующих транзакций — она и отправляет
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim track waiter: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,77 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"music-go-bot/internal/domain"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Сколько последних замеров этапа храним — окно скользящей статистики
+const durationWindow = 500
+
+// redisStageStats держит последние durationWindow длительностей этапа в списке Redis
+// (миллисекунды, новые слева). Перцентили считаются на чтении — список маленький.
+type redisStageStats struct {
+	client *redis.Client
+}
+
+func NewRedisStageStats(client *redis.Client) domain.StageStats {
+	return &redisStageStats{client: client}
+}
+
+func durationsKey(stage string) string {
+	return fmt.Sprintf("stats:stage_duration:%s", stage)
+}
+
+func (s *redisStageStats) RecordDuration(ctx context.Context, stage string, d time.Duration) error {
+	key := durationsKey(stage)
+
+	pipe := s.client.TxPipeline()
+	pipe.LPush(ctx, key, d.Milliseconds())
+	pipe.LTrim(ctx, key, 0, durationWindow-1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis record duration: %w", err)
+	}
+	return nil
+}
+
+func (s *redisStageStats) Durations(ctx context.Context, stage string) (domain.DurationStats, error) {
+	raw, err := s.client.LRange(ctx, durationsKey(stage), 0, -1).Result()
+	if err != nil {
+		return domain.DurationStats{}, fmt.Errorf("redis read durations: %w", err)
+	}
+
+	values := make([]int64, 0, len(raw))
+	for _, r := range raw {
+		ms, err := strconv.ParseInt(r, 10, 64)
+		if err != nil {
+			continue
+		}
+		values = append(values, ms)
+	}
+	if len(values) == 0 {
+		return domain.DurationStats{}, nil
+	}
+
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	return domain.DurationStats{
+		Samples: len(values),
+		P50:     percentile(values, 0.50),
+		P95:     percentile(values, 0.95),
+	}, nil
+}
+
+// percentile по отсортированным значениям, метод nearest-rank
+func percentile(sorted []int64, p float64) time.Duration {
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return time.Duration(sorted[idx]) * time.Millisecond
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"music-go-bot/internal/domain"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// localStorage хранит аудио в директории на диске
+type localStorage struct {
+	root string
+}
+
+func NewLocalStorage(root string) (domain.AudioStorage, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage dir: %w", err)
+	}
+	if err := os.MkdirAll(abs, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage dir: %w", err)
+	}
+	return &localStorage{root: abs}, nil
+}
+
+func (s *localStorage) Name() string {
+	return domain.StorageLocal
+}
+
+func (s *localStorage) Put(ctx context.Context, track *domain.Track, filePath string) (domain.StoredAudio, error) {
+	key := objectKey(track, filePath)
+
+	dst, err := s.path(key)
+	if err != nil {
+		return domain.StoredAudio{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return domain.StoredAudio{}, fmt.Errorf("failed to create dir: %w", err)
+	}
+
+	// Пишем во временный файл и переименовываем, чтобы читатели не увидели половину файла
+	if err := copyFile(filePath, dst+".tmp"); err != nil {
+		return domain.StoredAudio{}, err
+	}
+	if err := os.Rename(dst+".tmp", dst); err != nil {
+		os.Remove(dst + ".tmp")
+		return domain.StoredAudio{}, fmt.Errorf("failed to move file: %w", err)
+	}
+
+	return domain.StoredAudio{Key: key}, nil
+}
+
+// StreamURL возвращает file:// ссылку — прокси отдает такой файл прямо с диска
+func (s *localStorage) StreamURL(ctx context.Context, key string) (string, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(p); err != nil {
+		return "", fmt.Errorf("stored file is missing: %w", err)
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(p)}).String(), nil
+}
+
+func (s *localStorage) Delete(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove file: %w", err)
+	}
+	return nil
+}
+
+// path переводит ключ в путь на диске и не дает выйти за пределы root
+func (s *localStorage) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
+
+// objectKey — ключ файла в локальном и S3 хранилищах: tracks/<id>.<ext>
+func objectKey(track *domain.Track, filePath string) string {
+	ext := filepath.Ext(filePath)
+	if ext == "" {
+		ext = ".mp3"
+	}
+	return fmt.Sprintf("tracks/%d%s", track.ID, ext)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open source: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create destination: %w", err)
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return fmt.Errorf("failed to copy file: %w", err)
+	}
+	return out.Close()
+}
@@ -0,0 +1,282 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"music-go-bot/internal/domain"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config — настройки S3-совместимого бакета (AWS, MinIO и т.п.)
+type S3Config struct {
+	Endpoint   string // Например, http://localhost:9000 для локального MinIO
+	Region     string
+	Bucket     string
+	AccessKey  string
+	SecretKey  string
+	PresignTTL time.Duration // Сколько живет ссылка на стрим
+}
+
+// s3Storage работает с бакетом напрямую по REST API с подписью AWS Signature V4
+// (path-style адресация: <endpoint>/<bucket>/<key>, ее понимают и AWS, и MinIO).
+type s3Storage struct {
+	cfg    S3Config
+	client *http.Client
+}
+
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func NewS3Storage(cfg S3Config) domain.AudioStorage {
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.PresignTTL <= 0 {
+		cfg.PresignTTL = time.Hour
+	}
+	return &s3Storage{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+func (s *s3Storage) Name() string {
+	return domain.StorageS3
+}
+
+func (s *s3Storage) Put(ctx context.Context, track *domain.Track, filePath string) (domain.StoredAudio, error) {
+	key := objectKey(track, filePath)
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return domain.StoredAudio{}, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return domain.StoredAudio{}, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	// Хэш тела входит в подпись, поэтому читаем файл дважды
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return domain.StoredAudio{}, fmt.Errorf("failed to hash file: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return domain.StoredAudio{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key), f)
+	if err != nil {
+		return domain.StoredAudio{}, err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "audio/mpeg")
+	s.sign(req, hex.EncodeToString(h.Sum(nil)), time.Now())
+
+	if err := s.do(req); err != nil {
+		return domain.StoredAudio{}, fmt.Errorf("s3 put %s: %w", key, err)
+	}
+
+	return domain.StoredAudio{Key: key}, nil
+}
+
+// StreamURL возвращает presigned GET-ссылку, живущую PresignTTL
+func (s *s3Storage) StreamURL(ctx context.Context, key string) (string, error) {
+	u, err := url.Parse(s.objectURL(key))
+	if err != nil {
+		return "", err
+	}
+	return s.presign(u, time.Now()), nil
+}
+
+// presign — GET-ссылка на u с подписью в параметрах (подписан только host)
+func (s *s3Storage) presign(u *url.URL, now time.Time) string {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := s.scope(now)
+
+	q := url.Values{}
+	q.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	q.Set("X-Amz-Credential", s.cfg.AccessKey+"/"+scope)
+	q.Set("X-Amz-Date", amzDate)
+	q.Set("X-Amz-Expires", fmt.Sprintf("%d", int(s.cfg.PresignTTL.Seconds())))
+	q.Set("X-Amz-SignedHeaders", "host")
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		canonicalURI(u),
+		canonicalQuery(q),
+		"host:" + u.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	q.Set("X-Amz-Signature", s.signature(now, canonicalRequest))
+
+	signed := *u
+	signed.RawQuery = canonicalQuery(q)
+	return signed.String()
+}
+
+func (s *s3Storage) Delete(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+	s.sign(req, emptyPayloadHash, time.Now())
+
+	if err := s.do(req); err != nil {
+		return fmt.Errorf("s3 delete %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *s3Storage) do(req *http.Request) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (s *s3Storage) objectURL(key string) string {
+	segments := strings.Split(key, "/")
+	for i, seg := range segments {
+		segments[i] = uriEncode(seg)
+	}
+	return s.cfg.Endpoint + "/" + uriEncode(s.cfg.Bucket) + "/" + strings.Join(segments, "/")
+}
+
+// sign добавляет заголовки x-amz-* и Authorization (AWS Signature V4).
+// Подписываются host и все заголовки, уже выставленные на запросе
+func (s *s3Storage) sign(req *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		if strings.EqualFold(name, "Authorization") {
+			continue
+		}
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		// Значение без крайних пробелов, подряд идущие пробелы — один
+		canonicalHeaders.WriteString(name + ":" + strings.Join(strings.Fields(headers[name]), " ") + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI(req.URL),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, s.scope(now), signedHeaders, s.signature(now, canonicalRequest),
+	))
+}
+
+func (s *s3Storage) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.cfg.Region + "/s3/aws4_request"
+}
+
+func (s *s3Storage) signature(now time.Time, canonicalRequest string) string {
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format("20060102T150405Z"),
+		s.scope(now),
+		hex.EncodeToString(hashed[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalURI — путь, каждый сегмент которого закодирован по правилам SigV4
+// (стандартный EscapedPath оставляет, например, '$' как есть)
+func canonicalURI(u *url.URL) string {
+	if u.Path == "" {
+		return "/"
+	}
+	segments := strings.Split(u.Path, "/")
+	for i, seg := range segments {
+		segments[i] = uriEncode(seg)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery — параметры, отсортированные по ключу и закодированные по правилам SigV4
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range q[k] {
+			pairs = append(pairs, uriEncode(k)+"="+uriEncode(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode кодирует всё, кроме unreserved-символов RFC 3986 (как требует SigV4)
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
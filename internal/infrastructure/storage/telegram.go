@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"music-go-bot/internal/domain"
+	"music-go-bot/internal/infrastructure/tgerr"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Telegram гарантирует, что ссылка из getFile живет не меньше часа — берем с запасом
+const telegramFileLinkTTL = 50 * time.Minute
+
+// telegramStorage хранит аудио сообщениями в служебном чате (STORAGE_CHAT_ID).
+// Ключ: "<message_id>:<file_id>". У старых записей ключ — просто file_id.
+type telegramStorage struct {
+	bot           *tgbotapi.BotAPI
+	storageChatID int64
+
+	mu    sync.Mutex
+	links map[string]cachedLink // file_id -> ссылка на файл, пока не протухла
+}
+
+type cachedLink struct {
+	url       string
+	expiresAt time.Time
+}
+
+func NewTelegramStorage(bot *tgbotapi.BotAPI, storageChatID int64) domain.AudioStorage {
+	return &telegramStorage{
+		bot:           bot,
+		storageChatID: storageChatID,
+		links:         make(map[string]cachedLink),
+	}
+}
+
+func (s *telegramStorage) Name() string {
+	return domain.StorageTelegram
+}
+
+func (s *telegramStorage) Put(ctx context.Context, track *domain.Track, filePath string) (domain.StoredAudio, error) {
+	audioCfg := tgbotapi.NewAudio(s.storageChatID, tgbotapi.FilePath(filePath))
+	audioCfg.Title = track.Title
+	audioCfg.Performer = track.Artist
+	audioCfg.Duration = track.Duration
+
+	msg, err := s.bot.Send(audioCfg)
+	if err != nil {
+		return domain.StoredAudio{}, fmt.Errorf("bot.Send: %w", tgerr.Classify(redactURLError(err)))
+	}
+	if msg.Audio == nil {
+		return domain.StoredAudio{}, fmt.Errorf("telegram returned no audio metadata")
+	}
+
+	return domain.StoredAudio{
+		Key:          fmt.Sprintf("%d:%s", msg.MessageID, msg.Audio.FileID),
+		FileID:       msg.Audio.FileID,
+		FileUniqueID: msg.Audio.FileUniqueID,
+	}, nil
+}
+
+// StreamURL резолвит file_id в ссылку на файловый сервер Telegram и кэширует ее до истечения.
+// В ссылке токен бота — она только для прокси.
+func (s *telegramStorage) StreamURL(ctx context.Context, key string) (string, error) {
+	_, fileID := splitTelegramKey(key)
+	now := time.Now()
+
+	s.mu.Lock()
+	cached, ok := s.links[fileID]
+	s.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.url, nil
+	}
+
+	file, err := s.bot.GetFile(tgbotapi.FileConfig{FileID: fileID})
+	if err != nil {
+		return "", redactURLError(err)
+	}
+	link := file.Link(s.bot.Token)
+
+	s.mu.Lock()
+	// Заодно выкидываем протухшие ссылки, чтобы кэш не рос бесконечно
+	for id, l := range s.links {
+		if now.After(l.expiresAt) {
+			delete(s.links, id)
+		}
+	}
+	s.links[fileID] = cachedLink{url: link, expiresAt: now.Add(telegramFileLinkTTL)}
+	s.mu.Unlock()
+
+	return link, nil
+}
+
+// Delete удаляет сообщение из чата-хранилища.
+// Для старых ключей без message_id удалять нечего — Telegram не умеет удалять файл по file_id.
+func (s *telegramStorage) Delete(ctx context.Context, key string) error {
+	messageID, _ := splitTelegramKey(key)
+	if messageID == 0 {
+		return nil
+	}
+
+	if _, err := s.bot.Request(tgbotapi.NewDeleteMessage(s.storageChatID, messageID)); err != nil {
+		return fmt.Errorf("bot.DeleteMessage: %w", redactURLError(err))
+	}
+	return nil
+}
+
+// redactURLError убирает URL из сетевой ошибки: в адресах Bot API зашит токен
+func redactURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s telegram api: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
+
+func splitTelegramKey(key string) (int, string) {
+	head, fileID, found := strings.Cut(key, ":")
+	if !found {
+		return 0, key
+	}
+	messageID, err := strconv.Atoi(head)
+	if err != nil {
+		return 0, key
+	}
+	return messageID, fileID
+}
@@ -0,0 +1,47 @@
+// Package tgerr переводит ошибки Bot API в типизированные ошибки этапов (domain.TaskError)
+package tgerr
+
+import (
+	"errors"
+	"music-go-bot/internal/domain"
+	"net/http"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Если Telegram ответил 429 без retry_after
+const defaultRetryAfter = 30 * time.Second
+
+// Classify: 429 — подождать retry_after секунд, 400/403/413 — запрос не пройдет
+// и при повторе (файл слишком большой, чат не найден, бота заблокировали), остальное — временное
+func Classify(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return domain.TransientError("telegram_unavailable", err)
+	}
+
+	switch apiErr.Code {
+	case http.StatusTooManyRequests:
+		delay := time.Duration(apiErr.RetryAfter) * time.Second
+		if delay <= 0 {
+			delay = defaultRetryAfter
+		}
+		return domain.RateLimitedError("telegram_flood_wait", delay, err)
+	case http.StatusRequestEntityTooLarge:
+		return domain.PermanentError("file_too_large", err)
+	case http.StatusForbidden:
+		return domain.PermanentError("telegram_forbidden", err)
+	case http.StatusBadRequest:
+		if strings.Contains(strings.ToLower(apiErr.Message), "too big") {
+			return domain.PermanentError("file_too_large", err)
+		}
+		return domain.PermanentError("telegram_bad_request", err)
+	}
+	return domain.TransientError("telegram_error", err)
+}
@@ -0,0 +1,125 @@
+package tgerr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"music-go-bot/internal/domain"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestClassify(t *testing.T) {
+	apiErr := func(code int, msg string, retryAfter int) error {
+		return &tgbotapi.Error{Code: code, Message: msg, ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: retryAfter}}
+	}
+
+	tests := []struct {
+		name       string
+		err        error
+		wantClass  string
+		wantReason string
+		wantDelay  time.Duration // Только для rate_limited
+	}{
+		{
+			name:       "flood wait",
+			err:        apiErr(429, "Too Many Requests: retry after 35", 35),
+			wantClass:  domain.ErrorRateLimited,
+			wantReason: "telegram_flood_wait",
+			wantDelay:  35 * time.Second,
+		},
+		{
+			name:       "429 without retry_after",
+			err:        apiErr(429, "Too Many Requests", 0),
+			wantClass:  domain.ErrorRateLimited,
+			wantReason: "telegram_flood_wait",
+			wantDelay:  defaultRetryAfter,
+		},
+		{
+			name:       "wrapped 429",
+			err:        fmt.Errorf("send audio: %w", apiErr(429, "Too Many Requests: retry after 3", 3)),
+			wantClass:  domain.ErrorRateLimited,
+			wantReason: "telegram_flood_wait",
+			wantDelay:  3 * time.Second,
+		},
+		{
+			name:       "file too big",
+			err:        apiErr(400, "Bad Request: file is too big", 0),
+			wantClass:  domain.ErrorPermanent,
+			wantReason: "file_too_large",
+		},
+		{
+			name:       "entity too large",
+			err:        apiErr(413, "Request Entity Too Large", 0),
+			wantClass:  domain.ErrorPermanent,
+			wantReason: "file_too_large",
+		},
+		{
+			name:       "chat not found",
+			err:        apiErr(400, "Bad Request: chat not found", 0),
+			wantClass:  domain.ErrorPermanent,
+			wantReason: "telegram_bad_request",
+		},
+		{
+			name:       "wrong file id",
+			err:        apiErr(400, "Bad Request: wrong file identifier/HTTP URL specified", 0),
+			wantClass:  domain.ErrorPermanent,
+			wantReason: "telegram_bad_request",
+		},
+		{
+			name:       "bot blocked",
+			err:        apiErr(403, "Forbidden: bot was blocked by the user", 0),
+			wantClass:  domain.ErrorPermanent,
+			wantReason: "telegram_forbidden",
+		},
+		{
+			name:       "bot kicked",
+			err:        apiErr(403, "Forbidden: bot was kicked from the group chat", 0),
+			wantClass:  domain.ErrorPermanent,
+			wantReason: "telegram_forbidden",
+		},
+		{
+			name:       "bad gateway",
+			err:        apiErr(502, "Bad Gateway", 0),
+			wantClass:  domain.ErrorTransient,
+			wantReason: "telegram_error",
+		},
+		{
+			name:       "internal server error",
+			err:        apiErr(500, "Internal Server Error: restart", 0),
+			wantClass:  domain.ErrorTransient,
+			wantReason: "telegram_error",
+		},
+		{
+			name:       "network",
+			err:        fmt.Errorf("Post \"https://api.telegram.org/bot/sendAudio\": %w", context.DeadlineExceeded),
+			wantClass:  domain.ErrorTransient,
+			wantReason: "telegram_unavailable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Classify(tt.err)
+			se := domain.ClassifyError(err)
+			if se.Class != tt.wantClass || se.Reason != tt.wantReason {
+				t.Fatalf("got [%s] %s, want [%s] %s", se.Class, se.Reason, tt.wantClass, tt.wantReason)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("original error is lost: %v", err)
+			}
+			delay, ok := domain.RetryAfter(err)
+			if ok != (tt.wantClass == domain.ErrorRateLimited) || delay != tt.wantDelay {
+				t.Errorf("RetryAfter = %v, %v; want %v", delay, ok, tt.wantDelay)
+			}
+		})
+	}
+}
+
+func TestClassifyNil(t *testing.T) {
+	if err := Classify(nil); err != nil {
+		t.Errorf("Classify(nil) = %v", err)
+	}
+}
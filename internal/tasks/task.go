@@ -2,6 +2,8 @@ package tasks
 
 import (
 	"encoding/json"
+	"music-go-bot/internal/domain"
+	"strconv"
 
 	"github.com/hibiken/asynq"
 )
@@ -10,26 +12,62 @@ const (
 	TypeDownloadYoutube = "download:youtube"
 	TypeTelegramUpload  = "telegram:upload"
 	TypeYoutubeSearch   = "youtube:search"
+	TypeTelegramNotify  = "telegram:notify"
+
+	// Периодическая задача планировщика, к конкретному треку не относится
+	TypeReconcile = "tracks:reconcile"
+
+	// Поиск пары на Deezer для присланного файла. Не этап цепочки: статус трека не меняет
+	TypeMatchUpload = "tracks:match_upload"
+
+	// Загрузка треклиста пакета (альбома/плейлиста) и запуск цепочек по его трекам
+	TypeBatchStart = "batches:start"
 )
 
+// UserID во всех payload — кто запустил цепочку (0 — никто конкретный, например предзагрузка).
+// Priority (domain.PriorityHigh/Low) — в какие очереди идут следующие этапы
 type DownloadYoutubePayload struct {
 	TrackID   int64  `json:"track_id"`
 	YoutubeID string `json:"youtube_id"`
+	UserID    int64  `json:"user_id"`
+	Priority  string `json:"priority"`
 }
 type TelegramUploadPayload struct {
 	TrackID  int64  `json:"track_id"`
 	FilePath string `json:"file_path"`
 	UserID   int64  `json:"user_id"` // Чтобы знать, кому отправить уведомление "Готово"
+	Priority string `json:"priority"`
 }
 type SearchYoutubePayload struct {
 	DeezerID int64
+	UserID   int64  `json:"user_id"`
+	Priority string `json:"priority"`
+}
+
+// NotifyPayload — финальный этап: разослать ждущим пользователям результат цепочки
+type NotifyPayload struct {
+	TrackID int64 `json:"track_id"`
+	UserID  int64 `json:"user_id"`
+}
+
+// MatchUploadPayload — здесь TrackID внутренний: у загрузки нет Deezer ID
+type MatchUploadPayload struct {
+	TrackID int64 `json:"track_id"`
+}
+
+// BatchStartPayload — пакет, треклист которого надо загрузить. UserID — владелец пакета
+type BatchStartPayload struct {
+	BatchID int64 `json:"batch_id"`
+	UserID  int64 `json:"user_id"`
 }
 
 // Вспомогательная функция для создания задачи
-func NewDownloadYoutubeTask(trackID int64, youtubeID string) (*asynq.Task, error) {
+func NewDownloadYoutubeTask(trackID int64, youtubeID string, userID int64, priority string) (*asynq.Task, error) {
 	payload, err := json.Marshal(DownloadYoutubePayload{
 		TrackID:   trackID,
 		YoutubeID: youtubeID,
+		UserID:    userID,
+		Priority:  priority,
 	})
 	if err != nil {
 		return nil, err
@@ -37,10 +75,12 @@ func NewDownloadYoutubeTask(trackID int64, youtubeID string) (*asynq.Task, error
 	return asynq.NewTask(TypeDownloadYoutube, payload), nil
 }
 
-func NewTelegramUploadTask(trackID int64, filePath string) (*asynq.Task, error) {
+func NewTelegramUploadTask(trackID int64, filePath string, userID int64, priority string) (*asynq.Task, error) {
 	payload, err := json.Marshal(TelegramUploadPayload{
 		TrackID:  trackID,
 		FilePath: filePath,
+		UserID:   userID,
+		Priority: priority,
 	})
 	if err != nil {
 		return nil, err
@@ -48,17 +88,58 @@ func NewTelegramUploadTask(trackID int64, filePath string) (*asynq.Task, error)
 	return asynq.NewTask(TypeTelegramUpload, payload), nil
 }
 
-func NewSearchYoutubeTask(deezerID int64) (*asynq.Task, error) {
-	payload, err := json.Marshal(SearchYoutubePayload{DeezerID: deezerID})
+func NewSearchYoutubeTask(deezerID int64, userID int64, priority string) (*asynq.Task, error) {
+	payload, err := json.Marshal(SearchYoutubePayload{DeezerID: deezerID, UserID: userID, Priority: priority})
 	if err != nil {
 		return nil, err
 	}
 	return asynq.NewTask(TypeYoutubeSearch, payload), nil
 }
+
+func NewNotifyTask(trackID int64, userID int64) (*asynq.Task, error) {
+	payload, err := json.Marshal(NotifyPayload{TrackID: trackID, UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeTelegramNotify, payload), nil
+}
+
+func NewMatchUploadTask(trackID int64) (*asynq.Task, error) {
+	payload, err := json.Marshal(MatchUploadPayload{TrackID: trackID})
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeMatchUpload, payload), nil
+}
+
+func NewBatchStartTask(batchID int64, userID int64) (*asynq.Task, error) {
+	payload, err := json.Marshal(BatchStartPayload{BatchID: batchID, UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeBatchStart, payload), nil
+}
+
+// NewReconcileTask — проверка треков, застрявших на этапах цепочки
+func NewReconcileTask() *asynq.Task {
+	return asynq.NewTask(TypeReconcile, nil)
+}
+
+// ExtractUserID — кто запустил цепочку, из payload любого этапа
+func ExtractUserID(t *asynq.Task) int64 {
+	var data struct {
+		UserID int64 `json:"user_id"`
+	}
+	if err := json.Unmarshal(t.Payload(), &data); err != nil {
+		return 0
+	}
+	return data.UserID
+}
 func ExtractID(t *asynq.Task) int64 {
+	// В payload загрузки/скачивания поле track_id — на самом деле Deezer ID
 	var data struct {
 		DeezerID int64 `json:"DeezerID"`
-		TrackID  int64 `json:"TrackID"`
+		TrackID  int64 `json:"track_id"`
 	}
 	if err := json.Unmarshal(t.Payload(), &data); err != nil {
 		return 0
@@ -68,3 +149,35 @@ func ExtractID(t *asynq.Task) int64 {
 	}
 	return data.TrackID
 }
+
+// TaskID — детерминированный ID задачи этапа для трека: "youtube:search:<deezer_id>".
+// Пока задача с таким ID живет в очереди, вторую такую же asynq не примет
+func TaskID(taskType string, deezerID int64) string {
+	return taskType + ":" + strconv.FormatInt(deezerID, 10)
+}
+
+// StageOf — этап (статус трека), которому соответствует тип задачи
+func StageOf(taskType string) string {
+	switch taskType {
+	case TypeYoutubeSearch:
+		return domain.StatusSearching
+	case TypeDownloadYoutube:
+		return domain.StatusDownloading
+	case TypeTelegramUpload:
+		return domain.StatusUploading
+	}
+	return taskType
+}
+
+// TypeOfStage — тип задачи, которая выполняет этап stage (обратная к StageOf)
+func TypeOfStage(stage string) string {
+	switch stage {
+	case domain.StatusSearching:
+		return TypeYoutubeSearch
+	case domain.StatusDownloading:
+		return TypeDownloadYoutube
+	case domain.StatusUploading:
+		return TypeTelegramUpload
+	}
+	return ""
+}
@@ -7,28 +7,47 @@ import (
 	"music-go-bot/internal/domain"
 	"os"
 	"time"
-
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// NotifyQueueClient — после финала цепочки ставим рассылку уведомлений
+type NotifyQueueClient interface {
+	EnqueueNotify(ctx context.Context, trackID int64, userID int64) error
+}
+
+// UploadQueueClient — очередь для этапа загрузки: уведомления в конце цепочки
+// и повторное скачивание, если файл пропал
+type UploadQueueClient interface {
+	NotifyQueueClient
+	EnqueueDownload(ctx context.Context, trackID int64, ytID string, userID int64, priority string) error
+}
+
+// TGUploaderUsecase — финальный этап цепочки: кладет скачанный файл в хранилище.
+// Исторически это был только Telegram, теперь бэкенд выбирается конфигом (AUDIO_STORAGE).
 type TGUploaderUsecase struct {
-	repo          domain.TrackRepository
-	bot           *tgbotapi.BotAPI
-	storageChatID int64
+	repo      domain.TrackRepository
+	storage   domain.AudioStorage
+	queue     UploadQueueClient
+	events    domain.TrackEventPublisher
+	artifacts domain.ArtifactStore
 }
 
-func NewTGUploaderUsecase(repo domain.TrackRepository, bot *tgbotapi.BotAPI, storageID int64) *TGUploaderUsecase {
+func NewTGUploaderUsecase(
+	repo domain.TrackRepository,
+	storage domain.AudioStorage,
+	queue UploadQueueClient,
+	events domain.TrackEventPublisher,
+	artifacts domain.ArtifactStore,
+) *TGUploaderUsecase {
 	return &TGUploaderUsecase{
-		repo:          repo,
-		bot:           bot,
-		storageChatID: storageID,
+		repo:      repo,
+		storage:   storage,
+		queue:     queue,
+		events:    events,
+		artifacts: artifacts,
 	}
 }
 
-func (u *TGUploaderUsecase) UploadFile(ctx context.Context, deezerID int64, filePath string) error {
-	// Важно: удаляем файл только после попытки загрузки
-	defer os.Remove(filePath)
-
+func (u *TGUploaderUsecase) UploadFile(ctx context.Context, deezerID int64, filePath string, userID int64, priority string) error {
 	// 1. Получаем инфо о треке из базы для метаданных
 	track, err := u.repo.GetByDeezerID(ctx, deezerID)
 	if err != nil {
@@ -38,37 +57,70 @@ func (u *TGUploaderUsecase) UploadFile(ctx context.Context, deezerID int64, file
 		return fmt.Errorf("track %d not found", deezerID)
 	}
 
-	l := slog.With("track_id", track.ID, "file", filePath)
-	l.Info("Начало загрузки файла в Telegram...")
+	// Файла нет (удалили с диска вручную, потеряли volume) — повторять загрузку бесполезно,
+	// возвращаем трек на скачивание
+	if _, err := os.Stat(filePath); err != nil {
+		return u.redownload(ctx, track, filePath, userID, priority)
+	}
+
+	if ok, err := enterStage(ctx, u.repo, deezerID, domain.StatusUploading); !ok {
+		return err
+	}
 
-	// 2. Формируем аудио сообщение
-	audioCfg := tgbotapi.NewAudio(u.storageChatID, tgbotapi.FilePath(filePath))
-	audioCfg.Title = track.Title
-	audioCfg.Performer = track.Artist
-	audioCfg.Duration = track.Duration
+	l := slog.With("track_id", track.ID, "file", filePath, "storage", u.storage.Name())
+	l.Info("Начало загрузки файла в хранилище...")
+	publishEvent(ctx, u.events, domain.TrackEvent{DeezerID: deezerID, TrackID: track.ID, Stage: domain.StageUploading})
 
-	// 3. Отправка
+	// 2. Отправка
 	start := time.Now()
-	msg, err := u.bot.Send(audioCfg)
+	stored, err := u.storage.Put(ctx, track, filePath)
 	if err != nil {
-		track.Status = "error"
-		u.repo.Save(ctx, track)
-		return fmt.Errorf("bot.Send: %w", err)
+		return fmt.Errorf("storage.Put: %w", err)
 	}
 
-	if msg.Audio == nil {
-		return fmt.Errorf("telegram returned no audio metadata")
-	}
-
-	// 4. Сохраняем результат
-	track.FileID = msg.Audio.FileID
-	track.FileUniqueID = msg.Audio.FileUniqueID
-	track.Status = domain.StatusReady
+	// 3. Сохраняем результат
+	track.StorageBackend = u.storage.Name()
+	track.StorageKey = stored.Key
+	track.FileID = stored.FileID
+	track.FileUniqueID = stored.FileUniqueID
 
 	if err := u.repo.Save(ctx, track); err != nil {
-		return fmt.Errorf("failed to save file_id: %w", err)
+		return fmt.Errorf("failed to save storage key: %w", err)
+	}
+	if err := u.repo.Transition(ctx, deezerID, domain.StatusReady); err != nil {
+		return fmt.Errorf("failed to mark track ready: %w", err)
 	}
 
+	publishEvent(ctx, u.events, domain.TrackEvent{DeezerID: deezerID, TrackID: track.ID, Stage: domain.StageReady})
 	l.Info("Файл успешно загружен", "took", time.Since(start).String())
+
+	// Загрузка подтверждена — только теперь локальная копия не нужна.
+	// До этого момента файл переживает любые ретраи
+	if err := u.artifacts.Remove(track.YoutubeID); err != nil {
+		l.Warn("Failed to remove uploaded artifact", "error", err)
+	}
+
+	// Трек уже ready: ретрай загрузки ничего не даст, поэтому сбой постановки только логируем
+	if err := u.queue.EnqueueNotify(ctx, deezerID, userID); err != nil {
+		l.Error("Failed to enqueue notify task", "error", err)
+	}
+	return nil
+}
+
+// redownload возвращает трек на этап скачивания, когда файла для загрузки нет.
+// Если артефакт уже снова в кэше, yt-dlp не запустится
+func (u *TGUploaderUsecase) redownload(ctx context.Context, track *domain.Track, filePath string, userID int64, priority string) error {
+	if track.YoutubeID == "" {
+		return domain.PermanentError("artifact_missing", fmt.Errorf("downloaded file %s is gone and track has no youtube_id", filePath))
+	}
+
+	if ok, err := enterStage(ctx, u.repo, track.DeezerID, domain.StatusDownloading); !ok {
+		return err
+	}
+
+	slog.Warn("Downloaded file is gone, re-enqueueing download", "track_id", track.ID, "file", filePath, "yt_id", track.YoutubeID)
+	if err := u.queue.EnqueueDownload(ctx, track.DeezerID, track.YoutubeID, userID, priority); err != nil {
+		return fmt.Errorf("queue.EnqueueDownload: %w", err)
+	}
 	return nil
 }
@@ -7,64 +7,101 @@ import (
 	"music-go-bot/internal/domain"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/lrstanley/go-ytdlp"
 )
 
 // Интерфейс очереди, чтобы поставить задачу на Upload
 type QueueClient interface {
-	EnqueueUpload(ctx context.Context, trackID int64, filePath string) error
+	EnqueueUpload(ctx context.Context, trackID int64, filePath string, userID int64, priority string) error
 }
 
 type YTDownloaderUsecase struct {
-	repo  domain.TrackRepository
-	queue QueueClient
+	repo      domain.TrackRepository
+	queue     QueueClient
+	events    domain.TrackEventPublisher
+	artifacts domain.ArtifactStore
 }
 
-func NewYTDownloaderUsecase(repo domain.TrackRepository, queue QueueClient) *YTDownloaderUsecase {
+func NewYTDownloaderUsecase(repo domain.TrackRepository, queue QueueClient, events domain.TrackEventPublisher, artifacts domain.ArtifactStore) *YTDownloaderUsecase {
 	return &YTDownloaderUsecase{
-		repo:  repo,
-		queue: queue,
+		repo:      repo,
+		queue:     queue,
+		events:    events,
+		artifacts: artifacts,
 	}
 }
 
-func (u *YTDownloaderUsecase) Download(ctx context.Context, deezerID int64, ytID string) error {
+func (u *YTDownloaderUsecase) Download(ctx context.Context, deezerID int64, ytID string, userID int64, priority string) error {
 	slog.Info("Запуск скачивания с YouTube", "yt_id", ytID)
 
-	// 1. Скачиваем
-	filePath, err := u.downloadFile(ctx, ytID)
-	if err != nil {
-		track, _ := u.repo.GetByDeezerID(ctx, deezerID)
-		if track != nil {
-			track.Status = "error"
-			u.repo.Save(ctx, track)
+	if ok, err := enterStage(ctx, u.repo, deezerID, domain.StatusDownloading); !ok {
+		return err
+	}
+	publishEvent(ctx, u.events, domain.TrackEvent{DeezerID: deezerID, Stage: domain.StageDownloading})
+
+	// 1. Скачиваем, если это видео еще не лежит в кэше артефактов
+	filePath, ok := u.artifacts.Get(ytID)
+	if ok {
+		slog.Info("Файл уже скачан, пропускаем yt-dlp", "yt_id", ytID, "file", filePath)
+	} else {
+		var err error
+		filePath, err = u.downloadFile(ctx, deezerID, ytID)
+		if err != nil {
+			// Отмена убивает yt-dlp через контекст, недокачанное удаляет downloadFile
+			if stageCancelled(ctx, u.repo, deezerID, domain.StatusDownloading) {
+				return nil
+			}
+			return err
 		}
-		return fmt.Errorf("ytdlp.Run: %w", err)
 	}
 
 	// 2. Пинкаем очередь на загрузку
 	slog.Info("Скачивание завершено, ставим задачу на Upload", "file", filePath)
-	return u.queue.EnqueueUpload(ctx, deezerID, filePath)
+	return u.queue.EnqueueUpload(ctx, deezerID, filePath, userID, priority)
 }
 
-func (u *YTDownloaderUsecase) downloadFile(ctx context.Context, ytID string) (string, error) {
-	tmpDir := "bot/downloads"
-	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+func (u *YTDownloaderUsecase) downloadFile(ctx context.Context, deezerID int64, ytID string) (string, error) {
+	outputPath, err := u.artifacts.TempPath(ytID, ".mp3")
+	if err != nil {
 		return "", err
 	}
+	// Недокачанный файл и промежуточные файлы yt-dlp (.part, .webm) не нужны ни при каком исходе:
+	// удачный результат Put уже перенес в кэш
+	defer removeWithSuffixes(outputPath)
 
-	outputPath := filepath.Join(tmpDir, fmt.Sprintf("%s.mp3", ytID))
 	dl := ytdlp.New().
 		ExtractAudio().
 		AudioFormat("mp3").
 		AudioQuality("0").
 		Output(outputPath).
-		NoPlaylist()
+		NoPlaylist().
+		ProgressFunc(time.Second, func(update ytdlp.ProgressUpdate) {
+			// Проценты скачивания уходят клиенту через события
+			publishEvent(ctx, u.events, domain.TrackEvent{
+				DeezerID: deezerID,
+				Stage:    domain.StageDownloading,
+				Progress: int(update.Percent()),
+			})
+		})
 
 	videoURL := fmt.Sprintf("https://www.youtube.com/watch?v=%s", ytID)
-	if _, err := dl.Run(ctx, videoURL); err != nil {
-		return "", err
+	if res, err := dl.Run(ctx, videoURL); err != nil {
+		return "", fmt.Errorf("ytdlp.Run: %w", classifyYtdlpError(res, err))
 	}
 
-	return outputPath, nil
+	filePath, err := u.artifacts.Put(ctx, ytID, outputPath)
+	if err != nil {
+		return "", fmt.Errorf("artifacts.Put: %w", err)
+	}
+	return filePath, nil
+}
+
+// removeWithSuffixes удаляет файл и все файлы с тем же префиксом имени
+func removeWithSuffixes(path string) {
+	matches, _ := filepath.Glob(path + "*")
+	for _, m := range matches {
+		os.Remove(m)
+	}
 }
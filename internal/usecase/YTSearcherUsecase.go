@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
-	"math"
 	"music-go-bot/internal/domain"
 
 	"github.com/lrstanley/go-ytdlp"
@@ -13,54 +12,123 @@ import (
 
 type SearchQueueClient interface {
 	// После поиска нам нужно пнуть загрузчик
-	EnqueueDownload(ctx context.Context, deezerID int64, ytID string) error
+	EnqueueDownload(ctx context.Context, deezerID int64, ytID string, userID int64, priority string) error
+	// Или, если совпадение сомнительное, сообщить ждущим
+	EnqueueNotify(ctx context.Context, deezerID int64, userID int64) error
 }
 type YTSearcherUsecase struct {
-	repo  domain.TrackRepository
-	queue SearchQueueClient
+	repo       domain.TrackRepository
+	candidates domain.CandidateRepository
+	queue      SearchQueueClient
+	events     domain.TrackEventPublisher
+	matcher    MatcherConfig
 }
 
-func NewSearchUsecaseYT(repo domain.TrackRepository, queue SearchQueueClient) *YTSearcherUsecase {
+func NewSearchUsecaseYT(
+	repo domain.TrackRepository,
+	candidates domain.CandidateRepository,
+	queue SearchQueueClient,
+	events domain.TrackEventPublisher,
+	matcher MatcherConfig,
+) *YTSearcherUsecase {
 	return &YTSearcherUsecase{
-		repo:  repo,
-		queue: queue,
+		repo:       repo,
+		candidates: candidates,
+		queue:      queue,
+		events:     events,
+		matcher:    matcher.withDefaults(),
 	}
 }
 
 // ExecuteSearch — это метод, который будет вызывать воркер из очереди
-func (u *YTSearcherUsecase) ExecuteSearch(ctx context.Context, deezerID int64) error {
+func (u *YTSearcherUsecase) ExecuteSearch(ctx context.Context, deezerID int64, userID int64, priority string) error {
 	slog.Info("Запуск поиска на YouTube", "deezer_id", deezerID)
 
+	if ok, err := enterStage(ctx, u.repo, deezerID, domain.StatusSearching); !ok {
+		return err
+	}
+	publishEvent(ctx, u.events, domain.TrackEvent{DeezerID: deezerID, Stage: domain.StageSearching})
+
 	// 1. Получаем данные трека из базы, чтобы знать что искать
 	track, err := u.repo.GetByDeezerID(ctx, deezerID)
 	if err != nil {
 		return fmt.Errorf("track not found in db: %w", err)
 	}
+	if track == nil {
+		return fmt.Errorf("track %d not found in db", deezerID)
+	}
+
+	// Видео, на которые пожаловались ("не тот трек"), не выбираем никогда
+	rejected, err := u.candidates.GetRejected(ctx, track.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load rejected videos: %w", err)
+	}
 
-	// 2. Выполняем поиск
-	ytID, err := u.findIDOnYoutube(ctx, track.Artist, track.Title, float64(track.Duration))
+	// 2. Выполняем поиск и оцениваем кандидатов
+	ranked, err := u.findOnYoutube(ctx, track.Artist, track.Title, float64(track.Duration), rejected)
 	if err != nil {
-		// Если не нашли — помечаем ошибку в базе
-		track.Status = domain.StatusError
-		_ = u.repo.Save(ctx, track)
+		if stageCancelled(ctx, u.repo, deezerID, domain.StatusSearching) {
+			return nil
+		}
+		// Ошибку и статус в базе фиксирует ErrorHandler воркера (с учетом ретраев)
 		return fmt.Errorf("youtube search failed: %w", err)
 	}
 
-	// 3. Обновляем track в базе: сохраняем найденный YoutubeID
-	track.YoutubeID = ytID
+	// 3. Сохраняем топ кандидатов — по ним потом можно выбрать другое видео
+	if len(ranked) > u.matcher.Candidates {
+		ranked = ranked[:u.matcher.Candidates]
+	}
+	candidates := make([]domain.TrackCandidate, len(ranked))
+	for i, e := range ranked {
+		candidates[i] = domain.TrackCandidate{
+			YoutubeID: e.ID,
+			Title:     e.Title,
+			Channel:   e.channel(),
+			Duration:  int(e.Duration),
+			Score:     e.Score,
+		}
+	}
+	if err := u.candidates.Replace(ctx, track.ID, candidates); err != nil {
+		return fmt.Errorf("failed to save candidates: %w", err)
+	}
+
+	best := ranked[0]
+	slog.Info("Лучший кандидат на YouTube",
+		"deezer_id", deezerID,
+		"yt_id", best.ID,
+		"title", best.Title,
+		"channel", best.channel(),
+		"score", best.Score,
+	)
+
+	// 4. Сомнительное совпадение не качаем молча — ждем, пока выберут видео вручную
+	if best.Score < u.matcher.Threshold {
+		if ok, err := enterStage(ctx, u.repo, deezerID, domain.StatusLowConfidence); !ok {
+			return err
+		}
+		publishEvent(ctx, u.events, domain.TrackEvent{DeezerID: deezerID, TrackID: track.ID, Stage: domain.StageLowConfidence})
+		// Статус уже сменился, ретрай поиска сюда не вернется — сбой постановки только логируем
+		if err := u.queue.EnqueueNotify(ctx, deezerID, userID); err != nil {
+			slog.Error("Failed to enqueue notify task", "deezer_id", deezerID, "error", err)
+		}
+		return nil
+	}
+
+	// 5. Обновляем track в базе: сохраняем найденный YoutubeID
+	track.YoutubeID = best.ID
 	if err := u.repo.Save(ctx, track); err != nil {
 		return fmt.Errorf("failed to update track with ytID: %w", err)
 	}
 
-	// 4. ПИНАЕМ ОЧЕРЕДЬ НА СКАЧИВАНИЕ
-	slog.Info("YouTube ID найден, ставим задачу на Download", "yt_id", ytID)
-	return u.queue.EnqueueDownload(ctx, deezerID, ytID)
+	// 6. ПИНАЕМ ОЧЕРЕДЬ НА СКАЧИВАНИЕ
+	slog.Info("YouTube ID найден, ставим задачу на Download", "yt_id", best.ID)
+	return u.queue.EnqueueDownload(ctx, deezerID, best.ID, userID, priority)
 }
 
-// Внутренний метод самого поиска (твоя логика с ytdlp)
-func (u *YTSearcherUsecase) findIDOnYoutube(ctx context.Context, artist, title string, targetDuration float64) (string, error) {
+// findOnYoutube ищет трек через ytsearch и возвращает кандидатов от лучшего к худшему
+func (u *YTSearcherUsecase) findOnYoutube(ctx context.Context, artist, title string, targetDuration float64, rejected map[string]bool) ([]scoredEntry, error) {
 	query := fmt.Sprintf("%s - %s", artist, title)
-	searchQuery := fmt.Sprintf("ytsearch5:%s", query)
+	searchQuery := fmt.Sprintf("ytsearch%d:%s", u.matcher.SearchSize, query)
 
 	result, err := ytdlp.New().
 		DumpSingleJSON().
@@ -68,47 +136,33 @@ func (u *YTSearcherUsecase) findIDOnYoutube(ctx context.Context, artist, title s
 		Run(ctx, searchQuery)
 
 	if err != nil {
-		return "", err
+		return nil, classifyYtdlpError(result, err)
 	}
 
 	var response struct {
-		Entries []struct {
-			ID       string  `json:"id"`
-			Duration float64 `json:"duration"`
-			Title    string  `json:"title"`
-		} `json:"entries"`
+		Entries []ytEntry `json:"entries"`
 	}
 
 	if err := json.Unmarshal([]byte(result.Stdout), &response); err != nil {
-		return "", err
+		return nil, err
 	}
 
+	// Пустая выдача не изменится от повтора — новый поиск запустит только новый запрос трека
 	if len(response.Entries) == 0 {
-		return "", fmt.Errorf("nothing found")
+		return nil, domain.PermanentError("not_found", fmt.Errorf("nothing found"))
 	}
 
-	var bestMatchID string
-	minDiff := -1.0
-
-	for _, entry := range response.Entries {
-		// Пропускаем слишком длинные видео в любом случае
-		if entry.Duration > 1200 {
-			continue
-		}
-
-		// Вычисляем абсолютную разницу в секундах
-		// Используем math.Abs для поиска ближайшего значения
-		diff := math.Abs(entry.Duration - targetDuration)
-
-		if minDiff == -1.0 || diff < minDiff {
-			minDiff = diff
-			bestMatchID = entry.ID
+	entries := response.Entries[:0]
+	for _, e := range response.Entries {
+		if !rejected[e.ID] {
+			entries = append(entries, e)
 		}
 	}
 
-	if bestMatchID == "" {
-		return "", fmt.Errorf("no suitable track found within duration limits")
+	ranked := rankEntries(entries, artist, title, targetDuration)
+	if len(ranked) == 0 {
+		return nil, domain.PermanentError("not_found", fmt.Errorf("no suitable track found within duration limits"))
 	}
 
-	return bestMatchID, nil
+	return ranked, nil
 }
@@ -0,0 +1,246 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"music-go-bot/internal/domain"
+	"music-go-bot/internal/infrastructure/deezer"
+	"strconv"
+)
+
+// BatchQueueClient — задача, которая загружает треклист пакета и запускает цепочки
+type BatchQueueClient interface {
+	EnqueueBatchStart(ctx context.Context, batchID int64, userID int64) error
+}
+
+// BatchUsecase — альбомы и плейлисты целиком: по цепочке на трек и общий прогресс.
+// Треклист загружается в воркере (задача batches:start, см. Run).
+// Пакет завершается, когда ни одна его цепочка больше не идет (см. TrackFinished).
+type BatchUsecase struct {
+	repo      domain.BatchRepository
+	trackRepo domain.TrackRepository
+	trackUC   *TrackUsecase
+	links     *LinkResolverUsecase
+	queue     BatchQueueClient
+}
+
+func NewBatchUsecase(repo domain.BatchRepository, trackRepo domain.TrackRepository, trackUC *TrackUsecase, links *LinkResolverUsecase, queue BatchQueueClient) *BatchUsecase {
+	return &BatchUsecase{
+		repo:      repo,
+		trackRepo: trackRepo,
+		trackUC:   trackUC,
+		links:     links,
+		queue:     queue,
+	}
+}
+
+// CreateFromDeezer — пакет по альбому или плейлисту Deezer
+func (u *BatchUsecase) CreateFromDeezer(ctx context.Context, userID int64, kind string, id int64, addToLibrary bool) (*domain.Batch, error) {
+	if kind != LinkAlbum && kind != LinkPlaylist {
+		return nil, fmt.Errorf("unsupported batch kind %q", kind)
+	}
+
+	return u.Create(ctx, &domain.Batch{
+		UserID:       userID,
+		Kind:         kind,
+		Source:       LinkDeezer,
+		SourceID:     strconv.FormatInt(id, 10),
+		AddToLibrary: addToLibrary,
+	})
+}
+
+// Create заводит пакет в статусе pending и ставит задачу batches:start.
+// Треклист и запуск цепочек — в воркере: альбом из сотни треков не держит запрос,
+// а отвалившийся клиент не оставит пакет без треков
+func (u *BatchUsecase) Create(ctx context.Context, batch *domain.Batch) (*domain.Batch, error) {
+	batch.Status = domain.BatchPending
+	if err := u.repo.Create(ctx, batch); err != nil {
+		return nil, fmt.Errorf("repo.CreateBatch: %w", err)
+	}
+
+	if err := u.queue.EnqueueBatchStart(ctx, batch.ID, batch.UserID); err != nil {
+		if failErr := u.repo.Fail(context.WithoutCancel(ctx), batch.ID); failErr != nil {
+			slog.Error("Batch: failed to mark batch failed", "batch_id", batch.ID, "error", failErr)
+		}
+		return nil, fmt.Errorf("failed to enqueue batch start: %w", err)
+	}
+
+	slog.Info("Batch created", "batch_id", batch.ID, "user_id", batch.UserID, "kind", batch.Kind, "source", batch.Source)
+	batch.Tracks = []domain.BatchTrack{}
+	return batch, nil
+}
+
+// Run — задача batches:start: загружает треклист и запускает цепочку по каждому треку
+// с низким приоритетом: альбом целиком — это импорт, он не должен забивать очередь тем,
+// кто ждет один трек. Уже готовые треки не перекачиваются, упавшие запускаются заново.
+// Повтор после сбоя безопасен: треки и цепочки не дублируются
+func (u *BatchUsecase) Run(ctx context.Context, userID, batchID int64) error {
+	batch, err := u.repo.GetByID(ctx, userID, batchID)
+	if err != nil {
+		return fmt.Errorf("repo.GetBatch: %w", err)
+	}
+	if batch == nil {
+		return domain.PermanentError("batch_not_found", domain.ErrBatchNotFound)
+	}
+	if batch.Status != domain.BatchPending {
+		return nil // Прошлая попытка успела запустить пакет
+	}
+
+	resolved, err := u.links.Resolve(ctx, MusicLink{Source: batch.Source, Kind: batch.Kind, ID: batch.SourceID})
+	if err != nil {
+		if errors.Is(err, ErrLinkNotResolved) || errors.Is(err, deezer.ErrNotFound) {
+			return domain.PermanentError("tracklist_not_found", err)
+		}
+		return domain.TransientError("tracklist_unavailable", err)
+	}
+
+	// Треки привязываем к пакету до запуска цепочек: пока пакет pending, TrackFinished
+	// его не видит и не завершит раньше времени из-за еще не запущенных idle-треков
+	tracks := make([]*domain.Track, 0, len(resolved.Tracks))
+	trackIDs := make([]int64, 0, len(resolved.Tracks))
+	for _, t := range resolved.Tracks {
+		track, _, err := u.trackUC.EnsureTrackByDeezer(ctx, t)
+		if err != nil {
+			return fmt.Errorf("ensure track %d: %w", t.DeezerID, err)
+		}
+		tracks = append(tracks, track)
+		trackIDs = append(trackIDs, track.ID)
+	}
+	if err := u.repo.AddTracks(ctx, batch.ID, trackIDs); err != nil {
+		return fmt.Errorf("repo.AddBatchTracks: %w", err)
+	}
+
+	for _, track := range tracks {
+		// Готовые не проверяем в хранилище по одному: пропавший файл перекачает первое прослушивание
+		if track.Status == domain.StatusReady {
+			continue
+		}
+		if _, err := u.trackUC.GetPlaybackState(ctx, *track, 0, domain.PriorityLow); err != nil {
+			slog.Warn("Batch: failed to start track", "batch_id", batch.ID, "deezer_id", track.DeezerID, "error", err)
+		}
+	}
+
+	started, err := u.repo.Start(ctx, batch.ID, resolved.Title, resolved.Missed)
+	if err != nil {
+		return fmt.Errorf("repo.StartBatch: %w", err)
+	}
+	if !started {
+		return nil
+	}
+	batch.Status = domain.BatchRunning
+
+	// Все треки уже были готовы или закончились, пока мы ставили остальные
+	if err := u.checkComplete(ctx, batch); err != nil {
+		return err
+	}
+
+	slog.Info("Batch started", "batch_id", batch.ID, "user_id", batch.UserID, "kind", batch.Kind, "tracks", len(trackIDs), "missed", resolved.Missed)
+	return nil
+}
+
+// Fail — треклист пакета так и не загрузился: пакет больше ничего не ждет
+func (u *BatchUsecase) Fail(ctx context.Context, batchID int64) error {
+	if err := u.repo.Fail(ctx, batchID); err != nil {
+		return fmt.Errorf("repo.FailBatch: %w", err)
+	}
+	slog.Warn("Batch failed: tracklist unavailable", "batch_id", batchID)
+	return nil
+}
+
+// Get — пакет с прогрессом и треками по порядку
+func (u *BatchUsecase) Get(ctx context.Context, userID, batchID int64) (*domain.Batch, error) {
+	batch, err := u.repo.GetByID(ctx, userID, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("usecase.GetBatch: %w", err)
+	}
+	if batch == nil {
+		return nil, domain.ErrBatchNotFound
+	}
+
+	batch.Tracks, err = u.repo.GetTracks(ctx, batch.ID)
+	if err != nil {
+		return nil, fmt.Errorf("usecase.GetBatch.Tracks: %w", err)
+	}
+	if batch.Tracks == nil {
+		batch.Tracks = []domain.BatchTrack{}
+	}
+	return batch, nil
+}
+
+// TrackFinished вызывается, когда цепочка трека закончилась (задача уведомления):
+// пакеты, в которых это был последний идущий трек, завершаются
+func (u *BatchUsecase) TrackFinished(ctx context.Context, deezerID int64) error {
+	track, err := u.trackRepo.GetByDeezerID(ctx, deezerID)
+	if err != nil {
+		return fmt.Errorf("repo.GetByDeezerID: %w", err)
+	}
+	if track == nil {
+		return nil
+	}
+
+	batches, err := u.repo.GetRunningByTrack(ctx, track.ID)
+	if err != nil {
+		return fmt.Errorf("repo.GetRunningByTrack: %w", err)
+	}
+	for i := range batches {
+		if err := u.checkComplete(ctx, &batches[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *BatchUsecase) checkComplete(ctx context.Context, batch *domain.Batch) error {
+	progress, err := u.repo.GetProgress(ctx, batch.ID)
+	if err != nil {
+		return err
+	}
+	if !progress.Done() {
+		return nil
+	}
+
+	completed, err := u.repo.Complete(ctx, batch.ID)
+	if err != nil {
+		return err
+	}
+	if !completed {
+		return nil
+	}
+	slog.Info("Batch completed",
+		"batch_id", batch.ID,
+		"total", progress.Total,
+		"ready", progress.Ready,
+		"failed", progress.Failed,
+		"cancelled", progress.Cancelled,
+	)
+
+	if batch.AddToLibrary {
+		u.addToLibrary(ctx, batch)
+	}
+	return nil
+}
+
+// addToLibrary кладет готовые треки пакета в библиотеку владельца.
+// Пакет уже завершен — сбой по отдельному треку только логируем
+func (u *BatchUsecase) addToLibrary(ctx context.Context, batch *domain.Batch) {
+	tracks, err := u.repo.GetTracks(ctx, batch.ID)
+	if err != nil {
+		slog.Error("Batch: failed to load tracks for library", "batch_id", batch.ID, "error", err)
+		return
+	}
+
+	var added int
+	for _, t := range tracks {
+		if t.Status != domain.StatusReady {
+			continue
+		}
+		if err := u.trackRepo.AddToUser(ctx, batch.UserID, t.ID); err != nil {
+			slog.Error("Batch: failed to add track to library", "batch_id", batch.ID, "track_id", t.ID, "error", err)
+			continue
+		}
+		added++
+	}
+	slog.Info("Batch tracks added to library", "batch_id", batch.ID, "user_id", batch.UserID, "added", added)
+}
@@ -0,0 +1,146 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"music-go-bot/internal/domain"
+	"music-go-bot/internal/infrastructure/deezer"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Сколько чего показывать на странице артиста
+const (
+	artistTopTracks = 10
+	artistAlbums    = 50
+	artistRelated   = 10
+)
+
+// ArtistPage — страница артиста
+type ArtistPage struct {
+	Artist    domain.Artist   `json:"artist"`
+	TopTracks []domain.Track  `json:"top_tracks"`
+	Albums    []domain.Album  `json:"albums"`
+	Related   []domain.Artist `json:"related"`
+}
+
+// AlbumPage — альбом с треклистом по порядку дисков и номеров
+type AlbumPage struct {
+	Album  domain.Album   `json:"album"`
+	Tracks []domain.Track `json:"tracks"`
+}
+
+// CatalogUsecase — страницы артистов и альбомов Deezer. Все, что пришло от Deezer,
+// сохраняется в базе; треки размечаются локальным статусом (есть ли у нас, готов ли)
+type CatalogUsecase struct {
+	deezer  *SearchUsecaseDZ
+	artists domain.ArtistRepository
+	albums  domain.AlbumRepository
+	tracks  domain.TrackRepository
+}
+
+func NewCatalogUsecase(deezer *SearchUsecaseDZ, artists domain.ArtistRepository, albums domain.AlbumRepository, tracks domain.TrackRepository) *CatalogUsecase {
+	return &CatalogUsecase{
+		deezer:  deezer,
+		artists: artists,
+		albums:  albums,
+		tracks:  tracks,
+	}
+}
+
+// GetArtist — артист, его популярные треки, альбомы и похожие артисты.
+// Несуществующий артист — domain.ErrArtistNotFound
+func (u *CatalogUsecase) GetArtist(ctx context.Context, artistID int64) (*ArtistPage, error) {
+	page := &ArtistPage{}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		artist, err := u.deezer.GetArtist(gctx, artistID)
+		if err != nil {
+			return err
+		}
+		page.Artist = *artist
+		return nil
+	})
+	g.Go(func() (err error) {
+		page.TopTracks, err = u.deezer.GetArtistTopTracks(gctx, artistID, artistTopTracks)
+		return err
+	})
+	g.Go(func() (err error) {
+		page.Albums, err = u.deezer.GetArtistAlbums(gctx, artistID, artistAlbums)
+		return err
+	})
+	g.Go(func() (err error) {
+		page.Related, err = u.deezer.GetRelatedArtists(gctx, artistID, artistRelated)
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		if errors.Is(err, deezer.ErrNotFound) {
+			return nil, domain.ErrArtistNotFound
+		}
+		return nil, err
+	}
+
+	// Артист раньше альбомов: альбомы ссылаются на него
+	artists := append([]domain.Artist{page.Artist}, page.Related...)
+	if err := u.artists.Upsert(ctx, artists); err != nil {
+		slog.Warn("Failed to save artists", "artist_id", artistID, "error", err)
+	} else if err := u.albums.Upsert(ctx, page.Albums); err != nil {
+		slog.Warn("Failed to save artist albums", "artist_id", artistID, "error", err)
+	}
+
+	if err := u.annotate(ctx, page.TopTracks); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// GetAlbum — альбом и его треклист. Несуществующий альбом — domain.ErrAlbumNotFound
+func (u *CatalogUsecase) GetAlbum(ctx context.Context, albumID int64) (*AlbumPage, error) {
+	album, tracks, err := u.deezer.GetAlbum(ctx, albumID)
+	if err != nil {
+		if errors.Is(err, deezer.ErrNotFound) {
+			return nil, domain.ErrAlbumNotFound
+		}
+		return nil, err
+	}
+
+	artist := domain.Artist{ID: album.ArtistID, Name: album.ArtistName}
+	if err := u.artists.Upsert(ctx, []domain.Artist{artist}); err != nil {
+		slog.Warn("Failed to save album artist", "album_id", albumID, "error", err)
+	} else if err := u.albums.Upsert(ctx, []domain.Album{*album}); err != nil {
+		slog.Warn("Failed to save album", "album_id", albumID, "error", err)
+	}
+
+	if tracks == nil {
+		tracks = []domain.Track{}
+	}
+	if err := u.annotate(ctx, tracks); err != nil {
+		return nil, err
+	}
+	return &AlbumPage{Album: *album, Tracks: tracks}, nil
+}
+
+// annotate проставляет трекам из Deezer внутренний ID и статус, если трек у нас уже есть.
+// Без статуса — трек еще ни разу не запускали
+func (u *CatalogUsecase) annotate(ctx context.Context, tracks []domain.Track) error {
+	ids := make([]int64, len(tracks))
+	for i, t := range tracks {
+		ids[i] = t.DeezerID
+	}
+
+	local, err := u.tracks.GetByDeezerIDs(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("repo.GetByDeezerIDs: %w", err)
+	}
+
+	for i := range tracks {
+		if t, ok := local[tracks[i].DeezerID]; ok {
+			tracks[i].ID = t.ID
+			tracks[i].Status = t.Status
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"music-go-bot/internal/domain"
+	"time"
+)
+
+// publishEvent отправляет событие этапа. Это best-effort: сбой Redis не должен ронять цепочку.
+func publishEvent(ctx context.Context, pub domain.TrackEventPublisher, event domain.TrackEvent) {
+	if pub == nil {
+		return
+	}
+	if err := pub.Publish(ctx, event); err != nil {
+		slog.Warn("Failed to publish track event",
+			"deezer_id", event.DeezerID,
+			"stage", event.Stage,
+			"error", err,
+		)
+	}
+}
+
+// enterStage переводит трек в этап воркера.
+// false без ошибки — трек этот этап больше не ждет (отменен, заблокирован), задачу надо тихо завершить.
+func enterStage(ctx context.Context, repo domain.TrackRepository, deezerID int64, status string) (bool, error) {
+	err := repo.Transition(ctx, deezerID, status)
+	if errors.Is(err, domain.ErrInvalidTransition) {
+		slog.Warn("Track is not waiting for this stage, skipping task",
+			"deezer_id", deezerID,
+			"stage", status,
+			"reason", err,
+		)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// stageCancelled — задачу отменили (CancelJob): контекст отменен, а трек уже не на этапе status.
+// Такую задачу завершаем без ошибки — ретраить и помечать трек failed нечего.
+// При остановке воркера контекст тоже отменяется, но трек остается на этапе — там нужен ретрай
+func stageCancelled(ctx context.Context, repo domain.TrackRepository, deezerID int64, status string) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+
+	// Контекст задачи уже отменен — читаем в своем
+	readCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	track, err := repo.GetByDeezerID(readCtx, deezerID)
+	if err != nil || track == nil || track.Status == status {
+		return false
+	}
+
+	slog.Info("Task cancelled, track left the stage", "deezer_id", deezerID, "stage", status, "status", track.Status)
+	return true
+}
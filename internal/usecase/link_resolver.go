@@ -0,0 +1,377 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"music-go-bot/internal/domain"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lrstanley/go-ytdlp"
+)
+
+// Откуда ссылка
+const (
+	LinkDeezer  = "deezer"
+	LinkYoutube = "youtube"
+	LinkSpotify = "spotify"
+)
+
+// Что за ссылка
+const (
+	LinkTrack    = "track"
+	LinkAlbum    = "album"
+	LinkPlaylist = "playlist"
+)
+
+var ErrLinkNotResolved = errors.New("link could not be resolved to deezer tracks")
+
+// MusicLink — распознанная ссылка на музыкальный сервис
+type MusicLink struct {
+	Source string // LinkDeezer, LinkYoutube, LinkSpotify
+	Kind   string // LinkTrack, LinkAlbum, LinkPlaylist
+	ID     string
+}
+
+// ResolvedLink — треки Deezer, в которые превратилась ссылка
+type ResolvedLink struct {
+	Title  string // Название альбома/плейлиста (для трека — "Artist — Title")
+	Tracks []domain.Track
+	Missed int // Сколько треков из YouTube/Spotify не нашлось на Deezer
+}
+
+// Минимальная оценка, с которой трек Deezer считается тем же, что и на YouTube/Spotify
+const deezerMatchThreshold = 0.5
+
+var nextDataRe = regexp.MustCompile(`(?s)<script id="__NEXT_DATA__" type="application/json">(.*?)</script>`)
+
+// LinkResolverUsecase превращает ссылки Deezer/YouTube/Spotify в треки Deezer.
+// Deezer отдает метаданные напрямую, остальное сопоставляется с Deezer по артисту и названию.
+type LinkResolverUsecase struct {
+	deezer *SearchUsecaseDZ
+	client *http.Client
+}
+
+func NewLinkResolverUsecase(deezer *SearchUsecaseDZ) *LinkResolverUsecase {
+	return &LinkResolverUsecase{
+		deezer: deezer,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (u *LinkResolverUsecase) Resolve(ctx context.Context, link MusicLink) (*ResolvedLink, error) {
+	switch link.Source {
+	case LinkDeezer:
+		return u.resolveDeezer(ctx, link)
+	case LinkYoutube:
+		return u.resolveYoutube(ctx, link)
+	case LinkSpotify:
+		return u.resolveSpotify(ctx, link)
+	}
+	return nil, fmt.Errorf("unsupported link source %q", link.Source)
+}
+
+// ExpandShortLink раскрывает короткие ссылки (deezer.page.link, spotify.link) по редиректам
+func (u *LinkResolverUsecase) ExpandShortLink(ctx context.Context, raw string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, raw, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to expand short link: %w", err)
+	}
+	resp.Body.Close()
+
+	return resp.Request.URL.String(), nil
+}
+
+func (u *LinkResolverUsecase) resolveDeezer(ctx context.Context, link MusicLink) (*ResolvedLink, error) {
+	id, err := strconv.ParseInt(link.ID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid deezer id %q", link.ID)
+	}
+
+	switch link.Kind {
+	case LinkTrack:
+		track, err := u.deezer.GetTrack(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return &ResolvedLink{Title: track.Artist + " — " + track.Title, Tracks: []domain.Track{*track}}, nil
+	case LinkAlbum:
+		title, tracks, err := u.deezer.GetAlbumTracks(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return &ResolvedLink{Title: title, Tracks: tracks}, nil
+	case LinkPlaylist:
+		title, tracks, err := u.deezer.GetPlaylistTracks(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return &ResolvedLink{Title: title, Tracks: tracks}, nil
+	}
+	return nil, fmt.Errorf("unsupported deezer link kind %q", link.Kind)
+}
+
+// externalTrack — трек с чужого сервиса, который надо найти на Deezer
+type externalTrack struct {
+	Artist   string
+	Title    string
+	Duration int // Секунды, 0 — неизвестно
+}
+
+func (u *LinkResolverUsecase) resolveYoutube(ctx context.Context, link MusicLink) (*ResolvedLink, error) {
+	if link.Kind == LinkTrack {
+		ext, err := u.youtubeVideo(ctx, link.ID)
+		if err != nil {
+			return nil, err
+		}
+		return u.matchAll(ctx, ext.Artist+" — "+ext.Title, []externalTrack{ext})
+	}
+
+	// Плейлисты и альбомы YouTube Music — через yt-dlp, он умеет их листать
+	result, err := ytdlp.New().
+		DumpSingleJSON().
+		FlatPlaylist().
+		PlaylistEnd(maxCollectionTracks).
+		Run(ctx, "https://www.youtube.com/playlist?list="+link.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list youtube playlist: %w", err)
+	}
+
+	var playlist struct {
+		Title   string    `json:"title"`
+		Entries []ytEntry `json:"entries"`
+	}
+	if err := json.Unmarshal([]byte(result.Stdout), &playlist); err != nil {
+		return nil, fmt.Errorf("failed to decode youtube playlist: %w", err)
+	}
+
+	tracks := make([]externalTrack, 0, len(playlist.Entries))
+	for _, e := range playlist.Entries {
+		ext := splitVideoTitle(e.Title, e.channel())
+		ext.Duration = int(e.Duration)
+		tracks = append(tracks, ext)
+	}
+	return u.matchAll(ctx, strings.TrimPrefix(playlist.Title, "Album - "), tracks)
+}
+
+// youtubeVideo берет название и канал через oEmbed — это быстрее, чем запускать yt-dlp
+func (u *LinkResolverUsecase) youtubeVideo(ctx context.Context, videoID string) (externalTrack, error) {
+	q := url.Values{}
+	q.Set("url", "https://www.youtube.com/watch?v="+videoID)
+	q.Set("format", "json")
+
+	var oembed struct {
+		Title      string `json:"title"`
+		AuthorName string `json:"author_name"`
+	}
+	if err := u.getJSON(ctx, "https://www.youtube.com/oembed?"+q.Encode(), &oembed); err != nil {
+		return externalTrack{}, fmt.Errorf("youtube oembed: %w", err)
+	}
+
+	return splitVideoTitle(oembed.Title, oembed.AuthorName), nil
+}
+
+// Разделители артиста и названия в заголовках клипов: дефис и тире
+var videoTitleSeparators = []string{" - ", " – ", " — "}
+
+// splitVideoTitle — "Artist - Title (Official Video)" -> артист и название.
+// Если разделителя в названии нет, артистом считается канал (без " - Topic" и "VEVO").
+func splitVideoTitle(title, channel string) externalTrack {
+	cut, sepLen := -1, 0
+	for _, sep := range videoTitleSeparators {
+		if i := strings.Index(title, sep); i >= 0 && (cut < 0 || i < cut) {
+			cut, sepLen = i, len(sep)
+		}
+	}
+	if cut >= 0 {
+		return externalTrack{Artist: strings.TrimSpace(title[:cut]), Title: strings.TrimSpace(title[cut+sepLen:])}
+	}
+
+	artist := strings.TrimSuffix(channel, " - Topic")
+	artist = strings.TrimSuffix(artist, "VEVO")
+	return externalTrack{Artist: strings.TrimSpace(artist), Title: strings.TrimSpace(title)}
+}
+
+func (u *LinkResolverUsecase) resolveSpotify(ctx context.Context, link MusicLink) (*ResolvedLink, error) {
+	// Публичного API без ключей у Spotify нет, а embed-страница отдает все данные в __NEXT_DATA__
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("https://open.spotify.com/embed/%s/%s", link.Kind, url.PathEscape(link.ID)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("spotify embed request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("spotify embed returned status %d", resp.StatusCode)
+	}
+
+	page, err := io.ReadAll(io.LimitReader(resp.Body, 5<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	m := nextDataRe.FindSubmatch(page)
+	if m == nil {
+		return nil, fmt.Errorf("spotify embed has no data")
+	}
+
+	var data struct {
+		Props struct {
+			PageProps struct {
+				State struct {
+					Data struct {
+						Entity spotifyEntity `json:"entity"`
+					} `json:"data"`
+				} `json:"state"`
+			} `json:"pageProps"`
+		} `json:"props"`
+	}
+	if err := json.Unmarshal(m[1], &data); err != nil {
+		return nil, fmt.Errorf("failed to decode spotify data: %w", err)
+	}
+	entity := data.Props.PageProps.State.Data.Entity
+
+	if link.Kind == LinkTrack {
+		ext := externalTrack{Artist: entity.artistNames(), Title: entity.name(), Duration: entity.Duration / 1000}
+		return u.matchAll(ctx, ext.Artist+" — "+ext.Title, []externalTrack{ext})
+	}
+
+	tracks := make([]externalTrack, 0, len(entity.TrackList))
+	for _, t := range entity.TrackList {
+		tracks = append(tracks, externalTrack{Artist: t.Subtitle, Title: t.Title, Duration: t.Duration / 1000})
+		if len(tracks) == maxCollectionTracks {
+			break
+		}
+	}
+	return u.matchAll(ctx, entity.name(), tracks)
+}
+
+type spotifyEntity struct {
+	Name     string `json:"name"`
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle"`
+	Duration int    `json:"duration"` // Миллисекунды
+	Artists  []struct {
+		Name string `json:"name"`
+	} `json:"artists"`
+	TrackList []struct {
+		Title    string `json:"title"`
+		Subtitle string `json:"subtitle"` // Артисты через запятую
+		Duration int    `json:"duration"`
+	} `json:"trackList"`
+}
+
+func (e spotifyEntity) name() string {
+	if e.Name != "" {
+		return e.Name
+	}
+	return e.Title
+}
+
+func (e spotifyEntity) artistNames() string {
+	if len(e.Artists) == 0 {
+		return e.Subtitle
+	}
+	names := make([]string, 0, len(e.Artists))
+	for _, a := range e.Artists {
+		names = append(names, a.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// matchAll ищет каждый трек на Deezer. Ненайденные пропускаются и считаются в Missed
+func (u *LinkResolverUsecase) matchAll(ctx context.Context, title string, tracks []externalTrack) (*ResolvedLink, error) {
+	resolved := &ResolvedLink{Title: title}
+	for _, ext := range tracks {
+		track, err := u.matchOnDeezer(ctx, ext)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			slog.Warn("Failed to match track on Deezer", "artist", ext.Artist, "title", ext.Title, "error", err)
+		}
+		if track == nil {
+			resolved.Missed++
+			continue
+		}
+		resolved.Tracks = append(resolved.Tracks, *track)
+	}
+
+	if len(resolved.Tracks) == 0 {
+		return nil, ErrLinkNotResolved
+	}
+	return resolved, nil
+}
+
+// matchOnDeezer — лучший трек из поиска Deezer по артисту и названию (nil, если уверенного нет)
+func (u *LinkResolverUsecase) matchOnDeezer(ctx context.Context, ext externalTrack) (*domain.Track, error) {
+	title := coreTitle(ext.Title)
+	found, err := u.deezer.SearchDeezer(ctx, strings.TrimSpace(ext.Artist+" "+title))
+	if err != nil {
+		return nil, err
+	}
+
+	best, score := bestDeezerMatch(found, ext.Artist, ext.Title, ext.Duration)
+	if score < deezerMatchThreshold {
+		return nil, nil
+	}
+	return best, nil
+}
+
+// bestDeezerMatch — трек из выдачи Deezer, больше всего похожий на artist/title, и его оценка.
+// Оценка — доля слов названия и артиста, нашедшихся в исходных тегах; длительность только штрафует
+func bestDeezerMatch(found []domain.Track, artist, title string, duration int) (*domain.Track, float64) {
+	sourceTokens := tokenSet(artist + " " + title)
+
+	var best *domain.Track
+	bestScore := 0.0
+	for i := range found {
+		dz := &found[i]
+		score := 0.6*coverage(coreTitle(dz.Title), sourceTokens) + 0.4*coverage(dz.Artist, sourceTokens)
+		if duration > 0 && dz.Duration > 0 {
+			// Длительность — только как тай-брейк между одинаковыми названиями
+			score -= math.Min(0.2, math.Abs(float64(dz.Duration-duration))/150)
+		}
+		if score > bestScore {
+			best, bestScore = dz, score
+		}
+	}
+	return best, bestScore
+}
+
+func (u *LinkResolverUsecase) getJSON(ctx context.Context, rawURL string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(dst)
+}
@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"music-go-bot/internal/domain"
+	"testing"
+)
+
+func TestSplitVideoTitle(t *testing.T) {
+	tests := []struct {
+		title, channel string
+		want           externalTrack
+	}{
+		{"Кино - Группа крови", "Kino Official", externalTrack{Artist: "Кино", Title: "Группа крови"}},
+		{"Rick Astley - Never Gonna Give You Up (Official Music Video)", "Rick Astley",
+			externalTrack{Artist: "Rick Astley", Title: "Never Gonna Give You Up (Official Music Video)"}},
+		// Разбивается по первому " - ": остальное — часть названия
+		{"Daft Punk - One More Time - Radio Edit", "Daft Punk", externalTrack{Artist: "Daft Punk", Title: "One More Time - Radio Edit"}},
+		{"Кино – Кукушка", "Kino", externalTrack{Artist: "Кино", Title: "Кукушка"}},
+		{"Земфира — Хочешь?", "Zemfira", externalTrack{Artist: "Земфира", Title: "Хочешь?"}},
+		// Без разделителя артист — канал
+		{"Bohemian Rhapsody", "Queen - Topic", externalTrack{Artist: "Queen", Title: "Bohemian Rhapsody"}},
+		{"Hello", "AdeleVEVO", externalTrack{Artist: "Adele", Title: "Hello"}},
+		{"Hello", "Adele VEVO", externalTrack{Artist: "Adele", Title: "Hello"}},
+		{"  Spaces  ", "  Channel  ", externalTrack{Artist: "Channel", Title: "Spaces"}},
+		// Дефис без пробелов — часть названия, а не разделитель
+		{"Jay-Z Freestyle", "JayZVEVO", externalTrack{Artist: "JayZ", Title: "Jay-Z Freestyle"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if got := splitVideoTitle(tt.title, tt.channel); got != tt.want {
+				t.Errorf("splitVideoTitle(%q, %q) = %+v, want %+v", tt.title, tt.channel, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBestDeezerMatch(t *testing.T) {
+	original := domain.Track{DeezerID: 1, Title: "Группа крови", Artist: "Кино", Duration: 285}
+	remaster := domain.Track{DeezerID: 2, Title: "Группа крови (Remastered)", Artist: "Кино", Duration: 290}
+	live := domain.Track{DeezerID: 3, Title: "Группа крови (Live)", Artist: "Кино", Duration: 420}
+	cover := domain.Track{DeezerID: 4, Title: "Группа крови", Artist: "Metallica", Duration: 285}
+	other := domain.Track{DeezerID: 5, Title: "Кукушка", Artist: "Кино", Duration: 400}
+
+	tests := []struct {
+		name          string
+		found         []domain.Track
+		artist, title string
+		duration      int
+		wantID        int64 // 0 — ничего
+		minScore      float64
+		maxScore      float64
+	}{
+		{"no results", nil, "Кино", "Группа крови", 285, 0, 0, 0},
+		{"exact match", []domain.Track{other, cover, original}, "Кино", "Группа крови", 285, 1, 0.99, 1},
+		{"case and punctuation ignored", []domain.Track{original}, "КИНО", "группа крови!", 0, 1, 0.99, 1},
+		{"youtube suffix stripped from source", []domain.Track{original}, "Кино", "Группа крови (Official Video)", 285, 1, 0.99, 1},
+		{"duration breaks tie between versions", []domain.Track{live, remaster, original}, "Кино", "Группа крови", 285, 1, 0.99, 1},
+		{"closest duration wins without exact version", []domain.Track{live, remaster}, "Кино", "Группа крови", 285, 2, 0.9, 1},
+		{"unknown duration keeps first best", []domain.Track{remaster, original}, "Кино", "Группа крови", 0, 2, 0.99, 1},
+		{"other artist scores lower", []domain.Track{cover}, "Кино", "Группа крови", 285, 4, 0.55, 0.65},
+		{"other song from same artist is below threshold", []domain.Track{other}, "Кино", "Группа крови", 285, 5, 0, deezerMatchThreshold},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			best, score := bestDeezerMatch(tt.found, tt.artist, tt.title, tt.duration)
+			var gotID int64
+			if best != nil {
+				gotID = best.DeezerID
+			}
+			if gotID != tt.wantID {
+				t.Errorf("best = %d, want %d (score %.2f)", gotID, tt.wantID, score)
+			}
+			if score < tt.minScore || score > tt.maxScore {
+				t.Errorf("score = %.3f, want in [%.2f, %.2f]", score, tt.minScore, tt.maxScore)
+			}
+		})
+	}
+}
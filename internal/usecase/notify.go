@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"music-go-bot/internal/domain"
+)
+
+// NotifyUsecase — последний этап цепочки: каждому, кто ждал трек, присылает аудио
+// или объясняет, почему не получилось. Каждый получает уведомление ровно один раз:
+// ожидание забирается атомарно (Claim) перед отправкой.
+type NotifyUsecase struct {
+	trackRepo domain.TrackRepository
+	waiters   domain.WaiterRepository
+	userRepo  domain.UserRepository
+	messenger domain.Messenger
+}
+
+func NewNotifyUsecase(
+	trackRepo domain.TrackRepository,
+	waiters domain.WaiterRepository,
+	userRepo domain.UserRepository,
+	messenger domain.Messenger,
+) *NotifyUsecase {
+	return &NotifyUsecase{
+		trackRepo: trackRepo,
+		waiters:   waiters,
+		userRepo:  userRepo,
+		messenger: messenger,
+	}
+}
+
+func (u *NotifyUsecase) Notify(ctx context.Context, deezerID int64, requestedBy int64) error {
+	track, err := u.trackRepo.GetByDeezerID(ctx, deezerID)
+	if err != nil {
+		return fmt.Errorf("repo.GetByDeezerID: %w", err)
+	}
+	if track == nil {
+		return nil
+	}
+
+	// Цепочку успели перезапустить — ждущие дождутся ее финала
+	switch track.Status {
+	case domain.StatusReady, domain.StatusFailed, domain.StatusLowConfidence:
+	default:
+		slog.Info("Track is not final anymore, skipping notify", "deezer_id", deezerID, "status", track.Status)
+		return nil
+	}
+
+	userIDs, err := u.waiters.List(ctx, track.ID)
+	if err != nil {
+		return fmt.Errorf("repo.ListWaiters: %w", err)
+	}
+
+	var errs []error
+	for _, userID := range userIDs {
+		claimed, err := u.waiters.Claim(ctx, track.ID, userID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !claimed {
+			continue // Уже отправил другой воркер
+		}
+
+		if !u.wantsNotifications(userID) {
+			continue
+		}
+
+		if err := u.send(ctx, userID, track); err != nil {
+			slog.Warn("Failed to notify user", "deezer_id", deezerID, "user_id", userID, "error", err)
+			if domain.IsPermanentError(err) {
+				continue // Бота заблокировали или чат недоступен — повтор не поможет
+			}
+			// Возвращаем ожидание — ретрай задачи отправит только тем, кому не дошло
+			if addErr := u.waiters.Add(ctx, track.ID, userID); addErr != nil {
+				slog.Error("Failed to restore track waiter", "user_id", userID, "error", addErr)
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	slog.Info("Track waiters notified",
+		"deezer_id", deezerID,
+		"status", track.Status,
+		"requested_by", requestedBy,
+		"waiters", len(userIDs),
+		"failed", len(errs),
+	)
+	return errors.Join(errs...)
+}
+
+func (u *NotifyUsecase) wantsNotifications(userID int64) bool {
+	user, err := u.userRepo.GetByID(userID)
+	if err != nil {
+		// Не смогли прочитать настройку — по умолчанию уведомления включены
+		return true
+	}
+	return user.NotifyReady
+}
+
+func (u *NotifyUsecase) send(ctx context.Context, userID int64, track *domain.Track) error {
+	name := fmt.Sprintf("%s — %s", track.Artist, track.Title)
+
+	switch track.Status {
+	case domain.StatusReady:
+		if track.FileID != "" {
+			return u.messenger.SendAudio(ctx, userID, track)
+		}
+		// Аудио лежит не в Telegram — file_id нет, слушать можно в приложении
+		return u.messenger.SendText(ctx, userID, "✅ "+name+" готов — слушай в приложении.")
+	case domain.StatusLowConfidence:
+		return u.messenger.SendText(ctx, userID, fmt.Sprintf(
+			"🤔 %s\nНе нашел точного совпадения на YouTube. Пришли ссылку на нужное видео: /wrong %d <ссылка>",
+			name, track.DeezerID,
+		))
+	default:
+		return u.messenger.SendText(ctx, userID, fmt.Sprintf(
+			"❌ %s\nНе удалось подготовить трек (этап: %s). Попробуй позже.",
+			name, track.FailedStage,
+		))
+	}
+}
@@ -0,0 +1,114 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"music-go-bot/internal/domain"
+	"strings"
+)
+
+const maxPlaylistTitleLen = 128
+
+var ErrInvalidPlaylistTitle = errors.New("invalid playlist title")
+
+type PlaylistUsecase struct {
+	repo    domain.PlaylistRepository
+	trackUC *TrackUsecase
+}
+
+func NewPlaylistUsecase(repo domain.PlaylistRepository, trackUC *TrackUsecase) *PlaylistUsecase {
+	return &PlaylistUsecase{
+		repo:    repo,
+		trackUC: trackUC,
+	}
+}
+
+func (u *PlaylistUsecase) Create(ctx context.Context, userID int64, title string) (*domain.Playlist, error) {
+	title, err := normalizePlaylistTitle(title)
+	if err != nil {
+		return nil, err
+	}
+
+	playlist := &domain.Playlist{UserID: userID, Title: title}
+	if err := u.repo.Create(ctx, playlist); err != nil {
+		return nil, fmt.Errorf("usecase.CreatePlaylist: %w", err)
+	}
+	return playlist, nil
+}
+
+func (u *PlaylistUsecase) List(ctx context.Context, userID int64) ([]domain.Playlist, error) {
+	playlists, err := u.repo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("usecase.ListPlaylists: %w", err)
+	}
+	if playlists == nil {
+		return []domain.Playlist{}, nil
+	}
+	return playlists, nil
+}
+
+// Get возвращает плейлист вместе с упорядоченным списком треков
+func (u *PlaylistUsecase) Get(ctx context.Context, userID, playlistID int64) (*domain.Playlist, error) {
+	playlist, err := u.repo.GetByID(ctx, userID, playlistID)
+	if err != nil {
+		return nil, fmt.Errorf("usecase.GetPlaylist: %w", err)
+	}
+	if playlist == nil {
+		return nil, domain.ErrPlaylistNotFound
+	}
+
+	entries, err := u.repo.GetEntries(ctx, playlist.ID)
+	if err != nil {
+		return nil, fmt.Errorf("usecase.GetPlaylist.Entries: %w", err)
+	}
+	playlist.Tracks = entries
+	if playlist.Tracks == nil {
+		playlist.Tracks = []domain.PlaylistEntry{}
+	}
+
+	return playlist, nil
+}
+
+func (u *PlaylistUsecase) Rename(ctx context.Context, userID, playlistID int64, title string) error {
+	title, err := normalizePlaylistTitle(title)
+	if err != nil {
+		return err
+	}
+	return u.repo.Rename(ctx, userID, playlistID, title)
+}
+
+func (u *PlaylistUsecase) Delete(ctx context.Context, userID, playlistID int64) error {
+	return u.repo.Delete(ctx, userID, playlistID)
+}
+
+// AddTrack добавляет трек Deezer в конец плейлиста.
+// Запись в tracks создается без запуска цепочки — трек скачается, когда его включат.
+func (u *PlaylistUsecase) AddTrack(ctx context.Context, userID, playlistID int64, dzTrack domain.Track) (int, error) {
+	if dzTrack.DeezerID == 0 {
+		return 0, errors.New("deezer_id is required")
+	}
+
+	track, _, err := u.trackUC.EnsureTrackByDeezer(ctx, dzTrack)
+	if err != nil {
+		return 0, fmt.Errorf("usecase.AddTrackToPlaylist: %w", err)
+	}
+
+	return u.repo.AddTrack(ctx, userID, playlistID, track.ID)
+}
+
+func (u *PlaylistUsecase) RemoveTrack(ctx context.Context, userID, playlistID int64, position int) error {
+	return u.repo.RemoveTrack(ctx, userID, playlistID, position)
+}
+
+func (u *PlaylistUsecase) MoveTrack(ctx context.Context, userID, playlistID int64, from, to int) error {
+	return u.repo.MoveTrack(ctx, userID, playlistID, from, to)
+}
+
+func normalizePlaylistTitle(title string) (string, error) {
+	title = strings.TrimSpace(title)
+	if title == "" || len([]rune(title)) > maxPlaylistTitleLen {
+		return "", ErrInvalidPlaylistTitle
+	}
+	return title, nil
+}
@@ -0,0 +1,400 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"music-go-bot/internal/domain"
+	"slices"
+	"time"
+)
+
+var ErrInvalidPrefetchContext = errors.New("invalid prefetch context")
+
+// PrefetchConfig — сколько треков вперед готовить и сколько предзагрузок держать на пользователя
+type PrefetchConfig struct {
+	Ahead          int // Сколько следующих треков ставить в очередь
+	MaxOutstanding int // Сколько предзагруженных треков пользователя может обрабатываться одновременно
+}
+
+const (
+	defaultPrefetchAhead       = 3
+	defaultPrefetchOutstanding = 5
+	// Список ID из запроса дальше этого не читаем — как и коллекции Deezer
+	maxPrefetchTracks = maxCollectionTracks
+	// Сколько ждать, пока параллельная предзагрузка того же пользователя закончит обновление
+	prefetchLockTimeout = 10 * time.Second
+)
+
+func (c PrefetchConfig) withDefaults() PrefetchConfig {
+	if c.Ahead <= 0 {
+		c.Ahead = defaultPrefetchAhead
+	}
+	if c.MaxOutstanding <= 0 {
+		c.MaxOutstanding = defaultPrefetchOutstanding
+	}
+	return c
+}
+
+// PrefetchContext — что слушает пользователь: альбом, плейлист или список треков,
+// и на каком треке он сейчас (0 — с начала)
+type PrefetchContext struct {
+	Type      string  `json:"type"`
+	ID        int64   `json:"id,omitempty"`         // album / playlist / user_playlist
+	DeezerIDs []int64 `json:"deezer_ids,omitempty"` // tracks
+	Current   int64   `json:"current_deezer_id,omitempty"`
+}
+
+// Key — ключ контекста: смена ключа = пользователь переключился на другое
+func (p PrefetchContext) Key() string {
+	if p.Type == domain.PrefetchTracks {
+		h := fnv.New64a()
+		for _, id := range p.DeezerIDs {
+			fmt.Fprintf(h, "%d,", id)
+		}
+		return fmt.Sprintf("%s:%x", p.Type, h.Sum64())
+	}
+	return fmt.Sprintf("%s:%d", p.Type, p.ID)
+}
+
+func (p PrefetchContext) validate() error {
+	switch p.Type {
+	case domain.PrefetchAlbum, domain.PrefetchPlaylist, domain.PrefetchUserPlaylist:
+		if p.ID <= 0 {
+			return fmt.Errorf("%w: id is required for %s", ErrInvalidPrefetchContext, p.Type)
+		}
+	case domain.PrefetchTracks:
+		if len(p.DeezerIDs) == 0 {
+			return fmt.Errorf("%w: deezer_ids is empty", ErrInvalidPrefetchContext)
+		}
+		if len(p.DeezerIDs) > maxPrefetchTracks {
+			return fmt.Errorf("%w: too many deezer_ids", ErrInvalidPrefetchContext)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidPrefetchContext, p.Type)
+	}
+	return nil
+}
+
+// PrefetchResult — что сделала предзагрузка
+type PrefetchResult struct {
+	Context     string  `json:"context"`
+	Enqueued    []int64 `json:"enqueued"`    // Поставлены в очередь этим запросом
+	Outstanding int     `json:"outstanding"` // Предзагруженных треков пользователя сейчас в обработке
+	Cancelled   int     `json:"cancelled"`   // Сняты с очереди из-за смены контекста
+}
+
+// PrefetchQueue — что предзагрузке нужно от очереди
+type PrefetchQueue interface {
+	CancelBackground(ctx context.Context, deezerID int64) (bool, error)
+}
+
+// PrefetchUsecase заранее ставит в очередь (с низким приоритетом) следующие треки
+// альбома или плейлиста, чтобы при переходе на них пользователь не ждал всю цепочку.
+type PrefetchUsecase struct {
+	trackUC    *TrackUsecase
+	deezer     *SearchUsecaseDZ
+	playlistUC *PlaylistUsecase
+	trackRepo  domain.TrackRepository
+	waiters    domain.WaiterRepository
+	queue      PrefetchQueue
+	registry   domain.PrefetchRegistry
+	cfg        PrefetchConfig
+}
+
+func NewPrefetchUsecase(
+	trackUC *TrackUsecase,
+	deezer *SearchUsecaseDZ,
+	playlistUC *PlaylistUsecase,
+	trackRepo domain.TrackRepository,
+	waiters domain.WaiterRepository,
+	queue PrefetchQueue,
+	registry domain.PrefetchRegistry,
+	cfg PrefetchConfig,
+) *PrefetchUsecase {
+	return &PrefetchUsecase{
+		trackUC:    trackUC,
+		deezer:     deezer,
+		playlistUC: playlistUC,
+		trackRepo:  trackRepo,
+		waiters:    waiters,
+		queue:      queue,
+		registry:   registry,
+		cfg:        cfg.withDefaults(),
+	}
+}
+
+// Prefetch ставит в очередь cfg.Ahead треков после текущего. Если пользователь
+// переключился на другой контекст, еще не начатые предзагрузки старого снимаются.
+// Готовые, заблокированные и упавшие треки пропускаются — фоном их не перезапускаем.
+func (u *PrefetchUsecase) Prefetch(ctx context.Context, userID int64, pc PrefetchContext) (PrefetchResult, error) {
+	if err := pc.validate(); err != nil {
+		return PrefetchResult{}, err
+	}
+	key := pc.Key()
+	result := PrefetchResult{Context: key, Enqueued: []int64{}}
+
+	window, err := u.upcoming(ctx, userID, pc)
+	if err != nil {
+		return PrefetchResult{}, err
+	}
+
+	// Автопредзагрузка после play, явный POST /api/prefetch и соседние play одного
+	// пользователя идут параллельно: без блокировки последний Save затер бы чужое окно,
+	// и эти треки не отменялись бы и не считались в MaxOutstanding
+	lockCtx, cancel := context.WithTimeout(ctx, prefetchLockTimeout)
+	defer cancel()
+	unlock, err := u.registry.Lock(lockCtx, userID)
+	if err != nil {
+		return PrefetchResult{}, fmt.Errorf("registry.Lock: %w", err)
+	}
+	defer unlock()
+
+	prev, err := u.registry.Get(ctx, userID)
+	if err != nil {
+		return PrefetchResult{}, fmt.Errorf("registry.Get: %w", err)
+	}
+	state := domain.PrefetchState{Context: key}
+	if prev != nil && prev.Context == key {
+		state.DeezerIDs = prev.DeezerIDs
+	} else if prev != nil {
+		// Текущий трек и окно нового контекста не отменяем, даже если они были и в старом
+		keep := make(map[int64]bool, len(window)+1)
+		keep[pc.Current] = true
+		for _, t := range window {
+			keep[t.DeezerID] = true
+		}
+		result.Cancelled = u.cancel(ctx, userID, prev.DeezerIDs, keep)
+		for _, id := range prev.DeezerIDs {
+			if keep[id] {
+				state.DeezerIDs = append(state.DeezerIDs, id)
+			}
+		}
+	}
+
+	// Сколько предзагрузок пользователя еще в работе; закончившиеся забываем
+	state.DeezerIDs, err = u.outstanding(ctx, userID, state.DeezerIDs)
+	if err != nil {
+		return PrefetchResult{}, err
+	}
+
+	for _, dzTrack := range window {
+		if len(state.DeezerIDs) >= u.cfg.MaxOutstanding {
+			break
+		}
+		if slices.Contains(state.DeezerIDs, dzTrack.DeezerID) {
+			continue
+		}
+
+		started, err := u.start(ctx, dzTrack)
+		if err != nil {
+			slog.Warn("Prefetch: failed to enqueue track", "deezer_id", dzTrack.DeezerID, "user_id", userID, "error", err)
+			continue
+		}
+		if !started {
+			continue
+		}
+		if err := u.registry.AddOwner(ctx, dzTrack.DeezerID, userID); err != nil {
+			slog.Warn("Prefetch: failed to register owner", "deezer_id", dzTrack.DeezerID, "error", err)
+		}
+		state.DeezerIDs = append(state.DeezerIDs, dzTrack.DeezerID)
+		result.Enqueued = append(result.Enqueued, dzTrack.DeezerID)
+	}
+	result.Outstanding = len(state.DeezerIDs)
+
+	if err := u.registry.Save(ctx, userID, state); err != nil {
+		return PrefetchResult{}, fmt.Errorf("registry.Save: %w", err)
+	}
+
+	if len(result.Enqueued) > 0 || result.Cancelled > 0 {
+		slog.Info("Prefetch updated",
+			"user_id", userID,
+			"context", key,
+			"enqueued", len(result.Enqueued),
+			"cancelled", result.Cancelled,
+			"outstanding", result.Outstanding,
+		)
+	}
+	return result, nil
+}
+
+// upcoming — до cfg.Ahead треков контекста, идущих после текущего
+func (u *PrefetchUsecase) upcoming(ctx context.Context, userID int64, pc PrefetchContext) ([]domain.Track, error) {
+	var list []domain.Track
+	switch pc.Type {
+	case domain.PrefetchAlbum:
+		_, tracks, err := u.deezer.GetAlbumTracks(ctx, pc.ID)
+		if err != nil {
+			return nil, err
+		}
+		list = tracks
+
+	case domain.PrefetchPlaylist:
+		_, tracks, err := u.deezer.GetPlaylistTracks(ctx, pc.ID)
+		if err != nil {
+			return nil, err
+		}
+		list = tracks
+
+	case domain.PrefetchUserPlaylist:
+		playlist, err := u.playlistUC.Get(ctx, userID, pc.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range playlist.Tracks {
+			list = append(list, e.Track)
+		}
+
+	case domain.PrefetchTracks:
+		// Метаданные нужны только окну — их и догружаем ниже
+		for _, id := range pc.DeezerIDs {
+			list = append(list, domain.Track{DeezerID: id})
+		}
+	}
+
+	start := 0
+	if pc.Current != 0 {
+		if i := slices.IndexFunc(list, func(t domain.Track) bool { return t.DeezerID == pc.Current }); i >= 0 {
+			start = i + 1
+		}
+	}
+	window := list[start:min(start+u.cfg.Ahead, len(list))]
+
+	if pc.Type == domain.PrefetchTracks {
+		return u.withMetadata(ctx, window)
+	}
+	return window, nil
+}
+
+// withMetadata дополняет голые Deezer ID: из базы, а кого там нет — из Deezer
+func (u *PrefetchUsecase) withMetadata(ctx context.Context, window []domain.Track) ([]domain.Track, error) {
+	ids := make([]int64, len(window))
+	for i, t := range window {
+		ids[i] = t.DeezerID
+	}
+	known, err := u.trackUC.GetByDeezerIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]domain.Track, 0, len(window))
+	for _, id := range ids {
+		if t := known[id]; t != nil {
+			res = append(res, *t)
+			continue
+		}
+		t, err := u.deezer.GetTrack(ctx, id)
+		if err != nil || t == nil {
+			slog.Warn("Prefetch: failed to get track from Deezer", "deezer_id", id, "error", err)
+			continue
+		}
+		res = append(res, *t)
+	}
+	return res, nil
+}
+
+// start создает запись трека и запускает цепочку, если трек еще не обрабатывался.
+// true — задача поставлена (или стояла с прошлого запуска)
+func (u *PrefetchUsecase) start(ctx context.Context, dzTrack domain.Track) (bool, error) {
+	track, _, err := u.trackUC.EnsureTrackByDeezer(ctx, dzTrack)
+	if err != nil {
+		return false, err
+	}
+	if track.Status != domain.StatusIdle {
+		return false, nil
+	}
+
+	// userID 0: предзагрузка никого не уведомляет — пользователь просто откроет трек готовым
+	res, err := u.trackUC.GetPlaybackState(ctx, *track, 0, domain.PriorityLow)
+	if err != nil {
+		return false, err
+	}
+	return res.Status == domain.StatusQueued, nil
+}
+
+// outstanding оставляет из предзагрузок пользователя только те, что еще обрабатываются
+func (u *PrefetchUsecase) outstanding(ctx context.Context, userID int64, ids []int64) ([]int64, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	tracks, err := u.trackUC.GetByDeezerIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var live []int64
+	for _, id := range ids {
+		if t := tracks[id]; t != nil && domain.IsInProgress(t.Status) {
+			live = append(live, id)
+			continue
+		}
+		if _, err := u.registry.RemoveOwner(ctx, id, userID); err != nil {
+			slog.Warn("Prefetch: failed to unregister owner", "deezer_id", id, "error", err)
+		}
+	}
+	return live, nil
+}
+
+// cancel снимает с очереди предзагрузки старого контекста, которые еще не начались.
+// Трек оставляем в покое, если его предзагружает кто-то еще, его ждут (нажали play)
+// или задачу уже забрал высокий приоритет. Возвращает число снятых
+func (u *PrefetchUsecase) cancel(ctx context.Context, userID int64, ids []int64, keep map[int64]bool) int {
+	var cancelled int
+	for _, id := range ids {
+		if keep[id] {
+			continue
+		}
+		ok, err := u.cancelOne(ctx, userID, id)
+		if err != nil {
+			slog.Warn("Prefetch: failed to cancel track", "deezer_id", id, "user_id", userID, "error", err)
+			continue
+		}
+		if ok {
+			cancelled++
+		}
+	}
+	return cancelled
+}
+
+func (u *PrefetchUsecase) cancelOne(ctx context.Context, userID, deezerID int64) (bool, error) {
+	left, err := u.registry.RemoveOwner(ctx, deezerID, userID)
+	if err != nil {
+		return false, err
+	}
+	if left > 0 {
+		return false, nil
+	}
+
+	track, err := u.trackRepo.GetByDeezerID(ctx, deezerID)
+	if err != nil {
+		return false, err
+	}
+	if track == nil || track.Status != domain.StatusQueued {
+		return false, nil
+	}
+	waiting, err := u.waiters.List(ctx, track.ID)
+	if err != nil {
+		return false, err
+	}
+	if len(waiting) > 0 {
+		return false, nil
+	}
+
+	removed, err := u.queue.CancelBackground(ctx, deezerID)
+	if err != nil {
+		return false, err
+	}
+	if !removed {
+		return false, nil
+	}
+
+	// Задачи больше нет — возвращаем трек в idle, следующий play запустит цепочку заново.
+	// Если параллельный play успел увидеть queued, трек подберет реконсайлер
+	if err := u.trackRepo.Transition(ctx, deezerID, domain.StatusIdle); err != nil {
+		return false, fmt.Errorf("failed to reset cancelled track: %w", err)
+	}
+	publishEvent(ctx, u.trackUC.events, domain.TrackEvent{DeezerID: deezerID, TrackID: track.ID, Stage: domain.StageIdle})
+	return true, nil
+}
@@ -0,0 +1,238 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"music-go-bot/internal/domain"
+	"sync/atomic"
+	"time"
+)
+
+// ReconcilerConfig — сколько трек может не менять статус на каждом этапе,
+// прежде чем реконсайлер пойдет проверять, жива ли его задача
+type ReconcilerConfig struct {
+	Timeouts    map[string]time.Duration
+	MaxRequeues int // После стольких попыток (attempts) трек не перезапускаем, а помечаем failed
+	BatchSize   int // Сколько треков одного статуса разбирать за проход
+}
+
+const (
+	defaultMaxRequeues    = 3
+	defaultReconcileBatch = 100
+)
+
+var defaultStageTimeouts = map[string]time.Duration{
+	domain.StatusQueued:      10 * time.Minute,
+	domain.StatusSearching:   5 * time.Minute,
+	domain.StatusDownloading: 30 * time.Minute, // Прогресс скачивания updated_at не двигает
+	domain.StatusUploading:   15 * time.Minute,
+}
+
+func (c ReconcilerConfig) withDefaults() ReconcilerConfig {
+	timeouts := make(map[string]time.Duration, len(defaultStageTimeouts))
+	for status, d := range defaultStageTimeouts {
+		timeouts[status] = d
+		if custom := c.Timeouts[status]; custom > 0 {
+			timeouts[status] = custom
+		}
+	}
+	c.Timeouts = timeouts
+
+	if c.MaxRequeues <= 0 {
+		c.MaxRequeues = defaultMaxRequeues
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultReconcileBatch
+	}
+	return c
+}
+
+// ReconcileQueue — что реконсайлеру нужно от очереди
+type ReconcileQueue interface {
+	HasLiveTask(ctx context.Context, stage string, deezerID int64) (bool, error)
+	EnqueueSearch(ctx context.Context, deezerID int64, userID int64, priority string) error
+	EnqueueDownload(ctx context.Context, trackID int64, ytID string, userID int64, priority string) error
+	EnqueueNotify(ctx context.Context, trackID int64, userID int64) error
+}
+
+// ReconcileStats — счетчики действий реконсайлера
+type ReconcileStats struct {
+	Checked  int64 `json:"checked"`  // Застрявших треков найдено
+	Alive    int64 `json:"alive"`    // Задача в очереди жива, трек просто долго обрабатывается
+	Requeued int64 `json:"requeued"` // Задача потерялась — поставили этап заново
+	Failed   int64 `json:"failed"`   // Перезапускать нечего или попытки кончились — failed
+}
+
+// ReconcilerUsecase находит треки, застрявшие на этапе цепочки (Redis потерял задачу,
+// воркер убили посреди скачивания, постановка в очередь упала после смены статуса),
+// и либо перезапускает этап, либо переводит трек в failed.
+type ReconcilerUsecase struct {
+	repo   domain.TrackRepository
+	queue  ReconcileQueue
+	events domain.TrackEventPublisher
+	cfg    ReconcilerConfig
+
+	// Накопленные с запуска процесса счетчики
+	checked, alive, requeued, failed atomic.Int64
+}
+
+func NewReconcilerUsecase(
+	repo domain.TrackRepository,
+	queue ReconcileQueue,
+	events domain.TrackEventPublisher,
+	cfg ReconcilerConfig,
+) *ReconcilerUsecase {
+	return &ReconcilerUsecase{
+		repo:   repo,
+		queue:  queue,
+		events: events,
+		cfg:    cfg.withDefaults(),
+	}
+}
+
+// Stats — счетчики за все время работы процесса
+func (u *ReconcilerUsecase) Stats() ReconcileStats {
+	return ReconcileStats{
+		Checked:  u.checked.Load(),
+		Alive:    u.alive.Load(),
+		Requeued: u.requeued.Load(),
+		Failed:   u.failed.Load(),
+	}
+}
+
+// Run — один проход по всем этапам. Ошибка по отдельному треку не останавливает проход
+func (u *ReconcilerUsecase) Run(ctx context.Context) error {
+	var run ReconcileStats
+	now := time.Now()
+
+	for status, timeout := range u.cfg.Timeouts {
+		tracks, err := u.repo.GetStale(ctx, status, now.Add(-timeout), u.cfg.BatchSize)
+		if err != nil {
+			return fmt.Errorf("repo.GetStale(%s): %w", status, err)
+		}
+
+		for i := range tracks {
+			run.Checked++
+			action, err := u.reconcile(ctx, &tracks[i], timeout)
+			if err != nil {
+				slog.Error("Reconciler: failed to handle stuck track",
+					"deezer_id", tracks[i].DeezerID,
+					"status", status,
+					"error", err,
+				)
+				continue
+			}
+			switch action {
+			case reconcileAlive:
+				run.Alive++
+			case reconcileRequeued:
+				run.Requeued++
+			case reconcileFailed:
+				run.Failed++
+			}
+		}
+	}
+
+	u.checked.Add(run.Checked)
+	u.alive.Add(run.Alive)
+	u.requeued.Add(run.Requeued)
+	u.failed.Add(run.Failed)
+
+	if run.Checked > 0 {
+		total := u.Stats()
+		slog.Info("Reconciler pass finished",
+			"checked", run.Checked,
+			"alive", run.Alive,
+			"requeued", run.Requeued,
+			"failed", run.Failed,
+			"total_requeued", total.Requeued,
+			"total_failed", total.Failed,
+		)
+	}
+	return nil
+}
+
+const (
+	reconcileAlive    = "alive"
+	reconcileRequeued = "requeued"
+	reconcileFailed   = "failed"
+)
+
+func (u *ReconcilerUsecase) reconcile(ctx context.Context, track *domain.Track, timeout time.Duration) (string, error) {
+	// queued — задача еще не начала этап: это может быть и поиск, и скачивание
+	stages := []string{track.Status}
+	if track.Status == domain.StatusQueued {
+		stages = []string{domain.StatusSearching, domain.StatusDownloading}
+	}
+	for _, stage := range stages {
+		live, err := u.queue.HasLiveTask(ctx, stage, track.DeezerID)
+		if err != nil {
+			return "", fmt.Errorf("queue.HasLiveTask: %w", err)
+		}
+		if live {
+			slog.Debug("Reconciler: track is slow but its task is alive", "deezer_id", track.DeezerID, "status", track.Status)
+			return reconcileAlive, nil
+		}
+	}
+
+	if track.Attempts >= u.cfg.MaxRequeues {
+		return u.fail(ctx, track, fmt.Sprintf("stuck in %s, attempts exhausted", track.Status))
+	}
+
+	// Какой этап ставить заново. Путь к скачанному файлу нигде не хранится, поэтому
+	// застрявшую загрузку в хранилище перезапускаем со скачивания: кэш артефактов
+	// по YouTube ID отдаст файл без повторного похода в YouTube, а переход
+	// uploading -> downloading разрешен
+	var enqueue func() error
+	switch {
+	case track.Status == domain.StatusSearching || (track.Status == domain.StatusQueued && track.YoutubeID == ""):
+		enqueue = func() error { return u.queue.EnqueueSearch(ctx, track.DeezerID, 0, domain.PriorityLow) }
+	case (track.Status == domain.StatusQueued ||
+		track.Status == domain.StatusDownloading ||
+		track.Status == domain.StatusUploading) && track.YoutubeID != "":
+		enqueue = func() error {
+			return u.queue.EnqueueDownload(ctx, track.DeezerID, track.YoutubeID, 0, domain.PriorityLow)
+		}
+	default:
+		return u.fail(ctx, track, fmt.Sprintf("stuck in %s for over %s, task lost", track.Status, timeout))
+	}
+
+	// Ошибка заодно двигает updated_at — следующий проход не тронет трек, пока идет новая задача
+	reason := fmt.Sprintf("stuck in %s for over %s, task lost, requeued", track.Status, timeout)
+	if err := u.repo.RecordError(ctx, track.DeezerID, track.Status, reason); err != nil {
+		return "", err
+	}
+	// userID 0: ждущие остались в track_waiters, уведомление до них и так дойдет
+	if err := enqueue(); err != nil {
+		return "", fmt.Errorf("failed to requeue: %w", err)
+	}
+
+	slog.Warn("Reconciler: requeued stuck track",
+		"deezer_id", track.DeezerID,
+		"status", track.Status,
+		"stuck_since", track.UpdatedAt,
+	)
+	return reconcileRequeued, nil
+}
+
+func (u *ReconcilerUsecase) fail(ctx context.Context, track *domain.Track, reason string) (string, error) {
+	if err := u.repo.MarkFailed(ctx, track.DeezerID, track.Status, reason); err != nil {
+		return "", err
+	}
+
+	slog.Warn("Reconciler: marked stuck track as failed",
+		"deezer_id", track.DeezerID,
+		"status", track.Status,
+		"reason", reason,
+	)
+	publishEvent(ctx, u.events, domain.TrackEvent{
+		DeezerID: track.DeezerID,
+		Stage:    domain.StageError,
+		Error:    "processing failed",
+	})
+	if err := u.queue.EnqueueNotify(ctx, track.DeezerID, 0); err != nil {
+		slog.Error("Failed to enqueue notify task", "deezer_id", track.DeezerID, "error", err)
+	}
+	return reconcileFailed, nil
+}
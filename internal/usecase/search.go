@@ -0,0 +1,337 @@
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"music-go-bot/internal/domain"
+	"regexp"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	ErrInvalidSearchType   = errors.New("invalid search type")
+	ErrInvalidSearchCursor = errors.New("invalid search cursor")
+)
+
+// Что можно искать в /api/search. Порядок — порядок секций в ответе
+const (
+	SearchTypeTrack    = "track"
+	SearchTypeArtist   = "artist"
+	SearchTypeAlbum    = "album"
+	SearchTypePlaylist = "playlist"
+)
+
+var searchTypes = []string{SearchTypeTrack, SearchTypeArtist, SearchTypeAlbum, SearchTypePlaylist}
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50 // На каждый тип
+)
+
+// SearchQuery — запрос общего поиска
+type SearchQuery struct {
+	Query  string
+	Types  []string // Пусто — все типы
+	Limit  int      // На каждый тип; 0 — по умолчанию
+	Cursor string   // Из next_cursor прошлого ответа; задает и типы, и смещения
+	UserID int64    // 0 — аноним: без отметки liked
+}
+
+// TrackHit — трек из выдачи с его состоянием у нас
+type TrackHit struct {
+	domain.Track
+	Cached     bool `json:"cached"`     // Аудио уже лежит у нас — играет сразу
+	Liked      bool `json:"liked"`      // В библиотеке того, кто ищет
+	Processing bool `json:"processing"` // Цепочка скачивания уже идет
+}
+
+// SearchSection — страница результатов одного типа. NextCursor продолжает только этот тип
+type SearchSection[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// SearchResult — секции запрошенных типов (незапрошенных в ответе нет).
+// NextCursor — следующая страница сразу всех типов, где еще что-то осталось
+type SearchResult struct {
+	Tracks     *SearchSection[TrackHit]               `json:"tracks,omitempty"`
+	Artists    *SearchSection[domain.Artist]          `json:"artists,omitempty"`
+	Albums     *SearchSection[domain.Album]           `json:"albums,omitempty"`
+	Playlists  *SearchSection[domain.CatalogPlaylist] `json:"playlists,omitempty"`
+	NextCursor string                                 `json:"next_cursor,omitempty"`
+}
+
+// SearchUsecase — общий поиск по каталогу Deezer: все типы одним запросом
+type SearchUsecase struct {
+	deezer *SearchUsecaseDZ
+	tracks domain.TrackRepository
+}
+
+func NewSearchUsecase(deezer *SearchUsecaseDZ, tracks domain.TrackRepository) *SearchUsecase {
+	return &SearchUsecase{
+		deezer: deezer,
+		tracks: tracks,
+	}
+}
+
+// Search ищет все запрошенные типы параллельно. Пустой запрос — пустой результат
+func (u *SearchUsecase) Search(ctx context.Context, q SearchQuery) (*SearchResult, error) {
+	offsets, err := searchOffsets(q.Types, q.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &SearchResult{}
+	query := normalizeDeezerQuery(q.Query)
+	if query == "" {
+		return res, nil
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	limit = min(limit, maxSearchLimit)
+
+	var tracks []domain.Track
+	g, gctx := errgroup.WithContext(ctx)
+	if index, ok := offsets[SearchTypeTrack]; ok {
+		g.Go(func() error {
+			found, total, err := u.deezer.SearchDeezerPage(gctx, query, index, limit)
+			if err != nil {
+				return err
+			}
+			tracks = found
+			res.Tracks = &SearchSection[TrackHit]{Total: total}
+			return nil
+		})
+	}
+	if index, ok := offsets[SearchTypeArtist]; ok {
+		g.Go(func() error {
+			found, total, err := u.deezer.SearchArtistsPage(gctx, query, index, limit)
+			if err != nil {
+				return err
+			}
+			res.Artists = &SearchSection[domain.Artist]{Items: found, Total: total}
+			return nil
+		})
+	}
+	if index, ok := offsets[SearchTypeAlbum]; ok {
+		g.Go(func() error {
+			found, total, err := u.deezer.SearchAlbumsPage(gctx, query, index, limit)
+			if err != nil {
+				return err
+			}
+			res.Albums = &SearchSection[domain.Album]{Items: found, Total: total}
+			return nil
+		})
+	}
+	if index, ok := offsets[SearchTypePlaylist]; ok {
+		g.Go(func() error {
+			found, total, err := u.deezer.SearchPlaylistsPage(gctx, query, index, limit)
+			if err != nil {
+				return err
+			}
+			res.Playlists = &SearchSection[domain.CatalogPlaylist]{Items: found, Total: total}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	if res.Tracks != nil {
+		if res.Tracks.Items, err = u.trackHits(ctx, tracks, q.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Курсоры: смещение следующей страницы для каждого типа, где она есть
+	next := make(map[string]int, len(offsets))
+	setNext := func(kind string, count, total int, cursor *string) {
+		if index := offsets[kind] + count; count > 0 && index < total {
+			next[kind] = index
+			*cursor = encodeSearchCursor(map[string]int{kind: index})
+		}
+	}
+	if s := res.Tracks; s != nil {
+		setNext(SearchTypeTrack, len(s.Items), s.Total, &s.NextCursor)
+	}
+	if s := res.Artists; s != nil {
+		setNext(SearchTypeArtist, len(s.Items), s.Total, &s.NextCursor)
+	}
+	if s := res.Albums; s != nil {
+		setNext(SearchTypeAlbum, len(s.Items), s.Total, &s.NextCursor)
+	}
+	if s := res.Playlists; s != nil {
+		setNext(SearchTypePlaylist, len(s.Items), s.Total, &s.NextCursor)
+	}
+	if len(next) > 0 {
+		res.NextCursor = encodeSearchCursor(next)
+	}
+
+	return res, nil
+}
+
+// trackHits размечает найденные треки локальным состоянием: есть ли аудио, идет ли
+// скачивание, лайкнул ли трек тот, кто ищет
+func (u *SearchUsecase) trackHits(ctx context.Context, tracks []domain.Track, userID int64) ([]TrackHit, error) {
+	ids := make([]int64, len(tracks))
+	for i, t := range tracks {
+		ids[i] = t.DeezerID
+	}
+
+	local, err := u.tracks.GetByDeezerIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("repo.GetByDeezerIDs: %w", err)
+	}
+
+	liked := map[int64]bool{}
+	if userID != 0 && len(local) > 0 {
+		trackIDs := make([]int64, 0, len(local))
+		for _, t := range local {
+			trackIDs = append(trackIDs, t.ID)
+		}
+		if liked, err = u.tracks.GetLikedIDs(ctx, userID, trackIDs); err != nil {
+			return nil, fmt.Errorf("repo.GetLikedIDs: %w", err)
+		}
+	}
+
+	hits := make([]TrackHit, len(tracks))
+	for i, t := range tracks {
+		hits[i] = TrackHit{Track: t}
+		lt, ok := local[t.DeezerID]
+		if !ok {
+			continue
+		}
+		hits[i].ID = lt.ID
+		hits[i].Status = lt.Status
+		hits[i].Cached = lt.Status == domain.StatusReady
+		hits[i].Processing = domain.IsInProgress(lt.Status)
+		hits[i].Liked = liked[lt.ID]
+	}
+	return hits, nil
+}
+
+// searchOffsets — с какого места искать каждый тип. Курсор задает и типы, и смещения;
+// без курсора — первая страница запрошенных типов
+func searchOffsets(types []string, cursor string) (map[string]int, error) {
+	if cursor != "" {
+		offsets, err := decodeSearchCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		return offsets, nil
+	}
+
+	if len(types) == 0 {
+		types = searchTypes
+	}
+	offsets := make(map[string]int, len(types))
+	for _, t := range types {
+		if !isSearchType(t) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSearchType, t)
+		}
+		offsets[t] = 0
+	}
+	return offsets, nil
+}
+
+func isSearchType(t string) bool {
+	for _, st := range searchTypes {
+		if t == st {
+			return true
+		}
+	}
+	return false
+}
+
+// Курсор — смещения по типам в JSON, закодированные в base64url. Для клиента непрозрачен
+
+func encodeSearchCursor(offsets map[string]int) string {
+	raw, _ := json.Marshal(offsets)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeSearchCursor(cursor string) (map[string]int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidSearchCursor
+	}
+	var offsets map[string]int
+	if err := json.Unmarshal(raw, &offsets); err != nil || len(offsets) == 0 {
+		return nil, ErrInvalidSearchCursor
+	}
+	for t, index := range offsets {
+		if !isSearchType(t) || index < 0 {
+			return nil, ErrInvalidSearchCursor
+		}
+	}
+	return offsets, nil
+}
+
+// Фильтры расширенного поиска Deezer: artist:"…" album:"…" dur_min:120 и т.п.
+var (
+	deezerFilterRe = regexp.MustCompile(`(?i)\b(artist|album|track|label|dur_min|dur_max|bpm_min|bpm_max):`)
+	// Кавычки, которые подставляют клавиатуры телефонов, — Deezer понимает только прямые
+	smartQuotes = strings.NewReplacer("“", `"`, "”", `"`, "„", `"`, "«", `"`, "»", `"`)
+)
+
+// normalizeDeezerQuery приводит расширенный синтаксис к виду, который принимает Deezer:
+// прямые кавычки, ключи в нижнем регистре, текстовые значения в кавычках
+// (artist:кино track:группа крови -> artist:"кино" track:"группа крови").
+// Запрос без фильтров возвращается как есть
+func normalizeDeezerQuery(q string) string {
+	q = strings.TrimSpace(smartQuotes.Replace(q))
+	matches := deezerFilterRe.FindAllStringSubmatchIndex(q, -1)
+	if len(matches) == 0 {
+		return q
+	}
+
+	var parts []string
+	if free := strings.TrimSpace(q[:matches[0][0]]); free != "" {
+		parts = append(parts, free)
+	}
+	for i, m := range matches {
+		key := strings.ToLower(q[m[2]:m[3]])
+		end := len(q)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		value := strings.TrimSpace(q[m[1]:end])
+
+		var rest string
+		switch {
+		case strings.HasPrefix(value, `"`):
+			// Уже в кавычках: значение до закрывающей, дальше — обычный текст
+			if j := strings.Index(value[1:], `"`); j >= 0 {
+				value, rest = value[1:j+1], value[j+2:]
+			} else {
+				value = value[1:]
+			}
+		case strings.HasPrefix(key, "dur_") || strings.HasPrefix(key, "bpm_"):
+			// Числовой фильтр — одно слово
+			if fields := strings.SplitN(value, " ", 2); len(fields) == 2 {
+				value, rest = fields[0], fields[1]
+			}
+		}
+
+		if value = strings.TrimSpace(value); value != "" {
+			if strings.HasPrefix(key, "dur_") || strings.HasPrefix(key, "bpm_") {
+				parts = append(parts, key+":"+value)
+			} else {
+				parts = append(parts, key+`:"`+value+`"`)
+			}
+		}
+		if rest = strings.TrimSpace(rest); rest != "" {
+			parts = append(parts, rest)
+		}
+	}
+	return strings.Join(parts, " ")
+}
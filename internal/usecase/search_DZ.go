@@ -2,186 +2,106 @@ package usecase
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
 	"music-go-bot/internal/domain"
-	"net/http"
-	"net/url"
-	"time"
+	"music-go-bot/internal/infrastructure/deezer"
 )
 
-// Структура для поиска треков (уже была)
-type DeezerSearchResponse struct {
-	Data []struct {
-		ID       int64  `json:"id"`
-		Title    string `json:"title"`
-		Duration int    `json:"duration"`
-		Artist   struct {
-			Name string `json:"name"`
-		} `json:"artist"`
-		Album struct {
-			CoverMedium string `json:"cover_medium"`
-		} `json:"album"`
-	} `json:"data"`
-}
-
-// --- НОВОЕ: Структура для поиска артистов ---
-type DeezerArtistResponse struct {
-	Data []struct {
-		ID            int64  `json:"id"`
-		Name          string `json:"name"`
-		PictureMedium string `json:"picture_medium"`
-		NbAlbum       int    `json:"nb_album"`
-	} `json:"data"`
-}
-
+// SearchUsecaseDZ — каталог Deezer для остальных usecase. Сам HTTP, квота, ретраи
+// и кэш — в клиенте deezer.API
 type SearchUsecaseDZ struct {
-	client *http.Client
+	client deezer.API
 }
 
-func NewSearchUsecaseDZ() *SearchUsecaseDZ {
-	return &SearchUsecaseDZ{
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-	}
+func NewSearchUsecaseDZ(client deezer.API) *SearchUsecaseDZ {
+	return &SearchUsecaseDZ{client: client}
 }
 
 // SearchDeezer — Поиск треков
 func (s *SearchUsecaseDZ) SearchDeezer(ctx context.Context, query string) ([]domain.Track, error) {
-	u, _ := url.Parse("https://api.deezer.com/search/track")
-	q := u.Query()
-	q.Set("q", query)
-	u.RawQuery = q.Encode()
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var dzResp DeezerSearchResponse
-	if err := json.NewDecoder(resp.Body).Decode(&dzResp); err != nil {
-		return nil, err
-	}
+	tracks, _, err := s.SearchDeezerPage(ctx, query, 0, 0)
+	return tracks, err
+}
 
-	tracks := make([]domain.Track, 0, len(dzResp.Data))
-	for _, d := range dzResp.Data {
-		tracks = append(tracks, domain.Track{
-			DeezerID: d.ID,
-			Title:    d.Title,
-			Artist:   d.Artist.Name,
-			Duration: d.Duration,
-			CoverURL: d.Album.CoverMedium,
-		})
-	}
-	return tracks, nil
+// SearchDeezerPage — поиск треков со смещением index; limit 0 — размер страницы Deezer по умолчанию.
+// Возвращает еще и общее число найденных треков, чтобы было понятно, есть ли следующая страница.
+func (s *SearchUsecaseDZ) SearchDeezerPage(ctx context.Context, query string, index, limit int) ([]domain.Track, int, error) {
+	return s.client.SearchTracks(ctx, query, index, limit)
 }
 
-// --- ИСПРАВЛЕНО: Поиск артистов ---
-func (s *SearchUsecaseDZ) SearchArtists(ctx context.Context, query string) ([]interface{}, error) {
-	// 1. Формируем URL для поиска артистов
-	u, err := url.Parse("https://api.deezer.com/search/artist")
-	if err != nil {
-		return nil, err
-	}
+// GetTrack — метаданные одного трека по Deezer ID
+func (s *SearchUsecaseDZ) GetTrack(ctx context.Context, deezerID int64) (*domain.Track, error) {
+	return s.client.Track(ctx, deezerID)
+}
 
-	q := u.Query()
-	q.Set("q", query)
-	u.RawQuery = q.Encode()
+// SearchArtists — поиск артистов
+func (s *SearchUsecaseDZ) SearchArtists(ctx context.Context, query string) ([]domain.Artist, error) {
+	artists, _, err := s.SearchArtistsPage(ctx, query, 0, 0)
+	return artists, err
+}
 
-	// 2. Выполняем запрос
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
+// SearchArtistsPage — страница поиска артистов и общее число найденных (как SearchDeezerPage)
+func (s *SearchUsecaseDZ) SearchArtistsPage(ctx context.Context, query string, index, limit int) ([]domain.Artist, int, error) {
+	return s.client.SearchArtists(ctx, query, index, limit)
+}
 
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("deezer artist api call failed: %w", err)
-	}
-	defer resp.Body.Close()
+// SearchAlbums — поиск альбомов
+func (s *SearchUsecaseDZ) SearchAlbums(ctx context.Context, query string) ([]domain.Album, error) {
+	albums, _, err := s.SearchAlbumsPage(ctx, query, 0, 0)
+	return albums, err
+}
 
-	// 3. Декодируем
-	var dzResp DeezerArtistResponse
-	if err := json.NewDecoder(resp.Body).Decode(&dzResp); err != nil {
-		return nil, fmt.Errorf("failed to decode artist response: %w", err)
-	}
+// SearchAlbumsPage — страница поиска альбомов и общее число найденных
+func (s *SearchUsecaseDZ) SearchAlbumsPage(ctx context.Context, query string, index, limit int) ([]domain.Album, int, error) {
+	return s.client.SearchAlbums(ctx, query, index, limit)
+}
 
-	// 4. Мапим в слайс интерфейсов (чтобы Handler легко проглотил JSON)
-	// Либо можно создать доменную модель Artist, если она нужна в базе
-	result := make([]interface{}, 0, len(dzResp.Data))
-	for _, a := range dzResp.Data {
-		result = append(result, map[string]interface{}{
-			"id":             a.ID,
-			"name":           a.Name,
-			"picture_medium": a.PictureMedium,
-			"nb_album":       a.NbAlbum,
-		})
-	}
+// SearchPlaylistsPage — страница поиска публичных плейлистов и общее число найденных
+func (s *SearchUsecaseDZ) SearchPlaylistsPage(ctx context.Context, query string, index, limit int) ([]domain.CatalogPlaylist, int, error) {
+	return s.client.SearchPlaylists(ctx, query, index, limit)
+}
 
-	return result, nil
+// GetArtist — карточка артиста
+func (s *SearchUsecaseDZ) GetArtist(ctx context.Context, artistID int64) (*domain.Artist, error) {
+	return s.client.Artist(ctx, artistID)
 }
 
-// --- НОВОЕ: Структура для поиска альбомов ---
-type DeezerAlbumResponse struct {
-	Data []struct {
-		ID          int64  `json:"id"`
-		Title       string `json:"title"`
-		CoverMedium string `json:"cover_medium"`
-		Artist      struct {
-			Name string `json:"name"`
-		} `json:"artist"`
-	} `json:"data"`
+// GetArtistTopTracks — самые популярные треки артиста
+func (s *SearchUsecaseDZ) GetArtistTopTracks(ctx context.Context, artistID int64, limit int) ([]domain.Track, error) {
+	return s.client.ArtistTop(ctx, artistID, limit)
 }
 
-// ... существующие методы ...
+// GetArtistAlbums — альбомы артиста
+func (s *SearchUsecaseDZ) GetArtistAlbums(ctx context.Context, artistID int64, limit int) ([]domain.Album, error) {
+	return s.client.ArtistAlbums(ctx, artistID, limit)
+}
 
-// SearchAlbums — Поиск альбомов через Deezer API
-func (s *SearchUsecaseDZ) SearchAlbums(ctx context.Context, query string) ([]interface{}, error) {
-	// 1. Подготовка URL
-	u, err := url.Parse("https://api.deezer.com/search/album")
-	if err != nil {
-		return nil, err
-	}
+// GetRelatedArtists — похожие артисты
+func (s *SearchUsecaseDZ) GetRelatedArtists(ctx context.Context, artistID int64, limit int) ([]domain.Artist, error) {
+	return s.client.ArtistRelated(ctx, artistID, limit)
+}
 
-	q := u.Query()
-	q.Set("q", query)
-	u.RawQuery = q.Encode()
+// GetAlbum — альбом и его полный треклист по порядку (диск, номер на диске)
+func (s *SearchUsecaseDZ) GetAlbum(ctx context.Context, albumID int64) (*domain.Album, []domain.Track, error) {
+	return s.client.Album(ctx, albumID)
+}
 
-	// 2. Выполнение запроса
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create album request: %w", err)
-	}
+// Сколько треков альбома/плейлиста забираем максимум — защита от плейлистов на тысячи треков
+const maxCollectionTracks = 100
 
-	resp, err := s.client.Do(req)
+// GetAlbumTracks — название альбома и его треки по порядку (до maxCollectionTracks)
+func (s *SearchUsecaseDZ) GetAlbumTracks(ctx context.Context, albumID int64) (string, []domain.Track, error) {
+	album, tracks, err := s.GetAlbum(ctx, albumID)
 	if err != nil {
-		return nil, fmt.Errorf("deezer album api call failed: %w", err)
+		return "", nil, err
 	}
-	defer resp.Body.Close()
-
-	// 3. Декодирование
-	var dzResp DeezerAlbumResponse
-	if err := json.NewDecoder(resp.Body).Decode(&dzResp); err != nil {
-		return nil, fmt.Errorf("failed to decode album response: %w", err)
+	if len(tracks) > maxCollectionTracks {
+		tracks = tracks[:maxCollectionTracks]
 	}
 
-	// 4. Мапинг в слайс (ID, название, обложка, имя артиста)
-	result := make([]interface{}, 0, len(dzResp.Data))
-	for _, a := range dzResp.Data {
-		result = append(result, map[string]interface{}{
-			"id":           a.ID,
-			"title":        a.Title,
-			"cover_medium": a.CoverMedium,
-			"artist_name":  a.Artist.Name,
-		})
-	}
+	return album.ArtistName + " — " + album.Title, tracks, nil
+}
 
-	return result, nil
+// GetPlaylistTracks — название плейлиста и его треки по порядку (до maxCollectionTracks)
+func (s *SearchUsecaseDZ) GetPlaylistTracks(ctx context.Context, playlistID int64) (string, []domain.Track, error) {
+	return s.client.Playlist(ctx, playlistID, maxCollectionTracks)
 }
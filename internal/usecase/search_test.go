@@ -0,0 +1,137 @@
+package usecase
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeDeezerQuery(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"   ", ""},
+		{"группа крови", "группа крови"},
+		{"  eminem  ", "eminem"},
+		{"artist:кино track:группа крови", `artist:"кино" track:"группа крови"`},
+		{`artist:"Кино" track:"Группа крови"`, `artist:"Кино" track:"Группа крови"`},
+		{"ARTIST:Queen Album:Jazz", `artist:"Queen" album:"Jazz"`},
+
+		// Кавычки с телефонных клавиатур
+		{"artist:«Кино» track:“Звезда по имени Солнце”", `artist:"Кино" track:"Звезда по имени Солнце"`},
+		{"track:„Kukushka“", `track:"Kukushka"`},
+
+		// Незакрытая кавычка — значение до конца фильтра
+		{`track:"unterminated`, `track:"unterminated"`},
+		{`artist:"Кино track:Кукушка`, `artist:"Кино" track:"Кукушка"`},
+
+		// Числовые фильтры без кавычек, одно слово
+		{"dur_min:120", "dur_min:120"},
+		{"artist:daft punk dur_max:300 BPM_MIN:120", `artist:"daft punk" dur_max:300 bpm_min:120`},
+		{"bpm_max:140 live", "bpm_max:140 live"},
+
+		// Свободный текст до и после фильтров
+		{"любовь artist:кино", `любовь artist:"кино"`},
+		{`artist:"Кино" любовь dur_min:120 live`, `artist:"Кино" любовь dur_min:120 live`},
+		{"Artist:«Кино» любовь dur_min:120 live", `artist:"Кино" любовь dur_min:120 live`},
+
+		// Пустые значения выбрасываются
+		{"artist: track:x", `track:"x"`},
+		{`artist:""`, ""},
+		{"dur_min:", ""},
+
+		// Похожее на фильтр внутри слова — не фильтр
+		{"superartist:x", "superartist:x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := normalizeDeezerQuery(tt.in); got != tt.want {
+				t.Errorf("normalizeDeezerQuery(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchCursorRoundTrip(t *testing.T) {
+	tests := []map[string]int{
+		{SearchTypeTrack: 20},
+		{SearchTypeArtist: 0, SearchTypeAlbum: 40},
+		{SearchTypeTrack: 20, SearchTypeArtist: 20, SearchTypeAlbum: 20, SearchTypePlaylist: 980},
+	}
+
+	for _, offsets := range tests {
+		cursor := encodeSearchCursor(offsets)
+		got, err := decodeSearchCursor(cursor)
+		if err != nil {
+			t.Fatalf("decodeSearchCursor(%q): %v", cursor, err)
+		}
+		if !reflect.DeepEqual(got, offsets) {
+			t.Errorf("round trip = %v, want %v", got, offsets)
+		}
+	}
+}
+
+func TestDecodeSearchCursorRejectsInvalid(t *testing.T) {
+	enc := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	valid := encodeSearchCursor(map[string]int{SearchTypeTrack: 20})
+
+	tests := []struct {
+		name, cursor string
+	}{
+		{"not base64", "zzz"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte(`{"track":2}`))},
+		{"tampered", valid[:len(valid)-2] + "!!"},
+		{"truncated", valid[:len(valid)/2]},
+		{"not json", enc("track=20")},
+		{"json array", enc(`[20]`)},
+		{"empty object", enc(`{}`)},
+		{"null", enc(`null`)},
+		{"negative offset", enc(`{"track":-20}`)},
+		{"fractional offset", enc(`{"track":1.5}`)},
+		{"string offset", enc(`{"track":"20"}`)},
+		{"unknown type", enc(`{"user":0}`)},
+		{"one unknown among valid", enc(`{"track":20,"episode":0}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := decodeSearchCursor(tt.cursor); !errors.Is(err, ErrInvalidSearchCursor) {
+				t.Errorf("decodeSearchCursor(%q) = %v, %v; want ErrInvalidSearchCursor", tt.cursor, got, err)
+			}
+		})
+	}
+}
+
+func TestSearchOffsets(t *testing.T) {
+	all := map[string]int{SearchTypeTrack: 0, SearchTypeArtist: 0, SearchTypeAlbum: 0, SearchTypePlaylist: 0}
+
+	tests := []struct {
+		name    string
+		types   []string
+		cursor  string
+		want    map[string]int
+		wantErr error
+	}{
+		{"all types by default", nil, "", all, nil},
+		{"requested types", []string{SearchTypeTrack, SearchTypeAlbum}, "", map[string]int{SearchTypeTrack: 0, SearchTypeAlbum: 0}, nil},
+		{"unknown type", []string{SearchTypeTrack, "podcast"}, "", nil, ErrInvalidSearchType},
+		// Курсор задает типы сам — types игнорируются
+		{"cursor wins over types", []string{SearchTypeArtist}, encodeSearchCursor(map[string]int{SearchTypeTrack: 40}), map[string]int{SearchTypeTrack: 40}, nil},
+		{"bad cursor", nil, "zzz", nil, ErrInvalidSearchCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := searchOffsets(tt.types, tt.cursor)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("offsets = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
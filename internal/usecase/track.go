@@ -2,69 +2,102 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"music-go-bot/internal/domain"
 	"music-go-bot/internal/infrastructure/queue"
-
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 type TrackUsecase struct {
-	userRepo  domain.UserRepository
-	trackRepo domain.TrackRepository
-	queue     queue.TrackQueue // Наш новый интерфейс очереди
-	bot       *tgbotapi.BotAPI
+	userRepo      domain.UserRepository
+	trackRepo     domain.TrackRepository
+	candidateRepo domain.CandidateRepository
+	waiters       domain.WaiterRepository
+	queue         queue.TrackQueue // Наш новый интерфейс очереди
+	storages      domain.AudioStorages
+	events        domain.TrackEventPublisher
+	batches       domain.BatchRepository  // Для отмены: трек может быть нужен чужому пакету
+	prefetch      domain.PrefetchRegistry // Для отмены: трек может предзагружать другой пользователь
 }
 
 // Обновляем конструктор
 func NewTrackUsecase(
 	ur domain.UserRepository,
 	tr domain.TrackRepository,
+	cr domain.CandidateRepository,
+	wr domain.WaiterRepository,
 	q queue.TrackQueue, // Принимаем интерфейс
-	bt *tgbotapi.BotAPI,
+	storages domain.AudioStorages,
+	events domain.TrackEventPublisher,
+	batches domain.BatchRepository,
+	prefetch domain.PrefetchRegistry,
 ) *TrackUsecase {
 	return &TrackUsecase{
-		userRepo:  ur,
-		trackRepo: tr,
-		queue:     q,
-		bot:       bt,
+		userRepo:      ur,
+		trackRepo:     tr,
+		candidateRepo: cr,
+		waiters:       wr,
+		queue:         q,
+		storages:      storages,
+		events:        events,
+		batches:       batches,
+		prefetch:      prefetch,
 	}
 }
 
-// ГЛАВНЫЙ МЕТОД: Логика принятия решения по проигрыванию
-func (u *TrackUsecase) GetPlaybackState(ctx context.Context, dzTrack domain.Track) (domain.PlaybackResult, error) {
+// ГЛАВНЫЙ МЕТОД: Логика принятия решения по проигрыванию.
+// userID != 0 — пользователь получит трек в Telegram, когда цепочка закончится.
+// priority — domain.PriorityHigh, если пользователь ждет трек прямо сейчас
+func (u *TrackUsecase) GetPlaybackState(ctx context.Context, dzTrack domain.Track, userID int64, priority string) (domain.PlaybackResult, error) {
 	// 1. Проверяем наличие трека в БД или создаем запись
-	track, found, err := u.EnsureTrackByDeezer(ctx, dzTrack)
+	track, _, err := u.EnsureTrackByDeezer(ctx, dzTrack)
 	if err != nil {
 		return domain.PlaybackResult{}, fmt.Errorf("ensure track failed: %w", err)
 	}
 
-	// 2. Если трек готов (есть FileID) — пытаемся получить прямую ссылку
-	if track.FileID != "" {
-		link, err := u.GetTelegramFileLink(ctx, track.FileID)
+	// Встаем в ожидание до проверки статуса: если цепочка закончится между проверкой
+	// и записью, уведомление уже не придет. Лишнее ожидание снимаем ниже
+	if userID != 0 {
+		if err := u.waiters.Add(ctx, track.ID, userID); err != nil {
+			// Без уведомления трек все равно можно слушать — не валим запрос
+			slog.Warn("Failed to add track waiter", "track_id", track.ID, "user_id", userID, "error", err)
+		}
+	}
+
+	// 2. Если трек готов (аудио лежит в хранилище) — проверяем, что файл еще доступен.
+	// Саму ссылку хранилища наружу не отдаем: клиент слушает через прокси по TrackID
+	if _, _, ok := track.Locate(); ok {
+		_, err := u.ResolveAudioSource(ctx, track)
 		if err == nil {
+			u.unwait(ctx, track.ID, userID)
 			return domain.PlaybackResult{
-				Status:   domain.StatusReady,
-				PlayLink: link,
+				Status:  domain.StatusReady,
+				TrackID: track.ID,
 			}, nil
 		}
-		slog.Warn("Telegram link expired or invalid", "deezer_id", track.DeezerID)
-		// Если ссылка протухла, идем дальше к перекачиванию
+		slog.Warn("Stored audio is unavailable", "deezer_id", track.DeezerID, "error", err)
+		// Если файл пропал, идем дальше к перекачиванию
 	}
 
-	// 3. Если статус уже "processing", просто возвращаем статус ожидания
-	// Это предотвращает дублирование задач в очереди при частом нажатии кнопки
-	if track.Status == domain.StatusProcessing && found {
-		return domain.PlaybackResult{Status: domain.StatusProcessing}, nil
+	// 3. Если цепочка уже идет, трек заблокирован или ждет ручного выбора видео —
+	// просто возвращаем состояние (повторный поиск дал бы тех же сомнительных кандидатов)
+	if domain.IsInProgress(track.Status) || track.Status == domain.StatusBlocked || track.Status == domain.StatusLowConfidence {
+		if !domain.IsInProgress(track.Status) {
+			u.unwait(ctx, track.ID, userID)
+		}
+		return domain.PlaybackResult{Status: track.Status, TrackID: track.ID}, nil
 	}
 
 	// 4. ПОДГОТОВКА К ЗАПУСКУ ЦЕПОЧКИ
 
-	// Сначала фиксируем в базе, что мы начали работу
-	track.Status = domain.StatusProcessing
-	if err := u.Save(ctx, track); err != nil {
-		return domain.PlaybackResult{}, fmt.Errorf("failed to set processing status: %w", err)
+	// Атомарно переводим трек в queued. Если параллельный запрос успел раньше —
+	// получаем ErrInvalidTransition и не ставим дубль задачи в очередь
+	if err := u.trackRepo.Transition(ctx, track.DeezerID, domain.StatusQueued); err != nil {
+		if errors.Is(err, domain.ErrInvalidTransition) {
+			return u.currentState(ctx, track)
+		}
+		return domain.PlaybackResult{}, fmt.Errorf("failed to set queued status: %w", err)
 	}
 
 	// ПРИНЯТИЕ РЕШЕНИЯ: С чего начинать цепочку?
@@ -72,39 +105,70 @@ func (u *TrackUsecase) GetPlaybackState(ctx context.Context, dzTrack domain.Trac
 	if track.YoutubeID == "" {
 		// ШАГ А: YouTube ID неизвестен — отправляем на ПОИСК
 		slog.Info("Starting workflow from SEARCH", "deezer_id", track.DeezerID)
-		err = u.queue.EnqueueSearch(ctx, track.DeezerID)
+		err = u.queue.EnqueueSearch(ctx, track.DeezerID, userID, priority)
 	} else {
 		// ШАГ Б: YouTube ID уже есть — отправляем сразу на СКАЧИВАНИЕ
 		slog.Info("Starting workflow from DOWNLOAD", "deezer_id", track.DeezerID, "yt_id", track.YoutubeID)
-		err = u.queue.EnqueueDownload(ctx, track.DeezerID, track.YoutubeID)
+		err = u.queue.EnqueueDownload(ctx, track.DeezerID, track.YoutubeID, userID, priority)
 	}
 
 	if err != nil {
-		// Если не удалось положить в очередь, откатываем статус в базе (опционально)
-		track.Status = domain.StatusError
-		u.Save(ctx, track)
+		// Если не удалось положить в очередь, фиксируем сбой в базе
+		u.failQueued(ctx, track.DeezerID, err)
 		return domain.PlaybackResult{}, fmt.Errorf("failed to enqueue task: %w", err)
 	}
 
 	// Возвращаем пользователю, что процесс пошел
-	return domain.PlaybackResult{Status: domain.StatusProcessing}, nil
+	return domain.PlaybackResult{Status: domain.StatusQueued, TrackID: track.ID}, nil
 }
 
-// Вспомогательный метод для получения ссылки из TG
-func (u *TrackUsecase) GetTelegramFileLink(ctx context.Context, fileID string) (string, error) {
-	file, err := u.bot.GetFile(tgbotapi.FileConfig{FileID: fileID})
+// unwait снимает ожидание, если цепочку запускать не пришлось
+func (u *TrackUsecase) unwait(ctx context.Context, trackID, userID int64) {
+	if userID == 0 {
+		return
+	}
+	if err := u.waiters.Remove(ctx, trackID, userID); err != nil {
+		slog.Warn("Failed to remove track waiter", "track_id", trackID, "user_id", userID, "error", err)
+	}
+}
+
+// currentState перечитывает трек, когда его статус поменяли параллельно с нами
+func (u *TrackUsecase) currentState(ctx context.Context, track *domain.Track) (domain.PlaybackResult, error) {
+	fresh, err := u.trackRepo.GetByDeezerID(ctx, track.DeezerID)
+	if err != nil {
+		return domain.PlaybackResult{}, fmt.Errorf("repo.GetByDeezerID: %w", err)
+	}
+	if fresh == nil {
+		return domain.PlaybackResult{}, fmt.Errorf("track %d disappeared", track.DeezerID)
+	}
+	return domain.PlaybackResult{Status: fresh.Status, TrackID: fresh.ID}, nil
+}
+
+// ResolveAudioSource отдает серверную ссылку на аудио из того хранилища, где лежит трек.
+// Ссылка может содержать секреты — только для прокси, не для клиента.
+func (u *TrackUsecase) ResolveAudioSource(ctx context.Context, track *domain.Track) (string, error) {
+	backend, key, ok := track.Locate()
+	if !ok {
+		return "", fmt.Errorf("track %d has no stored audio", track.ID)
+	}
+
+	storage, err := u.storages.Get(backend)
 	if err != nil {
 		return "", err
 	}
-	return file.Link(u.bot.Token), nil
+	return storage.StreamURL(ctx, key)
 }
+
 func (u *TrackUsecase) Save(ctx context.Context, track *domain.Track) error {
-	// Санитарная проверка
-	if track.Title == "" {
-		track.Title = "Unknown Track"
+	// У присланного файла нет deezer_id — он сохраняется по своим правилам
+	if track.DeezerID == 0 {
+		return u.SaveUpload(ctx, track)
 	}
-	if track.Artist == "" {
-		track.Artist = "Unknown Artist"
+
+	sanitizeTags(track)
+	// Присланный пользователем файл уже лежит в Telegram — он сразу готов
+	if track.Status == "" && track.FileID != "" {
+		track.Status = domain.StatusReady
 	}
 
 	if err := u.trackRepo.Save(ctx, track); err != nil {
@@ -113,6 +177,37 @@ func (u *TrackUsecase) Save(ctx context.Context, track *domain.Track) error {
 	return nil
 }
 
+// SaveUpload сохраняет присланный пользователем файл. Он сразу готов к прослушиванию,
+// а для нового файла в фоне ищется пара на Deezer (см. UploadMatcherUsecase)
+func (u *TrackUsecase) SaveUpload(ctx context.Context, track *domain.Track) error {
+	sanitizeTags(track)
+	track.Status = domain.StatusReady
+
+	created, err := u.trackRepo.SaveUpload(ctx, track)
+	if err != nil {
+		return fmt.Errorf("usecase.SaveUpload: %w", err)
+	}
+	if !created {
+		return nil
+	}
+
+	// Файл уже сохранен и играет — без пары на Deezer он тоже полноценный трек
+	if err := u.queue.EnqueueMatchUpload(ctx, track.ID); err != nil {
+		slog.Error("Failed to enqueue upload match", "track_id", track.ID, "error", err)
+	}
+	return nil
+}
+
+// sanitizeTags — санитарная проверка: у файлов без тегов нет ни названия, ни артиста
+func sanitizeTags(track *domain.Track) {
+	if track.Title == "" {
+		track.Title = unknownTitle
+	}
+	if track.Artist == "" {
+		track.Artist = unknownArtist
+	}
+}
+
 // SaveTrackToUser — это "бизнес-действие": пользователь сохранил трек себе.
 func (u *TrackUsecase) AddTrackToUser(ctx context.Context, user *domain.User, track *domain.Track) error {
 	// 1. Сначала обеспечим наличие юзера (это логика UserUC, но допустим оставим тут для простоты)
@@ -120,9 +215,11 @@ func (u *TrackUsecase) AddTrackToUser(ctx context.Context, user *domain.User, tr
 		return fmt.Errorf("usecase.SaveTrackToUser.UpsertUser: %w", err)
 	}
 
-	// 2. Регистрируем сам трек в системе (Шаг 1)
-	if err := u.Save(ctx, track); err != nil {
-		return err
+	// 2. Регистрируем сам трек в системе (Шаг 1), если он еще не в базе
+	if track.ID == 0 {
+		if err := u.Save(ctx, track); err != nil {
+			return err
+		}
 	}
 
 	// 3. Создаем связь "Пользователь <-> Трек" (Шаг 2)
@@ -148,6 +245,21 @@ func (u *TrackUsecase) GetUserLibrary(ctx context.Context, userID int64) ([]doma
 	return tracks, nil
 }
 
+// SearchUserLibrary — поиск в библиотеке пользователя, самые релевантные первыми.
+// Кириллица и латиница взаимозаменяемы: "kino" находит "Кино"
+func (u *TrackUsecase) SearchUserLibrary(ctx context.Context, userID int64, query string) ([]domain.Track, error) {
+	tracks, err := u.trackRepo.SearchByUserID(ctx, userID, query)
+	if err != nil {
+		return nil, fmt.Errorf("usecase.SearchUserLibrary: %w", err)
+	}
+
+	if tracks == nil {
+		return []domain.Track{}, nil
+	}
+
+	return tracks, nil
+}
+
 // RemoveTrackFromUser — НОВОЕ: Удаляет связь трека с юзером (не сам файл)
 func (u *TrackUsecase) RemoveTrackFromUser(ctx context.Context, userID int64, trackID int64) error {
 	// Мы вызываем DeleteFromUser, который удалит строку из user_tracks
@@ -157,6 +269,14 @@ func (u *TrackUsecase) RemoveTrackFromUser(ctx context.Context, userID int64, tr
 	return nil
 }
 
+func (u *TrackUsecase) GetByID(ctx context.Context, id int64) (*domain.Track, error) {
+	track, err := u.trackRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("usecase.GetByID: %w", err)
+	}
+	return track, nil
+}
+
 func (u *TrackUsecase) GetByDeezerID(ctx context.Context, deezerID int64) (*domain.Track, error) {
 	track, err := u.trackRepo.GetByDeezerID(ctx, deezerID)
 	if err != nil {
@@ -165,6 +285,23 @@ func (u *TrackUsecase) GetByDeezerID(ctx context.Context, deezerID int64) (*doma
 	return track, nil
 }
 
+// GetByDeezerIDs — локальные записи для списка треков Deezer (например, выдачи поиска)
+func (u *TrackUsecase) GetByDeezerIDs(ctx context.Context, deezerIDs []int64) (map[int64]*domain.Track, error) {
+	tracks, err := u.trackRepo.GetByDeezerIDs(ctx, deezerIDs)
+	if err != nil {
+		return nil, fmt.Errorf("usecase.GetByDeezerIDs: %w", err)
+	}
+	return tracks, nil
+}
+
+func (u *TrackUsecase) GetByFileUniqueID(ctx context.Context, fileUniqueID string) (*domain.Track, error) {
+	track, err := u.trackRepo.GetByFileUniqueID(ctx, fileUniqueID)
+	if err != nil {
+		return nil, fmt.Errorf("usecase.GetByFileUniqueID: %w", err)
+	}
+	return track, nil
+}
+
 func (u *TrackUsecase) EnsureTrackByDeezer(ctx context.Context, dzTrack domain.Track) (*domain.Track, bool, error) {
 	// 1. Пытаемся найти в базе
 	existing, err := u.trackRepo.GetByDeezerID(ctx, dzTrack.DeezerID)
@@ -178,8 +315,9 @@ func (u *TrackUsecase) EnsureTrackByDeezer(ctx context.Context, dzTrack domain.T
 	}
 
 	// 3. Если НЕ нашли — создаем "пустышку" (запись в базе)
-	// Устанавливаем начальный статус
-	dzTrack.Status = domain.StatusProcessing
+	// Статус idle: запись может появиться без запуска цепочки (например, из плейлиста),
+	// а GetPlaybackState сам переведет ее в queued
+	dzTrack.Status = domain.StatusIdle
 
 	// Сохраняем в репозиторий.
 	// ВАЖНО: Repo.Save должен заполнить поле dzTrack.ID после Insert (через RETURNING id)
@@ -191,7 +329,11 @@ func (u *TrackUsecase) EnsureTrackByDeezer(ctx context.Context, dzTrack domain.T
 	return &dzTrack, false, nil
 }
 
-func (u *TrackUsecase) UpdateTrackStatus(ctx context.Context, trackID int64, status string) error {
-
-	return u.trackRepo.UpdateStatus(ctx, trackID, status)
+// GetCandidates — кандидаты с YouTube, найденные последним поиском, от лучшего к худшему
+func (u *TrackUsecase) GetCandidates(ctx context.Context, trackID int64) ([]domain.TrackCandidate, error) {
+	candidates, err := u.candidateRepo.GetByTrackID(ctx, trackID)
+	if err != nil {
+		return nil, fmt.Errorf("repo.GetCandidates: %w", err)
+	}
+	return candidates, nil
 }
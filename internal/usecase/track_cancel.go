@@ -0,0 +1,115 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"music-go-bot/internal/domain"
+)
+
+// CancelResult — чем закончилась отмена
+type CancelResult struct {
+	Status    string `json:"status"`    // Статус трека после отмены
+	Cancelled bool   `json:"cancelled"` // Цепочка остановлена
+	Waiters   int    `json:"waiters"`   // Сколько пользователей трек еще ждут, качают пакетом или предзагружают (тогда цепочку не трогаем)
+}
+
+// CancelJob — пользователю трек больше не нужен (пропустил, запросил не тот).
+// Его ожидание снимается всегда, а сама цепочка останавливается, только если трек
+// больше никому не нужен (см. otherUsers): ждущие задачи удаляются, выполняющиеся
+// отменяются (yt-dlp убивается через контекст задачи), трек возвращается в idle.
+// Загрузку в хранилище не отменяем — файл уже скачан, она заканчивается за секунды.
+func (u *TrackUsecase) CancelJob(ctx context.Context, userID, deezerID int64) (CancelResult, error) {
+	track, err := u.trackRepo.GetByDeezerID(ctx, deezerID)
+	if err != nil {
+		return CancelResult{}, fmt.Errorf("repo.GetByDeezerID: %w", err)
+	}
+	if track == nil {
+		return CancelResult{}, domain.ErrTrackNotFound
+	}
+
+	u.unwait(ctx, track.ID, userID)
+
+	if !domain.IsInProgress(track.Status) {
+		return CancelResult{Status: track.Status}, nil
+	}
+
+	others, err := u.otherUsers(ctx, track, userID)
+	if err != nil {
+		return CancelResult{}, err
+	}
+	if others > 0 {
+		return CancelResult{Status: track.Status, Waiters: others}, nil
+	}
+
+	if track.Status == domain.StatusUploading {
+		return CancelResult{}, domain.ErrTrackBusy
+	}
+
+	// Сначала статус: задачи, которые мы не успеем снять, увидят idle в enterStage
+	// и завершатся молча, а отмененная посреди этапа — в stageCancelled
+	if err := u.trackRepo.Transition(ctx, deezerID, domain.StatusIdle); err != nil {
+		if errors.Is(err, domain.ErrInvalidTransition) {
+			// Цепочка успела дойти до загрузки или закончиться
+			return CancelResult{}, domain.ErrTrackBusy
+		}
+		return CancelResult{}, fmt.Errorf("failed to reset track: %w", err)
+	}
+	publishEvent(ctx, u.events, domain.TrackEvent{DeezerID: deezerID, TrackID: track.ID, Stage: domain.StageIdle})
+
+	stats, err := u.queue.CancelTrack(ctx, deezerID)
+	if err != nil {
+		// Трек уже idle: оставшиеся задачи ничего не сделают, только доработает текущий этап
+		slog.Error("Failed to cancel track tasks", "deezer_id", deezerID, "error", err)
+	}
+
+	// Уведомлять некого, но задача уведомления еще и закрывает пакеты, где трек был последним
+	if err := u.queue.EnqueueNotify(ctx, deezerID, 0); err != nil {
+		slog.Error("Failed to enqueue notify task", "deezer_id", deezerID, "error", err)
+	}
+
+	slog.Info("Track job cancelled",
+		"deezer_id", deezerID,
+		"user_id", userID,
+		"was", track.Status,
+		"deleted", stats.Deleted,
+		"cancelled", stats.Cancelled,
+	)
+	return CancelResult{Status: domain.StatusIdle, Cancelled: true}, nil
+}
+
+// otherUsers — сколько других пользователей еще нуждаются в треке: ждут его (play),
+// качают пакетом (альбом целиком) или предзагружают. Пакеты и предзагрузка запускают
+// цепочку без ожидания (userID 0), поэтому одних track_waiters мало
+func (u *TrackUsecase) otherUsers(ctx context.Context, track *domain.Track, userID int64) (int, error) {
+	users := map[int64]bool{}
+
+	waiting, err := u.waiters.List(ctx, track.ID)
+	if err != nil {
+		return 0, fmt.Errorf("waiters.List: %w", err)
+	}
+	for _, id := range waiting {
+		users[id] = true
+	}
+
+	batches, err := u.batches.GetRunningByTrack(ctx, track.ID)
+	if err != nil {
+		return 0, fmt.Errorf("batches.GetRunningByTrack: %w", err)
+	}
+	for _, b := range batches {
+		users[b.UserID] = true
+	}
+
+	owners, err := u.prefetch.Owners(ctx, track.DeezerID)
+	if err != nil {
+		return 0, fmt.Errorf("prefetch.Owners: %w", err)
+	}
+	for _, id := range owners {
+		users[id] = true
+	}
+
+	// Свой пакет или предзагрузку пользователь отменить вправе
+	delete(users, userID)
+	return len(users), nil
+}
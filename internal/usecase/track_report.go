@@ -0,0 +1,128 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"music-go-bot/internal/domain"
+)
+
+var ErrInvalidYoutubeURL = errors.New("invalid youtube url")
+
+// ReportWrongMatch — пользователь говорит, что скачалось не то видео.
+// Текущее видео навсегда исключается для трека, вместо него берется youtubeURL
+// (если передан) или следующий по оценке кандидат, и цепочка download -> upload запускается заново.
+// Если кандидатов не осталось — трек уходит на новый поиск.
+func (u *TrackUsecase) ReportWrongMatch(ctx context.Context, userID, deezerID int64, youtubeURL string) (domain.PlaybackResult, error) {
+	var manualID string
+	if youtubeURL != "" {
+		id, ok := ParseYoutubeID(youtubeURL)
+		if !ok {
+			return domain.PlaybackResult{}, ErrInvalidYoutubeURL
+		}
+		manualID = id
+	}
+
+	track, err := u.trackRepo.GetByDeezerID(ctx, deezerID)
+	if err != nil {
+		return domain.PlaybackResult{}, fmt.Errorf("repo.GetByDeezerID: %w", err)
+	}
+	if track == nil {
+		return domain.PlaybackResult{}, domain.ErrTrackNotFound
+	}
+
+	// 1. Отказ фиксируем сразу: даже если дальше что-то сорвется, это видео больше не выберем
+	if track.YoutubeID != "" {
+		if err := u.candidateRepo.Reject(ctx, track.ID, track.YoutubeID, userID); err != nil {
+			return domain.PlaybackResult{}, fmt.Errorf("repo.Reject: %w", err)
+		}
+	}
+
+	rejected, err := u.candidateRepo.GetRejected(ctx, track.ID)
+	if err != nil {
+		return domain.PlaybackResult{}, fmt.Errorf("repo.GetRejected: %w", err)
+	}
+	if manualID != "" && rejected[manualID] {
+		return domain.PlaybackResult{}, domain.ErrVideoRejected
+	}
+
+	// 2. Выбираем замену
+	nextID := manualID
+	if nextID == "" {
+		candidates, err := u.candidateRepo.GetByTrackID(ctx, track.ID)
+		if err != nil {
+			return domain.PlaybackResult{}, fmt.Errorf("repo.GetCandidates: %w", err)
+		}
+		for _, c := range candidates {
+			if !rejected[c.YoutubeID] {
+				nextID = c.YoutubeID
+				break
+			}
+		}
+	}
+
+	// 3. Захватываем трек: queued — это и перезапуск, и защита от параллельной цепочки
+	if err := u.trackRepo.Transition(ctx, deezerID, domain.StatusQueued); err != nil {
+		if errors.Is(err, domain.ErrInvalidTransition) {
+			return domain.PlaybackResult{}, domain.ErrTrackBusy
+		}
+		return domain.PlaybackResult{}, fmt.Errorf("failed to set queued status: %w", err)
+	}
+
+	// 4. Забываем старый файл, в хранилище он больше не нужен
+	if err := u.trackRepo.ResetAudio(ctx, deezerID, nextID); err != nil {
+		u.failQueued(ctx, deezerID, err)
+		return domain.PlaybackResult{}, fmt.Errorf("failed to reset track audio: %w", err)
+	}
+	u.deleteStoredAudio(ctx, track)
+
+	// Пожаловавшийся получит новую версию, как только она будет готова
+	if userID != 0 {
+		if err := u.waiters.Add(ctx, track.ID, userID); err != nil {
+			slog.Warn("Failed to add track waiter", "track_id", track.ID, "user_id", userID, "error", err)
+		}
+	}
+
+	slog.Info("Wrong match reported",
+		"deezer_id", deezerID,
+		"user_id", userID,
+		"rejected_yt_id", track.YoutubeID,
+		"next_yt_id", nextID,
+	)
+
+	// 5. Перезапускаем цепочку
+	if nextID != "" {
+		err = u.queue.EnqueueDownload(ctx, deezerID, nextID, userID, domain.PriorityHigh)
+	} else {
+		err = u.queue.EnqueueSearch(ctx, deezerID, userID, domain.PriorityHigh)
+	}
+	if err != nil {
+		u.failQueued(ctx, deezerID, err)
+		return domain.PlaybackResult{}, fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	return domain.PlaybackResult{Status: domain.StatusQueued, TrackID: track.ID}, nil
+}
+
+// failQueued фиксирует сбой, случившийся до постановки задачи в очередь
+func (u *TrackUsecase) failQueued(ctx context.Context, deezerID int64, cause error) {
+	if err := u.trackRepo.MarkFailed(ctx, deezerID, domain.StatusQueued, cause.Error()); err != nil {
+		slog.Error("Failed to mark track as failed", "deezer_id", deezerID, "error", err)
+	}
+}
+
+// deleteStoredAudio удаляет старый файл из хранилища. Best-effort: запись в базе уже сброшена
+func (u *TrackUsecase) deleteStoredAudio(ctx context.Context, track *domain.Track) {
+	backend, key, ok := track.Locate()
+	if !ok {
+		return
+	}
+	storage, err := u.storages.Get(backend)
+	if err == nil {
+		err = storage.Delete(ctx, key)
+	}
+	if err != nil {
+		slog.Warn("Failed to delete stale audio", "track_id", track.ID, "backend", backend, "error", err)
+	}
+}
@@ -0,0 +1,127 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"music-go-bot/internal/domain"
+	"strings"
+)
+
+// Теги, которые ставятся файлам без тегов (см. sanitizeTags) — искать по ним нечего
+const (
+	unknownTitle  = "Unknown Track"
+	unknownArtist = "Unknown Artist"
+)
+
+// Пару загрузке ищем строже, чем для ссылок: связанный трек Deezer начнет играть
+// из присланного файла, и ошибка здесь подменит трек для всех
+const (
+	uploadMatchThreshold   = 0.8
+	uploadMatchMaxDelta    = 3 // Секунд расхождения по длительности
+	pendingUploadsPageSize = 100
+)
+
+// MatchQueue — что поиску пары нужно от очереди
+type MatchQueue interface {
+	EnqueueMatchUpload(ctx context.Context, trackID int64) error
+}
+
+// UploadMatcherUsecase ищет присланным файлам пару на Deezer по тегам и длительности
+type UploadMatcherUsecase struct {
+	repo   domain.TrackRepository
+	deezer *SearchUsecaseDZ
+	queue  MatchQueue
+}
+
+func NewUploadMatcherUsecase(repo domain.TrackRepository, deezer *SearchUsecaseDZ, queue MatchQueue) *UploadMatcherUsecase {
+	return &UploadMatcherUsecase{
+		repo:   repo,
+		deezer: deezer,
+		queue:  queue,
+	}
+}
+
+// Match — поиск пары для одной загрузки (задача tracks:match_upload)
+func (u *UploadMatcherUsecase) Match(ctx context.Context, trackID int64) error {
+	track, err := u.repo.GetByID(ctx, trackID)
+	if err != nil {
+		return fmt.Errorf("repo.GetByID: %w", err)
+	}
+	// Удалили или уже разобрали (задача могла встать дважды: при загрузке и при досылке на старте)
+	if track == nil || track.Source != domain.SourceUpload || track.MatchStatus != domain.MatchPending {
+		return nil
+	}
+
+	l := slog.With("track_id", track.ID, "artist", track.Artist, "title", track.Title)
+
+	match, err := u.findOnDeezer(ctx, track)
+	if err != nil {
+		return err
+	}
+	if match == nil {
+		l.Info("Upload: no confident Deezer match")
+		return u.repo.SetMatchStatus(ctx, track.ID, domain.MatchUnmatched)
+	}
+
+	claimed, err := u.repo.LinkUpload(ctx, track.ID, match)
+	if err != nil {
+		return fmt.Errorf("repo.LinkUpload: %w", err)
+	}
+	l.Info("Upload matched on Deezer", "deezer_id", match.DeezerID, "claimed", claimed)
+	return nil
+}
+
+// findOnDeezer — уверенная пара на Deezer или nil
+func (u *UploadMatcherUsecase) findOnDeezer(ctx context.Context, track *domain.Track) (*domain.Track, error) {
+	if track.Title == unknownTitle || track.Artist == unknownArtist || track.Duration <= 0 {
+		return nil, nil
+	}
+
+	found, err := u.deezer.SearchDeezer(ctx, strings.TrimSpace(track.Artist+" "+coreTitle(track.Title)))
+	if err != nil {
+		return nil, fmt.Errorf("deezer search: %w", err)
+	}
+
+	best, score := bestDeezerMatch(found, track.Artist, track.Title, track.Duration)
+	if best == nil || score < uploadMatchThreshold {
+		return nil, nil
+	}
+	// В оценке длительность лишь штрафует; здесь она обязана совпасть —
+	// иначе это другая версия трека (live, remix, radio edit)
+	if math.Abs(float64(best.Duration-track.Duration)) > uploadMatchMaxDelta {
+		return nil, nil
+	}
+	return best, nil
+}
+
+// EnqueuePending ставит поиск пары для загрузок, которые его еще не прошли:
+// присланных до появления поиска или потерявших задачу. Дубли задач отсекает очередь
+func (u *UploadMatcherUsecase) EnqueuePending(ctx context.Context) error {
+	var afterID int64
+	var total int
+	for {
+		tracks, err := u.repo.GetPendingUploads(ctx, afterID, pendingUploadsPageSize)
+		if err != nil {
+			return fmt.Errorf("repo.GetPendingUploads: %w", err)
+		}
+
+		for _, t := range tracks {
+			if err := u.queue.EnqueueMatchUpload(ctx, t.ID); err != nil {
+				return fmt.Errorf("queue.EnqueueMatchUpload: %w", err)
+			}
+			afterID = t.ID
+		}
+		total += len(tracks)
+
+		if len(tracks) < pendingUploadsPageSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		slog.Info("Enqueued pending upload matches", "count", total)
+	}
+	return nil
+}
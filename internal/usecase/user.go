@@ -30,3 +30,15 @@ func (u *UserUsecase) UpsertUser(ctx context.Context, user *domain.User) error {
 	}
 	return u.userRepo.Upsert(user)
 }
+
+// EnsureUser заводит пользователя, о котором известен только id (dev-режим без initData)
+func (u *UserUsecase) EnsureUser(ctx context.Context, id int64) error {
+	if id == 0 {
+		return errors.New("invalid user id")
+	}
+	return u.userRepo.Ensure(id)
+}
+
+func (u *UserUsecase) SetNotifyReady(ctx context.Context, id int64, enabled bool) error {
+	return u.userRepo.SetNotifyReady(id, enabled)
+}
@@ -0,0 +1,47 @@
+package usecase
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var youtubeIDRe = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// ParseYoutubeID достает ID видео из ссылки YouTube / YouTube Music / youtu.be
+// или принимает голый ID из 11 символов
+func ParseYoutubeID(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if youtubeIDRe.MatchString(raw) {
+		return raw, true
+	}
+
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	path := strings.Trim(u.Path, "/")
+
+	var id string
+	switch host {
+	case "youtu.be":
+		id = path
+	case "youtube.com", "m.youtube.com", "music.youtube.com":
+		switch {
+		case path == "watch":
+			id = u.Query().Get("v")
+		case strings.HasPrefix(path, "shorts/"), strings.HasPrefix(path, "embed/"), strings.HasPrefix(path, "live/"):
+			id = path[strings.Index(path, "/")+1:]
+		}
+	}
+
+	if !youtubeIDRe.MatchString(id) {
+		return "", false
+	}
+	return id, true
+}
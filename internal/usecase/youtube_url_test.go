@@ -0,0 +1,45 @@
+package usecase
+
+import "testing"
+
+func TestParseYoutubeID(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string // Пусто — ссылка не распознана
+	}{
+		{"dQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{"  dQw4w9WgXcQ  ", "dQw4w9WgXcQ"},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&list=PL123&index=2", "dQw4w9WgXcQ"},
+		{"youtube.com/watch?v=dQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{"https://m.youtube.com/watch?v=dQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{"https://music.youtube.com/watch?v=dQw4w9WgXcQ&si=AbCdEf", "dQw4w9WgXcQ"},
+		{"https://youtu.be/dQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{"https://youtu.be/dQw4w9WgXcQ?si=AbCdEfGh12", "dQw4w9WgXcQ"},
+		{"https://youtu.be/dQw4w9WgXcQ?t=42", "dQw4w9WgXcQ"},
+		{"https://www.youtube.com/shorts/dQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{"https://youtube.com/shorts/dQw4w9WgXcQ?feature=share", "dQw4w9WgXcQ"},
+		{"https://www.youtube.com/embed/dQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{"https://www.youtube.com/live/dQw4w9WgXcQ/", "dQw4w9WgXcQ"},
+
+		{"", ""},
+		{"dQw4w9WgXc", ""},   // 10 символов
+		{"dQw4w9WgXcQQ", ""}, // 12 символов
+		{"https://youtu.be/", ""},
+		{"https://www.youtube.com/watch", ""},
+		{"https://www.youtube.com/watch?v=short", ""},
+		{"https://www.youtube.com/channel/UC38IQsAvIsxxjztdMZQtwHA", ""},
+		{"https://www.youtube.com/playlist?list=PL123", ""},
+		{"https://example.com/watch?v=dQw4w9WgXcQ", ""},
+		{"https://vimeo.com/dQw4w9WgXcQ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, ok := ParseYoutubeID(tt.raw)
+			if ok != (tt.want != "") || got != tt.want {
+				t.Errorf("ParseYoutubeID(%q) = %q, %v; want %q", tt.raw, got, ok, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package usecase
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// MatcherConfig — настройки выбора видео на YouTube
+type MatcherConfig struct {
+	Threshold  float64 // Ниже этой оценки лучший кандидат считается сомнительным (low_confidence)
+	Candidates int     // Сколько лучших кандидатов сохранять в track_candidates
+	SearchSize int     // Сколько результатов запрашивать у ytsearch
+}
+
+const (
+	defaultMatchThreshold  = 0.6
+	defaultMatchCandidates = 5
+	defaultMatchSearchSize = 10
+
+	maxVideoDuration = 1200 // Длиннее 20 минут — это сборники и стримы, а не трек
+)
+
+func (c MatcherConfig) withDefaults() MatcherConfig {
+	if c.Threshold <= 0 {
+		c.Threshold = defaultMatchThreshold
+	}
+	if c.Candidates <= 0 {
+		c.Candidates = defaultMatchCandidates
+	}
+	if c.SearchSize <= 0 {
+		c.SearchSize = defaultMatchSearchSize
+	}
+	if c.SearchSize < c.Candidates {
+		c.SearchSize = c.Candidates
+	}
+	return c
+}
+
+// Веса составляющих оценки. В сумме 1 — идеальное совпадение без бонусов
+const (
+	weightTitle    = 0.40
+	weightArtist   = 0.25
+	weightDuration = 0.35
+
+	bonusTopic    = 0.10 // Автоканал "<Artist> - Topic" — это студийная запись
+	bonusOfficial = 0.05 // VEVO, "Official", канал самого артиста
+
+	penaltyKeyword = 0.25 // За каждую "не ту версию" в названии видео
+
+	durationTolerance = 30.0 // Секунд разницы, после которых длительность уже ничего не дает
+)
+
+// Признаки другой версии трека. Штрафуем, только если их нет в названии с Deezer
+// (у живого альбома "live" в названии — это правильно)
+var versionKeywords = []string{
+	"live", "cover", "remix", "sped up", "speed up", "slowed", "reverb",
+	"8d", "nightcore", "karaoke", "instrumental", "bass boosted", "reaction",
+}
+
+// Хвосты в названии с Deezer, которые на YouTube обычно не пишут
+var (
+	bracketsRe = regexp.MustCompile(`[(\[][^)\]]*[)\]]`)
+	suffixRe   = regexp.MustCompile(`\s+-\s+.*$`)
+)
+
+// ytEntry — результат ytsearch в режиме --flat-playlist
+type ytEntry struct {
+	ID       string  `json:"id"`
+	Title    string  `json:"title"`
+	Duration float64 `json:"duration"`
+	Channel  string  `json:"channel"`
+	Uploader string  `json:"uploader"`
+}
+
+func (e ytEntry) channel() string {
+	if e.Channel != "" {
+		return e.Channel
+	}
+	return e.Uploader
+}
+
+type scoredEntry struct {
+	ytEntry
+	Score float64
+}
+
+// rankEntries оценивает результаты поиска и сортирует их от лучшего к худшему.
+// Слишком длинные видео и записи без ID отбрасываются.
+func rankEntries(entries []ytEntry, artist, title string, targetDuration float64) []scoredEntry {
+	ranked := make([]scoredEntry, 0, len(entries))
+	for _, e := range entries {
+		if e.ID == "" || e.Duration > maxVideoDuration {
+			continue
+		}
+		ranked = append(ranked, scoredEntry{ytEntry: e, Score: scoreEntry(e, artist, title, targetDuration)})
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+	return ranked
+}
+
+// scoreEntry — оценка совпадения видео с треком от 0 до 1
+func scoreEntry(e ytEntry, artist, title string, targetDuration float64) float64 {
+	channel := strings.TrimSuffix(e.channel(), " - Topic")
+	videoTokens := tokenSet(e.Title + " " + channel)
+
+	score := weightTitle*coverage(coreTitle(title), videoTokens) +
+		weightArtist*coverage(artist, videoTokens) +
+		weightDuration*durationScore(e.Duration, targetDuration)
+
+	if strings.HasSuffix(e.channel(), " - Topic") {
+		score += bonusTopic
+	} else if isOfficialChannel(e.channel(), artist) {
+		score += bonusOfficial
+	}
+
+	videoTitle := " " + normalize(e.Title) + " "
+	sourceTitle := " " + normalize(title) + " "
+	for _, kw := range versionKeywords {
+		kw = " " + kw + " "
+		if strings.Contains(videoTitle, kw) && !strings.Contains(sourceTitle, kw) {
+			score -= penaltyKeyword
+		}
+	}
+
+	return math.Max(0, math.Min(1, score))
+}
+
+// durationScore — 1 при совпадении длительности, 0 при разнице от durationTolerance.
+// Если длительность с Deezer неизвестна, ставим нейтральные 0.5
+func durationScore(duration, target float64) float64 {
+	if target <= 0 || duration <= 0 {
+		return 0.5
+	}
+	return math.Max(0, 1-math.Abs(duration-target)/durationTolerance)
+}
+
+func isOfficialChannel(channel, artist string) bool {
+	c := normalize(channel)
+	if strings.Contains(c, "vevo") || strings.Contains(c, "official") {
+		return true
+	}
+	a := normalize(artist)
+	return a != "" && strings.ReplaceAll(c, " ", "") == strings.ReplaceAll(a, " ", "")
+}
+
+// coreTitle убирает из названия скобки и хвосты вида " - Remastered 2011"
+func coreTitle(title string) string {
+	core := strings.TrimSpace(suffixRe.ReplaceAllString(bracketsRe.ReplaceAllString(title, " "), ""))
+	if core == "" {
+		return title
+	}
+	return core
+}
+
+// coverage — доля слов source, которые встречаются в видео
+func coverage(source string, videoTokens map[string]struct{}) float64 {
+	words := strings.Fields(normalize(source))
+	if len(words) == 0 {
+		return 0
+	}
+
+	found := 0
+	for _, w := range words {
+		if _, ok := videoTokens[w]; ok {
+			found++
+		}
+	}
+	return float64(found) / float64(len(words))
+}
+
+func tokenSet(s string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, w := range strings.Fields(normalize(s)) {
+		set[w] = struct{}{}
+	}
+	return set
+}
+
+// normalize — нижний регистр, все кроме букв и цифр превращается в пробелы
+func normalize(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
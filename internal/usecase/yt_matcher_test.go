@@ -0,0 +1,134 @@
+package usecase
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScoreEntry(t *testing.T) {
+	const (
+		artist = "Кино"
+		title  = "Группа крови"
+		target = 285.0
+	)
+	// Совпали название и артист, длительность отличается на 15 с (половина допуска):
+	// 0.40 + 0.25 + 0.35*0.5
+	const base = weightTitle + weightArtist + weightDuration*0.5
+
+	tests := []struct {
+		name          string
+		entry         ytEntry
+		artist, title string
+		target        float64
+		want          float64
+	}{
+		{"plain upload", ytEntry{Title: "Кино - Группа крови", Channel: "Music Fan 2000", Duration: 300}, artist, title, target, base},
+		{"topic channel bonus", ytEntry{Title: "Группа крови", Channel: "Кино - Topic", Duration: 300}, artist, title, target, base + bonusTopic},
+		{"topic channel from uploader", ytEntry{Title: "Группа крови", Uploader: "Кино - Topic", Duration: 300}, artist, title, target, base + bonusTopic},
+		{"artist channel bonus", ytEntry{Title: "Группа крови", Channel: "КИНО", Duration: 300}, artist, title, target, base + bonusOfficial},
+		{"vevo channel bonus", ytEntry{Title: "Кино - Группа крови", Channel: "KinoVEVO", Duration: 300}, artist, title, target, base + bonusOfficial},
+		{"official channel bonus", ytEntry{Title: "Кино - Группа крови", Channel: "Kino Official", Duration: 300}, artist, title, target, base + bonusOfficial},
+		{"perfect match clamped to 1", ytEntry{Title: "Группа крови", Channel: "Кино - Topic", Duration: 285}, artist, title, target, 1},
+
+		{"live penalty", ytEntry{Title: "Кино - Группа крови (Live)", Channel: "Music Fan", Duration: 300}, artist, title, target, base - penaltyKeyword},
+		{"penalty per keyword", ytEntry{Title: "Кино - Группа крови (sped up + reverb)", Channel: "Music Fan", Duration: 300}, artist, title, target, base - 2*penaltyKeyword},
+		{"keyword inside a word is not penalised", ytEntry{Title: "Кино - Группа крови alive", Channel: "Music Fan", Duration: 300}, artist, title, target, base},
+		{"live in deezer title skips penalty", ytEntry{Title: "Кино - Группа крови (Live)", Channel: "Music Fan", Duration: 300}, artist, "Группа крови (Live)", target, base},
+		{"remix in deezer title skips penalty", ytEntry{Title: "Daft Punk - One More Time (Remix)", Channel: "Music Fan", Duration: 300}, "Daft Punk", "One More Time (Remix)", target, base},
+		{"other keyword still penalised", ytEntry{Title: "Daft Punk - One More Time (Remix) [Nightcore]", Channel: "Music Fan", Duration: 300}, "Daft Punk", "One More Time (Remix)", target, base - penaltyKeyword},
+
+		{"unknown deezer duration is neutral", ytEntry{Title: "Кино - Группа крови", Channel: "Music Fan", Duration: 1000}, artist, title, 0, base},
+		{"missing artist", ytEntry{Title: "Группа крови", Channel: "Music Fan", Duration: 300}, artist, title, target, weightTitle + weightDuration*0.5},
+		{"half the title", ytEntry{Title: "Кино - Группа", Channel: "Music Fan", Duration: 300}, artist, title, target, weightTitle*0.5 + weightArtist + weightDuration*0.5},
+		{"clamped to 0", ytEntry{Title: "Nightcore Karaoke Cover", Channel: "Music Fan", Duration: 0}, artist, title, target, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scoreEntry(tt.entry, tt.artist, tt.title, tt.target)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("scoreEntry = %.4f, want %.4f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDurationScore(t *testing.T) {
+	tests := []struct {
+		duration, target float64
+		want             float64
+	}{
+		{285, 285, 1},
+		{284, 285, 1 - 1/durationTolerance},
+		{300, 285, 0.5},
+		{270, 285, 0.5},
+		{285 + durationTolerance - 1, 285, 1 / durationTolerance},
+		{285 + durationTolerance, 285, 0}, // Граница допуска
+		{285 - durationTolerance, 285, 0},
+		{600, 285, 0},
+		{0, 285, 0.5}, // Длительность видео неизвестна
+		{285, 0, 0.5}, // Длительность с Deezer неизвестна
+	}
+
+	for _, tt := range tests {
+		if got := durationScore(tt.duration, tt.target); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("durationScore(%v, %v) = %.4f, want %.4f", tt.duration, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestCoreTitle(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Группа крови", "Группа крови"},
+		{"Группа крови (Remastered 2019)", "Группа крови"},
+		{"Bohemian Rhapsody - Remastered 2011", "Bohemian Rhapsody"},
+		{"Song [feat. Someone] (Live)", "Song"},
+		{"Track - Live at Wembley (2005)", "Track"},
+		{"Jay-Z", "Jay-Z"}, // Дефис без пробелов — не хвост
+		{"(Intro)", "(Intro)"},
+		{"  Spaces  ", "Spaces"},
+	}
+
+	for _, tt := range tests {
+		if got := coreTitle(tt.in); got != tt.want {
+			t.Errorf("coreTitle(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRankEntries(t *testing.T) {
+	entries := []ytEntry{
+		{ID: "live", Title: "Кино - Группа крови (Live)", Channel: "Music Fan", Duration: 285},
+		{ID: "", Title: "Кино - Группа крови", Channel: "Кино - Topic", Duration: 285}, // Без ID
+		{ID: "mix", Title: "Кино - Группа крови (Full Album)", Channel: "Кино", Duration: maxVideoDuration + 1},
+		{ID: "fan", Title: "Кино - Группа крови", Channel: "Music Fan", Duration: 290},
+		{ID: "topic", Title: "Группа крови", Channel: "Кино - Topic", Duration: 287},
+		{ID: "edge", Title: "Кино - Группа крови", Channel: "Music Fan", Duration: maxVideoDuration},
+	}
+
+	ranked := rankEntries(entries, "Кино", "Группа крови", 285)
+
+	var ids []string
+	for _, e := range ranked {
+		ids = append(ids, e.ID)
+	}
+	want := []string{"topic", "fan", "live", "edge"}
+	if len(ids) != len(want) {
+		t.Fatalf("ranked = %v, want %v", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("ranked = %v, want %v", ids, want)
+		}
+	}
+	for i := 1; i < len(ranked); i++ {
+		if ranked[i].Score > ranked[i-1].Score {
+			t.Errorf("not sorted: %v", ranked)
+		}
+	}
+	if rankEntries(nil, "Кино", "Группа крови", 285) == nil {
+		t.Error("rankEntries(nil) should return an empty slice")
+	}
+}
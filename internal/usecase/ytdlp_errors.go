@@ -0,0 +1,67 @@
+package usecase
+
+import (
+	"music-go-bot/internal/domain"
+	"strings"
+	"time"
+
+	"github.com/lrstanley/go-ytdlp"
+)
+
+// YouTube не говорит, сколько ждать после 429 и проверки "вы не бот" — берем с запасом
+const ytRateLimitDelay = 15 * time.Minute
+
+// Фрагменты stderr yt-dlp, по которым понятно, что повтор не поможет
+var (
+	ytUnavailableMarkers = []string{
+		"video unavailable",
+		"private video",
+		"has been removed",
+		"account associated with this video has been terminated",
+		"not available in your country",
+		"copyright claim",
+		"this live event will begin",
+		"members-only content",
+	}
+	ytAgeRestrictedMarkers = []string{
+		"sign in to confirm your age",
+		"age-restricted",
+		"inappropriate for some users",
+	}
+	ytRateLimitMarkers = []string{
+		"http error 429",
+		"too many requests",
+		"not a bot",
+	}
+)
+
+// classifyYtdlpError превращает ошибку yt-dlp в типизированную ошибку этапа по его stderr
+func classifyYtdlpError(res *ytdlp.Result, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var stderr string
+	if res != nil {
+		stderr = strings.ToLower(res.Stderr)
+	}
+
+	switch {
+	case containsAny(stderr, ytAgeRestrictedMarkers):
+		return domain.PermanentError("age_restricted", err)
+	case containsAny(stderr, ytUnavailableMarkers):
+		return domain.PermanentError("video_unavailable", err)
+	case containsAny(stderr, ytRateLimitMarkers):
+		return domain.RateLimitedError("youtube_rate_limited", ytRateLimitDelay, err)
+	}
+	return domain.TransientError("ytdlp_failed", err)
+}
+
+func containsAny(s string, markers []string) bool {
+	for _, m := range markers {
+		if strings.Contains(s, m) {
+			return true
+		}
+	}
+	return false
+}
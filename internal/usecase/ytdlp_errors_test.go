@@ -0,0 +1,145 @@
+package usecase
+
+import (
+	"errors"
+	"music-go-bot/internal/domain"
+	"testing"
+
+	"github.com/lrstanley/go-ytdlp"
+)
+
+func TestClassifyYtdlpError(t *testing.T) {
+	exitErr := errors.New("exit status 1")
+
+	tests := []struct {
+		name       string
+		stderr     string
+		wantClass  string
+		wantReason string
+	}{
+		{
+			name:       "terminated account",
+			stderr:     "ERROR: [youtube] dQw4w9WgXcQ: Video unavailable. This video is no longer available because the YouTube account associated with this video has been terminated.",
+			wantClass:  domain.ErrorPermanent,
+			wantReason: "video_unavailable",
+		},
+		{
+			name:       "private video",
+			stderr:     "ERROR: [youtube] dQw4w9WgXcQ: Private video. Sign in if you've been granted access to this video",
+			wantClass:  domain.ErrorPermanent,
+			wantReason: "video_unavailable",
+		},
+		{
+			name:       "removed for tos",
+			stderr:     "ERROR: [youtube] dQw4w9WgXcQ: This video has been removed for violating YouTube's Terms of Service",
+			wantClass:  domain.ErrorPermanent,
+			wantReason: "video_unavailable",
+		},
+		{
+			name:       "geo blocked",
+			stderr:     "ERROR: [youtube] dQw4w9WgXcQ: Video unavailable. The uploader has not made this video available in your country",
+			wantClass:  domain.ErrorPermanent,
+			wantReason: "video_unavailable",
+		},
+		{
+			name:       "copyright block",
+			stderr:     "ERROR: [youtube] dQw4w9WgXcQ: Video unavailable. This video is no longer available due to a copyright claim by SME",
+			wantClass:  domain.ErrorPermanent,
+			wantReason: "video_unavailable",
+		},
+		{
+			name:       "upcoming live",
+			stderr:     "ERROR: [youtube] dQw4w9WgXcQ: This live event will begin in 3 days.",
+			wantClass:  domain.ErrorPermanent,
+			wantReason: "video_unavailable",
+		},
+		{
+			name:       "members only",
+			stderr:     "ERROR: [youtube] dQw4w9WgXcQ: Join this channel to get access to members-only content like this video, and other exclusive perks.",
+			wantClass:  domain.ErrorPermanent,
+			wantReason: "video_unavailable",
+		},
+		{
+			name:       "age gate",
+			stderr:     "ERROR: [youtube] dQw4w9WgXcQ: Sign in to confirm your age. This video may be inappropriate for some users. Use --cookies-from-browser or --cookies for the authentication.",
+			wantClass:  domain.ErrorPermanent,
+			wantReason: "age_restricted",
+		},
+		{
+			name:       "age restricted with warnings before",
+			stderr:     "WARNING: [youtube] Skipping player responses from android clients\nERROR: [youtube] dQw4w9WgXcQ: This video is age-restricted and only available on YouTube.",
+			wantClass:  domain.ErrorPermanent,
+			wantReason: "age_restricted",
+		},
+		{
+			name:       "bot check",
+			stderr:     "ERROR: [youtube] dQw4w9WgXcQ: Sign in to confirm you’re not a bot. Use --cookies-from-browser or --cookies for the authentication.",
+			wantClass:  domain.ErrorRateLimited,
+			wantReason: "youtube_rate_limited",
+		},
+		{
+			name:       "http 429",
+			stderr:     "ERROR: unable to download video data: HTTP Error 429: Too Many Requests",
+			wantClass:  domain.ErrorRateLimited,
+			wantReason: "youtube_rate_limited",
+		},
+		{
+			name:       "http 403",
+			stderr:     "ERROR: unable to download video data: HTTP Error 403: Forbidden",
+			wantClass:  domain.ErrorTransient,
+			wantReason: "ytdlp_failed",
+		},
+		{
+			name:       "read timeout",
+			stderr:     "ERROR: [download] Got error: The read operation timed out",
+			wantClass:  domain.ErrorTransient,
+			wantReason: "ytdlp_failed",
+		},
+		{
+			name:       "requested format",
+			stderr:     "ERROR: [youtube] dQw4w9WgXcQ: Requested format is not available. Use --list-formats for a list of available formats",
+			wantClass:  domain.ErrorTransient,
+			wantReason: "ytdlp_failed",
+		},
+		{
+			name:       "ffmpeg missing",
+			stderr:     "ERROR: Postprocessing: ffprobe and ffmpeg not found. Please install or provide the path using --ffmpeg-location",
+			wantClass:  domain.ErrorTransient,
+			wantReason: "ytdlp_failed",
+		},
+		{
+			name:       "empty stderr",
+			stderr:     "",
+			wantClass:  domain.ErrorTransient,
+			wantReason: "ytdlp_failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyYtdlpError(&ytdlp.Result{Stderr: tt.stderr}, exitErr)
+			se := domain.ClassifyError(err)
+			if se.Class != tt.wantClass || se.Reason != tt.wantReason {
+				t.Errorf("got [%s] %s, want [%s] %s", se.Class, se.Reason, tt.wantClass, tt.wantReason)
+			}
+			if !errors.Is(err, exitErr) {
+				t.Errorf("original error is lost: %v", err)
+			}
+			if delay, ok := domain.RetryAfter(err); ok != (tt.wantClass == domain.ErrorRateLimited) || (ok && delay != ytRateLimitDelay) {
+				t.Errorf("RetryAfter = %v, %v", delay, ok)
+			}
+		})
+	}
+}
+
+func TestClassifyYtdlpErrorWithoutResult(t *testing.T) {
+	if err := classifyYtdlpError(&ytdlp.Result{Stderr: "ERROR: Video unavailable"}, nil); err != nil {
+		t.Errorf("nil error classified as %v", err)
+	}
+
+	// Процесс не запустился — stderr нет
+	se := domain.ClassifyError(classifyYtdlpError(nil, errors.New("executable file not found")))
+	if se.Class != domain.ErrorTransient || se.Reason != "ytdlp_failed" {
+		t.Errorf("got [%s] %s, want transient ytdlp_failed", se.Class, se.Reason)
+	}
+}
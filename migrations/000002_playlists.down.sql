@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_playlist_tracks_track_id;
+DROP TABLE IF EXISTS playlist_tracks;
+
+DROP INDEX IF EXISTS idx_playlists_user_id;
+DROP TABLE IF EXISTS playlists;
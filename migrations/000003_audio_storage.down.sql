@@ -0,0 +1,2 @@
+ALTER TABLE tracks DROP COLUMN IF EXISTS storage_key;
+ALTER TABLE tracks DROP COLUMN IF EXISTS storage_backend;
@@ -0,0 +1,8 @@
+-- Где лежит аудио трека: имя бэкенда (telegram, local, s3) и ключ в нем
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS storage_backend VARCHAR(20);
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS storage_key TEXT;
+
+-- Все уже загруженные треки лежат в Telegram, ключ — file_id
+UPDATE tracks
+SET storage_backend = 'telegram', storage_key = file_id
+WHERE file_id IS NOT NULL AND file_id <> '' AND storage_key IS NULL;
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_tracks_status_updated_at;
+
+ALTER TABLE tracks DROP CONSTRAINT IF EXISTS chk_tracks_status;
+ALTER TABLE tracks ALTER COLUMN status DROP NOT NULL;
+
+UPDATE tracks SET status = 'processing' WHERE status IN ('queued', 'searching', 'downloading', 'uploading');
+UPDATE tracks SET status = 'error' WHERE status IN ('failed', 'blocked');
+
+ALTER TABLE tracks DROP COLUMN IF EXISTS attempts;
+ALTER TABLE tracks DROP COLUMN IF EXISTS failed_stage;
+ALTER TABLE tracks DROP COLUMN IF EXISTS last_error;
+ALTER TABLE tracks DROP COLUMN IF EXISTS updated_at;
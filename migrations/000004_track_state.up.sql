@@ -0,0 +1,19 @@
+-- UpdateStatus давно пишет updated_at, а колонки не было
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW();
+
+-- Диагностика сбоев цепочки
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS last_error TEXT;
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS failed_stage VARCHAR(20);
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
+
+-- Старые статусы -> новая машина состояний
+UPDATE tracks SET status = 'queued' WHERE status = 'processing';
+UPDATE tracks SET status = 'failed' WHERE status = 'error';
+UPDATE tracks SET status = 'idle' WHERE status IS NULL;
+
+ALTER TABLE tracks ALTER COLUMN status SET NOT NULL;
+ALTER TABLE tracks ADD CONSTRAINT chk_tracks_status CHECK (status IN (
+    'idle', 'queued', 'searching', 'downloading', 'uploading', 'ready', 'failed', 'blocked'
+));
+
+CREATE INDEX IF NOT EXISTS idx_tracks_status_updated_at ON tracks(status, updated_at);
@@ -0,0 +1,8 @@
+UPDATE tracks SET status = 'failed' WHERE status = 'low_confidence';
+
+ALTER TABLE tracks DROP CONSTRAINT IF EXISTS chk_tracks_status;
+ALTER TABLE tracks ADD CONSTRAINT chk_tracks_status CHECK (status IN (
+    'idle', 'queued', 'searching', 'downloading', 'uploading', 'ready', 'failed', 'blocked'
+));
+
+DROP TABLE IF EXISTS track_candidates;
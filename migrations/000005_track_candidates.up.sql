@@ -0,0 +1,22 @@
+-- Кандидаты с YouTube, которых нашел поиск, с оценкой совпадения.
+-- Храним топ-N: пригодятся, если выбранное видео окажется не тем
+CREATE TABLE IF NOT EXISTS track_candidates (
+    id SERIAL PRIMARY KEY,
+    track_id INTEGER NOT NULL REFERENCES tracks(id) ON DELETE CASCADE,
+    youtube_id TEXT NOT NULL,
+    title TEXT NOT NULL,
+    channel TEXT,
+    duration INTEGER,
+    score DOUBLE PRECISION NOT NULL,
+    rank INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT uq_track_candidates_video UNIQUE (track_id, youtube_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_track_candidates_track_rank ON track_candidates(track_id, rank);
+
+-- Новое состояние: поиск нашел только сомнительные варианты
+ALTER TABLE tracks DROP CONSTRAINT IF EXISTS chk_tracks_status;
+ALTER TABLE tracks ADD CONSTRAINT chk_tracks_status CHECK (status IN (
+    'idle', 'queued', 'searching', 'low_confidence', 'downloading', 'uploading', 'ready', 'failed', 'blocked'
+));
@@ -0,0 +1,9 @@
+-- Видео, которые пользователи отметили как "не тот трек".
+-- Отдельно от track_candidates: кандидаты перезаписываются новым поиском, а отказ — навсегда
+CREATE TABLE IF NOT EXISTS track_rejections (
+    track_id INTEGER NOT NULL REFERENCES tracks(id) ON DELETE CASCADE,
+    youtube_id TEXT NOT NULL,
+    user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (track_id, youtube_id)
+);
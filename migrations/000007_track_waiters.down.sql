@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS notify_ready;
+
+DROP TABLE IF EXISTS track_waiters;
@@ -0,0 +1,11 @@
+-- Кто ждет трек: после окончания цепочки каждому придет аудио или объяснение, почему не вышло.
+-- Строка удаляется при отправке уведомления — так каждый получает его ровно один раз
+CREATE TABLE IF NOT EXISTS track_waiters (
+    track_id INTEGER NOT NULL REFERENCES tracks(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (track_id, user_id)
+);
+
+-- Пользователь может отказаться от уведомлений в чате (/notify off)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS notify_ready BOOLEAN NOT NULL DEFAULT TRUE;
@@ -0,0 +1,25 @@
+-- Пакетная задача: альбом или плейлист целиком. Прогресс считается по статусам треков
+CREATE TABLE IF NOT EXISTS batches (
+    id SERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,          -- album / playlist
+    source VARCHAR(20) NOT NULL,        -- deezer / spotify / youtube
+    source_id TEXT NOT NULL,            -- ID альбома/плейлиста в источнике
+    title TEXT NOT NULL DEFAULT '',
+    add_to_library BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(20) NOT NULL DEFAULT 'running',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_batches_user_id ON batches(user_id);
+
+CREATE TABLE IF NOT EXISTS batch_tracks (
+    batch_id INTEGER NOT NULL REFERENCES batches(id) ON DELETE CASCADE,
+    track_id INTEGER NOT NULL REFERENCES tracks(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL CHECK (position >= 0),
+    PRIMARY KEY (batch_id, track_id)
+);
+
+-- Закончилась цепочка трека — ищем незавершенные пакеты, где он есть
+CREATE INDEX IF NOT EXISTS idx_batch_tracks_track_id ON batch_tracks(track_id);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_tracks_match_status;
+DROP INDEX IF EXISTS uq_tracks_file_unique_id;
+ALTER TABLE tracks DROP CONSTRAINT IF EXISTS chk_tracks_source;
+
+ALTER TABLE tracks DROP COLUMN IF EXISTS matched_deezer_id;
+ALTER TABLE tracks DROP COLUMN IF EXISTS match_status;
+ALTER TABLE tracks DROP COLUMN IF EXISTS source;
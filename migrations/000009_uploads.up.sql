@@ -0,0 +1,41 @@
+-- Присланные пользователями файлы — отдельный источник треков.
+-- У них нет deezer_id (NULL, а не 0), а уникальны они по file_unique_id
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS source VARCHAR(20) NOT NULL DEFAULT 'deezer';
+-- Фоновый поиск пары на Deezer: pending -> matched | unmatched (только для загрузок)
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS match_status VARCHAR(20);
+-- Найденный трек Deezer. Если этот deezer_id никем не занят, он же записывается в deezer_id
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS matched_deezer_id BIGINT;
+
+-- Все загрузки до сих пор писались с deezer_id = 0 и затирали друг друга:
+-- от них осталась одна строка с последним файлом
+UPDATE tracks SET deezer_id = NULL WHERE deezer_id = 0;
+UPDATE tracks SET source = 'upload', match_status = 'pending' WHERE deezer_id IS NULL;
+
+-- Один файл Telegram — одна строка. Из дублей оставляем трек Deezer, иначе самый старый
+CREATE TEMP TABLE track_dupes AS
+SELECT id, FIRST_VALUE(id) OVER (PARTITION BY file_unique_id ORDER BY deezer_id IS NULL, id) AS keep_id
+FROM tracks
+WHERE file_unique_id IS NOT NULL AND file_unique_id <> '';
+
+DELETE FROM track_dupes WHERE id = keep_id;
+
+INSERT INTO user_tracks (user_id, track_id, added_at)
+SELECT ut.user_id, d.keep_id, ut.added_at
+FROM user_tracks ut
+JOIN track_dupes d ON d.id = ut.track_id
+ON CONFLICT DO NOTHING;
+
+UPDATE playlist_tracks pt SET track_id = d.keep_id
+FROM track_dupes d
+WHERE d.id = pt.track_id;
+
+DELETE FROM tracks WHERE id IN (SELECT id FROM track_dupes);
+DROP TABLE track_dupes;
+
+-- Пустая строка — "файла нет" (см. ResetAudio), такие строки не уникальны
+CREATE UNIQUE INDEX IF NOT EXISTS uq_tracks_file_unique_id ON tracks(file_unique_id)
+    WHERE file_unique_id IS NOT NULL AND file_unique_id <> '';
+
+ALTER TABLE tracks ADD CONSTRAINT chk_tracks_source CHECK (source IN ('deezer', 'upload'));
+
+CREATE INDEX IF NOT EXISTS idx_tracks_match_status ON tracks(match_status) WHERE match_status IS NOT NULL;
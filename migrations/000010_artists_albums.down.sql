@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_tracks_album_id;
+DROP INDEX IF EXISTS idx_tracks_artist_id;
+
+ALTER TABLE tracks DROP COLUMN IF EXISTS track_position;
+ALTER TABLE tracks DROP COLUMN IF EXISTS disc_number;
+ALTER TABLE tracks DROP COLUMN IF EXISTS album_id;
+ALTER TABLE tracks DROP COLUMN IF EXISTS artist_id;
+
+DROP TABLE IF EXISTS albums;
+DROP TABLE IF EXISTS artists;
@@ -0,0 +1,36 @@
+-- Артисты и альбомы Deezer. Первичный ключ — сам Deezer ID: других источников у них нет.
+-- Строка может быть "заготовкой" (только имя/название из выдачи поиска) —
+-- остальное дозаписывается, когда открывают страницу артиста или альбома
+CREATE TABLE IF NOT EXISTS artists (
+    id BIGINT PRIMARY KEY,
+    name TEXT NOT NULL,
+    picture_medium TEXT,
+    picture_xl TEXT,
+    nb_album INTEGER,
+    nb_fan INTEGER,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS albums (
+    id BIGINT PRIMARY KEY,
+    -- У треков в выдаче поиска артиста альбома нет (сборники), поэтому может быть NULL
+    artist_id BIGINT REFERENCES artists(id) ON DELETE SET NULL,
+    title TEXT NOT NULL DEFAULT '',
+    cover_medium TEXT,
+    cover_xl TEXT,
+    release_date DATE,
+    record_type VARCHAR(20),
+    nb_tracks INTEGER,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_albums_artist_id ON albums(artist_id);
+
+-- Место трека в альбоме: диск и номер на диске
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS artist_id BIGINT REFERENCES artists(id) ON DELETE SET NULL;
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS album_id BIGINT REFERENCES albums(id) ON DELETE SET NULL;
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS disc_number INTEGER;
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS track_position INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_tracks_artist_id ON tracks(artist_id);
+CREATE INDEX IF NOT EXISTS idx_tracks_album_id ON tracks(album_id);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_tracks_search_translit;
+DROP INDEX IF EXISTS idx_tracks_search_vector;
+
+ALTER TABLE tracks DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE tracks DROP COLUMN IF EXISTS search_translit;
+
+DROP FUNCTION IF EXISTS music_translit(TEXT);
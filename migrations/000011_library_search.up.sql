@@ -0,0 +1,44 @@
+-- Поиск по библиотеке. Пользователи пишут одних и тех же артистов то кириллицей, то латиницей,
+-- поэтому кроме самих названий индексируется их транслитерация: "kino" находит "Кино"
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- music_translit — кириллица (русская и украинская) в латиницу, все в нижнем регистре.
+-- Заглавные переводятся явно, а lower применяется уже к латинице: lower для кириллицы
+-- зависит от локали базы. Этой же функцией нормализуется строка поиска
+CREATE OR REPLACE FUNCTION music_translit(s TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE STRICT PARALLEL SAFE
+AS $$
+    SELECT lower(translate(
+        replace(replace(replace(replace(replace(replace(replace(replace(replace(
+        replace(replace(replace(replace(replace(replace(replace(replace(replace(
+            s,
+            'ж', 'zh'), 'Ж', 'Zh'),
+            'х', 'kh'), 'Х', 'Kh'),
+            'ц', 'ts'), 'Ц', 'Ts'),
+            'ч', 'ch'), 'Ч', 'Ch'),
+            'ш', 'sh'), 'Ш', 'Sh'),
+            'щ', 'shch'), 'Щ', 'Shch'),
+            'ю', 'yu'), 'Ю', 'Yu'),
+            'я', 'ya'), 'Я', 'Ya'),
+            'є', 'ye'), 'Є', 'Ye'),
+        -- Одна буква в одну; ъ и ь (в конце, без пары) выбрасываются
+        'абвгдеёзийклмнопрстуфыэіїґАБВГДЕЁЗИЙКЛМНОПРСТУФЫЭІЇҐъьЪЬ',
+        'abvgdeeziyklmnoprstufyeiigABVGDEEZIYKLMNOPRSTUFYEIIG'
+    ))
+$$;
+
+-- Транслитерация "артист название" — для нечеткого поиска по триграммам
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS search_translit TEXT
+    GENERATED ALWAYS AS (music_translit(artist || ' ' || title)) STORED;
+
+-- Полнотекстовый вектор: оригинал весит больше транслитерации, название — больше артиста
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS search_vector TSVECTOR
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', title), 'A') ||
+        setweight(to_tsvector('simple', artist), 'B') ||
+        setweight(to_tsvector('simple', music_translit(title)), 'C') ||
+        setweight(to_tsvector('simple', music_translit(artist)), 'D')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_tracks_search_vector ON tracks USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_tracks_search_translit ON tracks USING GIN (search_translit gin_trgm_ops);
@@ -0,0 +1,3 @@
+-- Треклист пакета загружает воркер (задача batches:start), пока пакет в статусе pending.
+-- missed — сколько треков из YouTube/Spotify не нашлось на Deezer
+ALTER TABLE batches ADD COLUMN IF NOT EXISTS missed INTEGER NOT NULL DEFAULT 0;